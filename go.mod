@@ -6,6 +6,7 @@ toolchain go1.24.1
 
 require (
 	github.com/DangerBlack/gobgg v0.0.0-20251106174421-5c2eecf9748a
+	github.com/bluele/gcache v0.0.2
 	github.com/jackc/pgx/v5 v5.7.2
 	github.com/joho/godotenv v1.5.1
 	github.com/mattn/go-sqlite3 v1.14.24
@@ -15,7 +16,6 @@ require (
 )
 
 require (
-	github.com/bluele/gcache v0.0.2 // indirect
 	github.com/bytedance/sonic v1.11.6 // indirect
 	github.com/bytedance/sonic/loader v0.1.1 // indirect
 	github.com/cloudwego/base64x v0.1.4 // indirect
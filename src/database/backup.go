@@ -0,0 +1,217 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the database file inside the database path.
+const FileName = "bot_data.sqlite"
+
+const (
+	backupPrefix = "bot_data-"
+	backupSuffix = ".sqlite"
+	// backupLayout sorts the backups by date when sorted by name.
+	backupLayout = "20060102-150405"
+)
+
+// ErrNoBackups is returned when restoring from a directory without backups.
+var ErrNoBackups = errors.New("no backups found")
+
+// IntegrityCheck runs SQLite's integrity check on the database and returns
+// the problems it reports.
+func (d *Database) IntegrityCheck() error {
+	return integrityCheck(d.db)
+}
+
+func integrityCheck(db *sql.DB) error {
+	rows, err := db.Query(`PRAGMA integrity_check;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	problems := []string{}
+	for rows.Next() {
+		var result string
+		if err = rows.Scan(&result); err != nil {
+			return err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Backup writes a consistent copy of the database into dir with VACUUM INTO,
+// while the bot keeps running, then deletes the oldest backups so that only
+// keep of them are left. It returns the path of the new backup.
+func (d *Database) Backup(dir string, keep int, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, backupPrefix+now.UTC().Format(backupLayout)+backupSuffix)
+	// the backup gets its name once complete, so that a partial file is never
+	// taken for a backup
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	if _, err := d.db.Exec(`VACUUM INTO ?;`, tmp); err != nil {
+		return "", err
+	}
+
+	if err := checkBackup(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+
+	if err := rotateBackups(dir, keep); err != nil {
+		log.Default().Println("failed to rotate backups:", err)
+	}
+
+	return path, nil
+}
+
+// checkBackup opens the database at path and runs the integrity check on it.
+func checkBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return integrityCheck(db)
+}
+
+// ListBackups returns the paths of the backups in dir, oldest first.
+func ListBackups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	backups := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+func rotateBackups(dir string, keep int) error {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > keep {
+		if err = os.Remove(backups[0]); err != nil {
+			return err
+		}
+		log.Default().Printf("Deleted old backup %s", backups[0])
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// RestoreBackup replaces the database in dbPath with the backup at
+// backupPath, or with the latest backup in backupDir when backupPath is
+// empty. The backup is checked first and the replaced database is kept next
+// to it. The bot must not be running. It returns the path of the restored
+// backup.
+func RestoreBackup(dbPath, backupDir, backupPath string) (string, error) {
+	if backupPath == "" {
+		backups, err := ListBackups(backupDir)
+		if err != nil {
+			return "", err
+		}
+		if len(backups) == 0 {
+			return "", ErrNoBackups
+		}
+		backupPath = backups[len(backups)-1]
+	}
+
+	if err := checkBackup(backupPath); err != nil {
+		return "", fmt.Errorf("invalid backup %s: %w", backupPath, err)
+	}
+
+	if err := os.MkdirAll(dbPath, 0o755); err != nil {
+		return "", err
+	}
+
+	current := filepath.Join(dbPath, FileName)
+	if _, err := os.Stat(current); err == nil {
+		replaced := filepath.Join(dbPath, fmt.Sprintf("%s.replaced-%s", FileName, time.Now().UTC().Format(backupLayout)))
+		if err = os.Rename(current, replaced); err != nil {
+			return "", err
+		}
+		log.Default().Printf("Moved the current database to %s", replaced)
+	}
+
+	// the journals of the replaced database would corrupt the restored one
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err := os.Remove(current + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	if err := copyFile(backupPath, current); err != nil {
+		return "", err
+	}
+
+	return backupPath, nil
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
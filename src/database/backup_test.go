@@ -0,0 +1,108 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDatabase(t *testing.T, path string) *Database {
+	t.Helper()
+	db := NewDatabase(path)
+	db.CreateTables()
+	db.MigrateToV1()
+	return db
+}
+
+func TestBackupRotatesAndRestores(t *testing.T) {
+	dbPath := t.TempDir()
+	backupDir := filepath.Join(t.TempDir(), "backups")
+	db := newTestDatabase(t, dbPath)
+
+	if err := db.IntegrityCheck(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.InsertEvent(nil, -100, 1, "host", "Kept", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC)
+	var latest string
+	for i := 0; i < 3; i++ {
+		path, err := db.Backup(backupDir, 2, start.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		latest = path
+	}
+
+	backups, err := ListBackups(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[1] != latest {
+		t.Fatalf("expected the 2 latest backups to be kept, got %v", backups)
+	}
+
+	if _, err = db.InsertEvent(nil, -100, 1, "host", "Lost", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	restored, err := RestoreBackup(dbPath, backupDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != latest {
+		t.Errorf("expected the latest backup to be restored, got %s", restored)
+	}
+
+	db = NewDatabase(dbPath)
+	defer db.Close()
+	if err = db.IntegrityCheck(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	rows, err := db.db.Query(`SELECT name FROM events ORDER BY name;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if len(names) != 1 || names[0] != "Kept" {
+		t.Errorf("expected the events of the backup, got %v", names)
+	}
+
+	replaced, _ := filepath.Glob(filepath.Join(dbPath, FileName+".replaced-*"))
+	if len(replaced) != 1 {
+		t.Errorf("expected the replaced database to be kept, got %v", replaced)
+	}
+}
+
+func TestRestoreRejectsInvalidBackups(t *testing.T) {
+	dbPath := t.TempDir()
+	backupDir := t.TempDir()
+
+	if _, err := RestoreBackup(dbPath, backupDir, ""); err != ErrNoBackups {
+		t.Errorf("expected ErrNoBackups, got %v", err)
+	}
+
+	corrupted := filepath.Join(backupDir, "bot_data-20250101-200000.sqlite")
+	if err := os.WriteFile(corrupted, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestoreBackup(dbPath, backupDir, ""); err == nil {
+		t.Error("expected a corrupted backup to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(dbPath, FileName)); !os.IsNotExist(err) {
+		t.Errorf("expected the database to be left untouched, got %v", err)
+	}
+}
@@ -3,7 +3,9 @@ package database
 import (
 	"boardgame-night-bot/src/models"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"path/filepath"
 	"sort"
@@ -17,43 +19,92 @@ import (
 
 type Database struct {
 	db *sql.DB
+	// tx is set on the Database handed to WithTransaction callbacks; every
+	// query then runs inside that transaction.
+	tx *sql.Tx
+}
+
+// queryer is the subset of *sql.DB and *sql.Tx used by the queries.
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
 }
 
 type DatabaseService interface {
 	CreateTables()
 	Close()
 	InsertEvent(id *string, chatID, userID int64, userName, name string, messageID *int64, location *string, startsAt *time.Time) (string, error)
-	InsertEventWithOptionalGame(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, addPlayerCounter bool) (string, error)
+	InsertEventWithOptionalGame(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, locked, addPlayerCounter bool) (string, error)
 	SelectEvent(chatID int64) (*models.Event, error)
 	SelectEventByEventID(eventID string) (*models.Event, error)
+	SelectEventsByUserID(userID int64, limit int) ([]models.Event, error)
+	SelectEventIDByMessageID(chatID, messageID int64) (string, error)
 	DeleteEvent(id string) error
-	InsertBoardGame(eventID string, id *string, name string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error)
+	InsertBoardGame(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error)
 	UpdateEventMessageID(eventID string, messageID int64) error
+	UpdateEventLocked(eventID string, locked bool) error
+	AddEventCoHost(eventID string, userID int64, userName string) error
+	RemoveEventCoHost(eventID string, userID int64) (bool, error)
 	UpdateBoardGameBGGInfoByID(ID int64, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) error
+	UpdateBoardGameSlot(ID int64, slot string) error
 	DeleteBoardGameByID(ID string) error
 	InsertParticipant(id *string, eventID string, boardgameID, userID int64, userName string, isTelegramUsername bool) (string, error)
-	RemoveParticipant(eventID string, userID int64) (string, int64, error)
+	RemoveParticipant(eventID string, userID int64, boardgameID *int64) (string, int64, error)
+	MoveParticipant(eventID string, userID, fromBoardgameID, toBoardgameID int64) error
+	RemoveRSVP(eventID string, userID int64) error
+	SetParticipantRSVP(id *string, eventID string, userID int64, userName string, isTelegramUsername bool, status models.RSVPStatus) (string, error)
 	HasBoardGameWithMessageID(messageID int64) bool
 	SelectGameIDByGameUUID(gameUUID string) (int64, error)
 	SelectGameUUIDByGameID(gameID int64) (string, error)
 	InsertChat(chatID int64, language *string, location *string, timezone *string) error
 	GetPreferredLanguage(chatID int64) string
 	GetDefaultTimezoneLocation(chatID int64) *time.Location
-	InsertWebhook(chatID int64, threadID *int64, url, secret string) (*int64, *string, error)
+	SetAutoPin(chatID int64, enabled bool) error
+	GetAutoPin(chatID int64) bool
+	UpdateEventPinned(eventID string, pinned bool) error
+	SelectPinnedEvents() ([]models.Event, error)
+	SetEventTopics(chatID int64, enabled bool) error
+	GetEventTopics(chatID int64) bool
+	UpdateEventTopic(eventID string, topicID int64) error
+	CloseEventTopic(eventID string) error
+	SelectOpenTopicEvents() ([]models.Event, error)
+	SelectEventsByParticipant(userID int64, limit int) ([]models.Event, error)
+	SelectEventsByChatID(chatID int64) ([]models.Event, error)
+	UpdateParticipantGuests(eventID string, userID, boardgameID int64, guests []string) error
+	SelectMaybeEventsToRemind() ([]models.Event, error)
+	SetMaybeReminded(eventID string) error
+	UpdateEventRSVPDeadline(eventID string, deadline *time.Time) error
+	SelectPendingLineupEvents() ([]models.Event, error)
+	SetLineupPosted(eventID string) error
+	SelectEventsToArchive() ([]models.Event, error)
+	SetEventFinished(eventID string) error
+	AnonymizeEventsBefore(cutoff time.Time, name string) (int64, error)
+	DeleteEventsBefore(cutoff time.Time) (int64, error)
+	ForgetUser(userID int64, name string) ([]string, error)
+	PurgeChat(chatID int64) (int64, error)
+	GetNotificationPreferences(userID int64) models.NotificationPreferences
+	SetNotificationPreferences(userID int64, preferences models.NotificationPreferences) error
+	InsertWebhook(chatID int64, threadID *int64, url, secret string, format models.WebhookFormat) (*int64, *string, error)
 	RemoveWebhook(webhookID int64) error
 	GetWebhooksByChatID(chatID int64) ([]models.Webhook, error)
 	GetWebhookByWebhookID(webhookID string) (*models.Webhook, error)
+	WithTransaction(fn func(db DatabaseService) error) error
 }
 
 var ErrNoRows = errors.New("sql: no rows in result set")
 
+// BusyTimeout is how long a connection waits for the write lock held by
+// another transaction, such as a webhook batch, before giving up.
+const BusyTimeout = 5 * time.Second
+
 func NewDatabase(path string) *Database {
-	db, err := sql.Open("sqlite3", filepath.Join(path, "bot_data.sqlite"))
+	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_busy_timeout=%d", filepath.Join(path, FileName), BusyTimeout.Milliseconds()))
 	if err != nil {
-		log.Fatal("failed to open database '"+filepath.Join(path, "bot_data.sqlite")+"':", err)
+		log.Fatal("failed to open database '"+filepath.Join(path, FileName)+"':", err)
 	}
 
-	return &Database{db}
+	return &Database{db: db}
 }
 
 func NamedArgs(arg map[string]any) []any {
@@ -311,11 +362,256 @@ func (d *Database) MigrateToV6() {
 	log.Default().Println("database migration to v6 completed")
 }
 
+func (d *Database) MigrateToV7() {
+	var err error
+	_, err = d.addColumnIfNotExists("webhooks", "format", "TEXT NOT NULL DEFAULT 'bgnb'")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v7 completed")
+}
+
+func (d *Database) MigrateToV8() {
+	var err error
+	_, err = d.addColumnIfNotExists("chats", "auto_pin", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = d.addColumnIfNotExists("events", "pinned", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v8 completed")
+}
+
+func (d *Database) MigrateToV9() {
+	var err error
+	_, err = d.addColumnIfNotExists("chats", "event_topics", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = d.addColumnIfNotExists("events", "topic_id", "INTEGER")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = d.addColumnIfNotExists("events", "topic_closed", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v9 completed")
+}
+
+func (d *Database) MigrateToV10() {
+	_, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS users (
+		user_id INTEGER PRIMARY KEY,
+		notify_waitlist BOOLEAN NOT NULL DEFAULT 1,
+		notify_cancellations BOOLEAN NOT NULL DEFAULT 1
+	);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v10 completed")
+}
+
+func (d *Database) MigrateToV11() {
+	_, err := d.addColumnIfNotExists("participants", "guests", "TEXT NOT NULL DEFAULT '[]'")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v11 completed")
+}
+
+func (d *Database) MigrateToV12() {
+	var err error
+	_, err = d.addColumnIfNotExists("participants", "status", "TEXT NOT NULL DEFAULT 'going'")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = d.addColumnIfNotExists("events", "maybe_reminded", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = d.addColumnIfNotExists("users", "notify_reminders", "BOOLEAN NOT NULL DEFAULT 1")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v12 completed")
+}
+
+func (d *Database) MigrateToV13() {
+	var err error
+	_, err = d.addColumnIfNotExists("events", "rsvp_deadline", "TIMESTAMP")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = d.addColumnIfNotExists("events", "lineup_posted", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v13 completed")
+}
+
+// MigrateToV14 moves the lock out of the event name into its own column and
+// adds the co-hosts of an event.
+func (d *Database) MigrateToV14() {
+	var err error
+	var added bool
+	added, err = d.addColumnIfNotExists("events", "locked", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if added {
+		_, err = d.db.Exec(`UPDATE events SET locked = 1, name = TRIM(REPLACE(name, '🔒', '')) WHERE name LIKE '%🔒%';`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	_, err = d.db.Exec(`CREATE TABLE IF NOT EXISTS event_cohosts (
+		event_id TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		user_name TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY(event_id, user_id),
+		FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE
+	);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v14 completed")
+}
+
+// MigrateToV15 adds the time slot of a game and lets a user join one game per
+// slot: the participants table is rebuilt so that the unique constraint covers
+// the game instead of the whole event. One seat per slot is enforced by
+// InsertParticipant.
+func (d *Database) MigrateToV15() {
+	_, err := d.addColumnIfNotExists("boardgames", "slot", "TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Guard: the rebuild already happened when the participants DDL no longer
+	// has the unique constraint on (event_id, user_id).
+	var ddl string
+	err = d.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type='table' AND name='participants'`).Scan(&ddl)
+	if err != nil {
+		log.Fatal("MigrateToV15: could not read participants DDL: ", err)
+	}
+	if !strings.Contains(ddl, "UNIQUE(event_id, user_id) ON CONFLICT REPLACE") {
+		log.Default().Println("database migration to v15 already applied, skipping")
+		return
+	}
+
+	steps := []string{
+		`PRAGMA foreign_keys = OFF`,
+		`CREATE TABLE IF NOT EXISTS participants_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			uuid TEXT UNIQUE,
+			event_id INTEGER,
+			boardgame_id INTEGER,
+			user_id INTEGER,
+			user_name TEXT,
+			is_telegram_username BOOLEAN DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			guests TEXT NOT NULL DEFAULT '[]',
+			status TEXT NOT NULL DEFAULT 'going',
+			FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE,
+			FOREIGN KEY(boardgame_id) REFERENCES boardgames(id) ON DELETE CASCADE,
+			UNIQUE(event_id, user_id, boardgame_id) ON CONFLICT REPLACE
+		)`,
+		`INSERT INTO participants_new (id, uuid, event_id, boardgame_id, user_id, user_name, is_telegram_username, created_at, guests, status)
+		 SELECT id, uuid, event_id, boardgame_id, user_id, user_name, is_telegram_username, created_at, guests, status FROM participants`,
+		`DROP TABLE participants`,
+		`ALTER TABLE participants_new RENAME TO participants`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS participants_uuid_idx ON participants(uuid)`,
+		`PRAGMA foreign_keys = ON`,
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, step := range steps {
+		if _, err = tx.Exec(step); err != nil {
+			tx.Rollback()
+			log.Fatal("MigrateToV15 failed at step: ", step, " error: ", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v15 completed")
+}
+
+// MigrateToV16 marks the events that are over: they are archived and no
+// longer accept changes.
+func (d *Database) MigrateToV16() {
+	_, err := d.addColumnIfNotExists("events", "finished", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Default().Println("database migration to v16 completed")
+}
+
 func (d *Database) Close() {
 	d.db.Close()
 	log.Default().Println("database connection closed")
 }
 
+func (d *Database) conn() queryer {
+	if d.tx != nil {
+		return d.tx
+	}
+	return d.db
+}
+
+// WithTransaction runs fn against a DatabaseService bound to a single
+// transaction, committing when fn returns nil and rolling back otherwise.
+// Calls made while already inside a transaction join the outer one.
+func (d *Database) WithTransaction(fn func(db DatabaseService) error) error {
+	return d.inTransaction(func(tx *Database) error {
+		return fn(tx)
+	})
+}
+
+func (d *Database) inTransaction(fn func(tx *Database) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = fn(&Database{db: d.db, tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (d *Database) InsertEvent(id *string, chatID, userID int64, userName, name string, messageID *int64, location *string, startsAt *time.Time) (string, error) {
 	var eventID string
 	query := `INSERT INTO events 
@@ -342,7 +638,7 @@ func (d *Database) InsertEvent(id *string, chatID, userID int64, userName, name
 		eventID = uuid.New().String()
 	}
 
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"event_id":   eventID,
 			"chat_id":    chatID,
@@ -363,13 +659,7 @@ func (d *Database) InsertEvent(id *string, chatID, userID int64, userName, name
 // InsertEventWithOptionalGame atomically inserts an event and, when addPlayerCounter
 // is true, also inserts the PLAYER_COUNTER game. Both writes share a single transaction
 // so a failure mid-way leaves no partial state in the database.
-func (d *Database) InsertEventWithOptionalGame(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, addPlayerCounter bool) (string, error) {
-	tx, err := d.db.Begin()
-	if err != nil {
-		return "", err
-	}
-	defer tx.Rollback()
-
+func (d *Database) InsertEventWithOptionalGame(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, locked, addPlayerCounter bool) (string, error) {
 	var eventID string
 	if id != nil {
 		eventID = *id
@@ -377,46 +667,54 @@ func (d *Database) InsertEventWithOptionalGame(id *string, chatID, userID int64,
 		eventID = uuid.New().String()
 	}
 
-	eventQuery := `INSERT INTO events
-	(id, chat_id, user_id, user_name, name, location, starts_at)
-	VALUES (
-		@event_id, @chat_id, @user_id, @user_name, @name,
-		COALESCE(@location, (SELECT default_location FROM chats WHERE chat_id = @chat_id)),
-		@starts_at
-	)
-	RETURNING id;`
-
-	if err = tx.QueryRow(eventQuery,
-		NamedArgs(map[string]any{
-			"event_id":  eventID,
-			"chat_id":   chatID,
-			"user_id":   userID,
-			"user_name": userName,
-			"name":      name,
-			"location":  location,
-			"starts_at": startsAt,
-		})...,
-	).Scan(&eventID); err != nil {
-		return "", err
-	}
+	err := d.inTransaction(func(tx *Database) error {
+		eventQuery := `INSERT INTO events
+		(id, chat_id, user_id, user_name, name, location, starts_at, locked)
+		VALUES (
+			@event_id, @chat_id, @user_id, @user_name, @name,
+			COALESCE(@location, (SELECT default_location FROM chats WHERE chat_id = @chat_id)),
+			@starts_at, @locked
+		)
+		RETURNING id;`
 
-	if addPlayerCounter {
-		gameUUID := uuid.New().String()
-		gameQuery := `INSERT INTO boardgames (event_id, uuid, name, max_players) VALUES (@event_id, @uuid, @name, @max_players) RETURNING id;`
-		var gameID int64
-		if err = tx.QueryRow(gameQuery,
+		if err := tx.conn().QueryRow(eventQuery,
 			NamedArgs(map[string]any{
-				"event_id":    eventID,
-				"uuid":        gameUUID,
-				"name":        models.PLAYER_COUNTER,
-				"max_players": models.UnlimitedPlayers,
+				"event_id":  eventID,
+				"chat_id":   chatID,
+				"user_id":   userID,
+				"user_name": userName,
+				"name":      name,
+				"location":  location,
+				"starts_at": startsAt,
+				"locked":    locked,
 			})...,
-		).Scan(&gameID); err != nil {
-			return "", err
+		).Scan(&eventID); err != nil {
+			return err
+		}
+
+		if addPlayerCounter {
+			gameUUID := uuid.New().String()
+			gameQuery := `INSERT INTO boardgames (event_id, uuid, name, max_players) VALUES (@event_id, @uuid, @name, @max_players) RETURNING id;`
+			var gameID int64
+			if err := tx.conn().QueryRow(gameQuery,
+				NamedArgs(map[string]any{
+					"event_id":    eventID,
+					"uuid":        gameUUID,
+					"name":        models.PLAYER_COUNTER,
+					"max_players": models.UnlimitedPlayers,
+				})...,
+			).Scan(&gameID); err != nil {
+				return err
+			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return eventID, tx.Commit()
+	return eventID, nil
 }
 
 func (d *Database) SelectEvent(chatID int64) (*models.Event, error) {
@@ -430,6 +728,11 @@ func (d *Database) SelectEvent(chatID int64) (*models.Event, error) {
 	e.user_name,
 	e.starts_at,
 	e.location,
+	e.pinned,
+	e.topic_id,
+	e.rsvp_deadline,
+	e.locked,
+	e.finished,
 	b.id,
 	b.uuid,
 	b.name,
@@ -439,11 +742,13 @@ func (d *Database) SelectEvent(chatID int64) (*models.Event, error) {
 	b.bgg_name,
 	b.bgg_url,
 	b.bgg_image_url,
+	b.slot,
 	p.id,
 	p.uuid,
 	p.user_id,
 	p.user_name,
 	p.is_telegram_username,
+	p.guests,
 	p.created_at
 	FROM events e
 	LEFT JOIN boardgames b ON e.id = b.event_id
@@ -463,6 +768,11 @@ func (d *Database) SelectEventByEventID(eventID string) (*models.Event, error) {
 	e.user_name,
 	e.starts_at,
 	e.location,
+	e.pinned,
+	e.topic_id,
+	e.rsvp_deadline,
+	e.locked,
+	e.finished,
 	b.id,
 	b.uuid,
 	b.name,
@@ -472,11 +782,13 @@ func (d *Database) SelectEventByEventID(eventID string) (*models.Event, error) {
 	b.bgg_name,
 	b.bgg_url,
 	b.bgg_image_url,
+	b.slot,
 	p.id,
 	p.uuid,
 	p.user_id,
 	p.user_name,
 	p.is_telegram_username,
+	p.guests,
 	p.created_at
 	FROM events e
 	LEFT JOIN boardgames b ON e.id = b.event_id
@@ -485,8 +797,80 @@ func (d *Database) SelectEventByEventID(eventID string) (*models.Event, error) {
 	return d.selectEventByQuery(query, map[string]any{"id": eventID})
 }
 
+// SelectEventsByUserID returns the latest events created or joined by userID,
+// newest first.
+func (d *Database) SelectEventsByUserID(userID int64, limit int) ([]models.Event, error) {
+	query := `SELECT e.id FROM events e
+	WHERE e.user_id = @user_id
+	OR EXISTS (SELECT 1 FROM participants p WHERE p.event_id = e.id AND p.user_id = @user_id)
+	ORDER BY e.created_at DESC
+	LIMIT @limit;`
+
+	return d.selectEventsByIDQuery(query, map[string]any{
+		"user_id": userID,
+		"limit":   limit,
+	})
+}
+
+// SelectEventsByParticipant returns the latest events joined by userID, or
+// that they answered maybe, newest first.
+func (d *Database) SelectEventsByParticipant(userID int64, limit int) ([]models.Event, error) {
+	query := `SELECT e.id FROM events e
+	WHERE EXISTS (SELECT 1 FROM participants p WHERE p.event_id = e.id AND p.user_id = @user_id AND p.status != 'declined')
+	ORDER BY e.created_at DESC
+	LIMIT @limit;`
+
+	return d.selectEventsByIDQuery(query, map[string]any{
+		"user_id": userID,
+		"limit":   limit,
+	})
+}
+
+// SelectEventsByChatID returns every event of the chat, oldest first.
+func (d *Database) SelectEventsByChatID(chatID int64) ([]models.Event, error) {
+	query := `SELECT id FROM events WHERE chat_id = @chat_id ORDER BY created_at, id;`
+
+	return d.selectEventsByIDQuery(query, map[string]any{
+		"chat_id": chatID,
+	})
+}
+
+// selectEventsByIDQuery runs a query returning event ids and loads each event.
+func (d *Database) selectEventsByIDQuery(query string, args map[string]any) ([]models.Event, error) {
+	rows, err := d.conn().Query(query, NamedArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var eventIDs []string
+	for rows.Next() {
+		var eventID string
+		if err = rows.Scan(&eventID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		eventIDs = append(eventIDs, eventID)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	events := []models.Event{}
+	for _, eventID := range eventIDs {
+		var event *models.Event
+		if event, err = d.SelectEventByEventID(eventID); err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+
+	return events, nil
+}
+
 func (d *Database) selectEventByQuery(query string, args map[string]any) (*models.Event, error) {
-	rows, err := d.db.Query(query, NamedArgs(args)...)
+	rows, err := d.conn().Query(query, NamedArgs(args)...)
 	if err != nil {
 		return nil, err
 	}
@@ -500,10 +884,10 @@ func (d *Database) selectEventByQuery(query string, args map[string]any) (*model
 		var boardGame models.BoardGame
 		var participant models.Participant
 
-		var eventMessageID, boardGameID, boardGameMaxPlayers, participantID, participantUserID, bggID, bgMessageID pgtype.Int8
-		var boardGameUUID, participantUUID, boardGameName, participantUserName, bggName, bggUrl, bggImageUrl, location pgtype.Text
-		var startsAt, participantCreatedAt pgtype.Timestamp
-		var isTelegramUsername pgtype.Bool
+		var eventMessageID, topicID, boardGameID, boardGameMaxPlayers, participantID, participantUserID, bggID, bgMessageID pgtype.Int8
+		var boardGameUUID, participantUUID, boardGameName, participantUserName, participantGuests, bggName, bggUrl, bggImageUrl, slot, location pgtype.Text
+		var startsAt, rsvpDeadline, participantCreatedAt pgtype.Timestamp
+		var isTelegramUsername, pinned pgtype.Bool
 
 		if err := rows.Scan(
 			&event.ID,
@@ -514,6 +898,11 @@ func (d *Database) selectEventByQuery(query string, args map[string]any) (*model
 			&event.UserName,
 			&startsAt,
 			&location,
+			&pinned,
+			&topicID,
+			&rsvpDeadline,
+			&event.Locked,
+			&event.Finished,
 			&boardGameID,
 			&boardGameUUID,
 			&boardGameName,
@@ -523,20 +912,24 @@ func (d *Database) selectEventByQuery(query string, args map[string]any) (*model
 			&bggName,
 			&bggUrl,
 			&bggImageUrl,
+			&slot,
 			&participantID,
 			&participantUUID,
 			&participantUserID,
 			&participantUserName,
 			&isTelegramUsername,
+			&participantGuests,
 			&participantCreatedAt,
 		); err != nil {
 			return nil, err
 		}
 
 		event.MessageID = IntOrNil(eventMessageID)
-		event.Locked = strings.Contains(event.Name, "🔒")
 		event.StartsAt = TimeOrNil(startsAt)
 		event.Location = StringOrNil(location)
+		event.Pinned = pinned.Valid && pinned.Bool
+		event.TopicID = IntOrNil(topicID)
+		event.RSVPDeadline = TimeOrNil(rsvpDeadline)
 
 		if IntOrNil(boardGameID) != nil {
 			boardGame = models.BoardGame{
@@ -549,6 +942,7 @@ func (d *Database) selectEventByQuery(query string, args map[string]any) (*model
 				BggName:     StringOrNil(bggName),
 				BggUrl:      StringOrNil(bggUrl),
 				BggImageUrl: StringOrNil(bggImageUrl),
+				Slot:        slot.String,
 			}
 
 			if _, ok := boardGameMap[boardGame.ID]; !ok {
@@ -563,8 +957,14 @@ func (d *Database) selectEventByQuery(query string, args map[string]any) (*model
 				UserID:             *IntOrNil(participantUserID),
 				UserName:           *StringOrNil(participantUserName),
 				IsTelegramUsername: *BoolOrNil(isTelegramUsername),
+				Status:             models.RSVPGoing,
 				CreatedAt:          TimeOrNil(participantCreatedAt),
 			}
+			if participantGuests.Valid {
+				if err := json.Unmarshal([]byte(participantGuests.String), &participant.Guests); err != nil {
+					return nil, err
+				}
+			}
 
 			boardGameMap[boardGame.ID].Participants = append(boardGameMap[boardGame.ID].Participants, participant)
 		}
@@ -602,53 +1002,153 @@ func (d *Database) selectEventByQuery(query string, args map[string]any) (*model
 		event.BoardGames = append(event.BoardGames, *boardGame)
 	}
 
+	// Games are grouped by time slot, the games without one first.
 	sort.SliceStable(event.BoardGames, func(i, j int) bool {
-		return event.BoardGames[i].Name < event.BoardGames[j].Name || (event.BoardGames[i].Name == event.BoardGames[j].Name && event.BoardGames[i].ID < event.BoardGames[j].ID)
+		a, b := event.BoardGames[i], event.BoardGames[j]
+		if a.Slot != b.Slot {
+			return a.Slot < b.Slot
+		}
+		return a.Name < b.Name || (a.Name == b.Name && a.ID < b.ID)
 	})
 
+	if event.ID != "" {
+		if err = d.selectRSVPs(event); err != nil {
+			return nil, err
+		}
+		if err = d.selectCoHosts(event); err != nil {
+			return nil, err
+		}
+	}
+
 	return event, nil
 }
 
-func (d *Database) DeleteEvent(id string) error {
-	query := `DELETE FROM events WHERE id = @id;`
-	_, err := d.db.Exec(query,
-		NamedArgs(map[string]any{
-			"id": id,
-		})...,
-	)
-	return err
-}
+// selectRSVPs loads the users who answered maybe or declined the event, in
+// order of answer.
+func (d *Database) selectRSVPs(event *models.Event) error {
+	query := `SELECT id, uuid, user_id, user_name, is_telegram_username, status, created_at
+	FROM participants
+	WHERE event_id = @event_id AND boardgame_id IS NULL
+	ORDER BY created_at, id;`
 
-func (d *Database) SelectGameIDByGameUUID(gameUUID string) (int64, error) {
-	query := `SELECT id FROM boardgames WHERE uuid = @uuid;`
-	var id int64
-	if err := d.db.QueryRow(query,
-		NamedArgs(map[string]any{
-			"uuid": gameUUID,
-		})...,
-	).Scan(&id); err != nil {
-		return 0, err
+	rows, err := d.conn().Query(query, NamedArgs(map[string]any{"event_id": event.ID})...)
+	if err != nil {
+		return err
 	}
-	return id, nil
-}
+	defer rows.Close()
 
-func (d *Database) SelectGameUUIDByGameID(gameID int64) (string, error) {
-	query := `SELECT uuid FROM boardgames WHERE id = @id;`
-	var uuid string
-	if err := d.db.QueryRow(query,
-		NamedArgs(map[string]any{
-			"id": gameID,
-		})...,
+	for rows.Next() {
+		var participant models.Participant
+		var createdAt pgtype.Timestamp
+		var isTelegramUsername pgtype.Bool
+		if err = rows.Scan(
+			&participant.ID,
+			&participant.UUID,
+			&participant.UserID,
+			&participant.UserName,
+			&isTelegramUsername,
+			&participant.Status,
+			&createdAt,
+		); err != nil {
+			return err
+		}
+		participant.IsTelegramUsername = isTelegramUsername.Valid && isTelegramUsername.Bool
+		participant.CreatedAt = TimeOrNil(createdAt)
+
+		switch participant.Status {
+		case models.RSVPMaybe:
+			event.Tentative = append(event.Tentative, participant)
+		case models.RSVPDeclined:
+			event.Declined = append(event.Declined, participant)
+		}
+	}
+
+	return rows.Err()
+}
+
+// selectCoHosts loads the co-hosts of the event, in order of appointment.
+func (d *Database) selectCoHosts(event *models.Event) error {
+	query := `SELECT user_id, user_name FROM event_cohosts WHERE event_id = @event_id ORDER BY created_at, user_id;`
+
+	rows, err := d.conn().Query(query, NamedArgs(map[string]any{"event_id": event.ID})...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var coHost models.CoHost
+		if err = rows.Scan(&coHost.UserID, &coHost.UserName); err != nil {
+			return err
+		}
+		event.CoHosts = append(event.CoHosts, coHost)
+	}
+
+	return rows.Err()
+}
+
+func (d *Database) DeleteEvent(id string) error {
+	query := `DELETE FROM events WHERE id = @id;`
+	_, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id": id,
+		})...,
+	)
+	return err
+}
+
+func (d *Database) SelectGameIDByGameUUID(gameUUID string) (int64, error) {
+	query := `SELECT id FROM boardgames WHERE uuid = @uuid;`
+	var id int64
+	if err := d.conn().QueryRow(query,
+		NamedArgs(map[string]any{
+			"uuid": gameUUID,
+		})...,
+	).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (d *Database) SelectGameUUIDByGameID(gameID int64) (string, error) {
+	query := `SELECT uuid FROM boardgames WHERE id = @id;`
+	var uuid string
+	if err := d.conn().QueryRow(query,
+		NamedArgs(map[string]any{
+			"id": gameID,
+		})...,
 	).Scan(&uuid); err != nil {
 		return "", err
 	}
 	return uuid, nil
 }
 
+// SelectEventIDByMessageID returns the event of the chat posted in messageID,
+// or owning the game posted in messageID.
+func (d *Database) SelectEventIDByMessageID(chatID, messageID int64) (string, error) {
+	query := `SELECT e.id FROM events e
+	WHERE e.chat_id = @chat_id
+	AND (e.message_id = @message_id OR EXISTS (SELECT 1 FROM boardgames b WHERE b.event_id = e.id AND b.message_id = @message_id))
+	ORDER BY e.created_at DESC
+	LIMIT 1;`
+
+	var eventID string
+	if err := d.conn().QueryRow(query,
+		NamedArgs(map[string]any{
+			"chat_id":    chatID,
+			"message_id": messageID,
+		})...,
+	).Scan(&eventID); err != nil {
+		return "", ParseError(err)
+	}
+
+	return eventID, nil
+}
+
 func (d *Database) UpdateEventMessageID(eventID string, messageID int64) error {
 	query := `UPDATE events SET message_id = @message_id where id = @event_id;`
 
-	if _, err := d.db.Exec(query,
+	if _, err := d.conn().Exec(query,
 		NamedArgs(map[string]any{
 			"event_id":   eventID,
 			"message_id": messageID,
@@ -660,14 +1160,14 @@ func (d *Database) UpdateEventMessageID(eventID string, messageID int64) error {
 	return nil
 }
 
-func (d *Database) InsertBoardGame(eventID string, id *string, name string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
+func (d *Database) InsertBoardGame(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
 	var boardGameID int64
 	if id == nil {
 		pId := uuid.New().String()
 		id = &pId
 	}
 
-	query := `INSERT INTO boardgames (event_id, uuid, name, max_players, bgg_id, bgg_name, bgg_url, bgg_image_url) VALUES (@event_id, @uuid, @name, @max_players, @bgg_id, @bgg_name, @bgg_url, @bgg_image_url) RETURNING id,uuid;`
+	query := `INSERT INTO boardgames (event_id, uuid, name, slot, max_players, bgg_id, bgg_name, bgg_url, bgg_image_url) VALUES (@event_id, @uuid, @name, @slot, @max_players, @bgg_id, @bgg_name, @bgg_url, @bgg_image_url) RETURNING id,uuid;`
 
 	if bggImageUrl != nil && *bggImageUrl == "" {
 		// Fix for BGG image URLs that contains a filter with mandatory (png)
@@ -677,11 +1177,12 @@ func (d *Database) InsertBoardGame(eventID string, id *string, name string, maxP
 		bggImageUrl = &tmp
 	}
 
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"event_id":      eventID,
 			"uuid":          id,
 			"name":          name,
+			"slot":          slot,
 			"max_players":   maxPlayers,
 			"bgg_id":        bggID,
 			"bgg_url":       bggUrl,
@@ -698,7 +1199,7 @@ func (d *Database) InsertBoardGame(eventID string, id *string, name string, maxP
 func (d *Database) UpdateBoardGameMessageID(boardgameID, messageID int64) error {
 	query := `UPDATE boardgames SET message_id = @message_id where id = @boardgame_id;`
 
-	if _, err := d.db.Exec(query,
+	if _, err := d.conn().Exec(query,
 		NamedArgs(map[string]any{
 			"boardgame_id": boardgameID,
 			"message_id":   messageID,
@@ -716,7 +1217,7 @@ func (d *Database) UpdateBoardGamePlayerNumber(messageID int64, maxPlayers int)
 
 	query := `UPDATE boardgames SET max_players = @max_players where message_id = @message_id RETURNING id, name;`
 
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"max_players": maxPlayers,
 			"message_id":  messageID,
@@ -741,7 +1242,7 @@ func (d *Database) UpdateBoardGameBGGInfo(messageID int64, maxPlayers int, bggID
 	bgg_image_url = @bgg_image_url
 	WHERE message_id = @message_id RETURNING uuid, name;`
 
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"max_players":   maxPlayers,
 			"message_id":    messageID,
@@ -767,7 +1268,7 @@ func (d *Database) UpdateBoardGameBGGInfoByID(ID int64, maxPlayers int, bggID *i
 	bgg_image_url = @bgg_image_url
 	WHERE id = @id RETURNING id;`
 
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"max_players":   maxPlayers,
 			"id":            ID,
@@ -783,10 +1284,27 @@ func (d *Database) UpdateBoardGameBGGInfoByID(ID int64, maxPlayers int, bggID *i
 	return nil
 }
 
+// UpdateBoardGameSlot moves a game to another time slot. Users keep the
+// seats they already have, even when two of them end up in the same slot.
+func (d *Database) UpdateBoardGameSlot(ID int64, slot string) error {
+	query := `UPDATE boardgames SET slot = @slot WHERE id = @id RETURNING id;`
+
+	if err := d.conn().QueryRow(query,
+		NamedArgs(map[string]any{
+			"id":   ID,
+			"slot": slot,
+		})...,
+	).Scan(&ID); err != nil {
+		return ParseError(err)
+	}
+
+	return nil
+}
+
 func (d *Database) DeleteBoardGameByID(ID string) error {
 	query := `DELETE FROM boardgames WHERE uuid = @uuid;`
 
-	if _, err := d.db.Exec(query,
+	if _, err := d.conn().Exec(query,
 		NamedArgs(map[string]any{
 			"uuid": ID,
 		})...,
@@ -801,7 +1319,7 @@ func (d *Database) HasBoardGameWithMessageID(messageID int64) bool {
 	query := `SELECT id FROM boardgames WHERE message_id = @message_id;`
 
 	var id int64
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"message_id": messageID,
 		})...,
@@ -812,47 +1330,202 @@ func (d *Database) HasBoardGameWithMessageID(messageID int64) bool {
 	return true
 }
 
+// InsertParticipant seats userID at a game. A user takes one seat per time
+// slot: the seat they had in the slot of the game, and their maybe or declined
+// answer, are replaced. Guests follow the participant when they move to
+// another game of the same slot.
 func (d *Database) InsertParticipant(id *string, eventID string, boardgameID, userID int64, userName string, isTelegramUsername bool) (string, error) {
-
 	if id == nil {
 		newID := uuid.New().String()
 		id = &newID
 	}
-	query := `INSERT INTO participants (uuid, event_id, boardgame_id, user_id, user_name, is_telegram_username, created_at) VALUES (@uuid, @event_id, @boardgame_id, @user_id, @user_name, @is_telegram_username, datetime('now')) RETURNING uuid;`
 
-	if err := d.db.QueryRow(query,
+	args := NamedArgs(map[string]any{
+		"uuid":                 id,
+		"event_id":             eventID,
+		"boardgame_id":         boardgameID,
+		"user_id":              userID,
+		"user_name":            userName,
+		"is_telegram_username": isTelegramUsername,
+	})
+
+	// seats of the user in the slot of the game
+	sameSlot := `SELECT p.id FROM participants p
+		JOIN boardgames b ON b.id = p.boardgame_id
+		WHERE p.event_id = @event_id AND p.user_id = @user_id
+		AND b.slot = (SELECT slot FROM boardgames WHERE id = @boardgame_id)`
+
+	err := d.inTransaction(func(tx *Database) error {
+		guests := "[]"
+		if err := tx.conn().QueryRow(`SELECT guests FROM participants WHERE id IN (`+sameSlot+`) ORDER BY created_at DESC LIMIT 1;`, args...).Scan(&guests); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		if _, err := tx.conn().Exec(`DELETE FROM participants WHERE id IN (`+sameSlot+`)
+			OR (event_id = @event_id AND user_id = @user_id AND boardgame_id IS NULL);`, args...); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO participants (uuid, event_id, boardgame_id, user_id, user_name, is_telegram_username, guests, status, created_at)
+		VALUES (@uuid, @event_id, @boardgame_id, @user_id, @user_name, @is_telegram_username, @guests, 'going', datetime('now'))
+		RETURNING uuid;`
+
+		return tx.conn().QueryRow(query, append(args, sql.Named("guests", guests))...).Scan(id)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return *id, nil
+}
+
+// UpdateParticipantGuests replaces the guests brought by userID to a game of
+// the event.
+func (d *Database) UpdateParticipantGuests(eventID string, userID, boardgameID int64, guests []string) error {
+	encoded, err := json.Marshal(guests)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE participants SET guests = @guests WHERE event_id = @event_id AND user_id = @user_id AND boardgame_id = @boardgame_id;`
+	result, err := d.conn().Exec(query,
 		NamedArgs(map[string]any{
-			"uuid":                 id,
-			"event_id":             eventID,
-			"boardgame_id":         boardgameID,
-			"user_id":              userID,
-			"user_name":            userName,
-			"is_telegram_username": isTelegramUsername,
+			"event_id":     eventID,
+			"user_id":      userID,
+			"boardgame_id": boardgameID,
+			"guests":       string(encoded),
 		})...,
-	).Scan(id); err != nil {
+	)
+	if err != nil {
+		return err
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// SetParticipantRSVP records that userID answered maybe or declined the
+// event, freeing every seat they may have taken. It returns the id of the
+// answer.
+func (d *Database) SetParticipantRSVP(id *string, eventID string, userID int64, userName string, isTelegramUsername bool, status models.RSVPStatus) (string, error) {
+	if id == nil {
+		newID := uuid.New().String()
+		id = &newID
+	}
+
+	args := NamedArgs(map[string]any{
+		"uuid":                 id,
+		"event_id":             eventID,
+		"user_id":              userID,
+		"user_name":            userName,
+		"is_telegram_username": isTelegramUsername,
+		"status":               status,
+	})
+
+	err := d.inTransaction(func(tx *Database) error {
+		if _, err := tx.conn().Exec(`DELETE FROM participants WHERE event_id = @event_id AND user_id = @user_id;`, args...); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO participants (uuid, event_id, boardgame_id, user_id, user_name, is_telegram_username, status, created_at)
+		VALUES (@uuid, @event_id, NULL, @user_id, @user_name, @is_telegram_username, @status, datetime('now'))
+		RETURNING uuid;`
+
+		return tx.conn().QueryRow(query, args...).Scan(id)
+	})
+	if err != nil {
 		return "", err
 	}
 
 	return *id, nil
 }
 
-func (d *Database) RemoveParticipant(eventID string, userID int64) (string, int64, error) {
-	query := `DELETE FROM participants WHERE event_id = @event_id AND user_id = @user_id RETURNING uuid, boardgame_id;`
+// RemoveParticipant frees the seat of userID at a game, or every seat they
+// took in the event when boardgameID is nil. It returns the id and the game of
+// the first seat freed.
+func (d *Database) RemoveParticipant(eventID string, userID int64, boardgameID *int64) (string, int64, error) {
+	query := `DELETE FROM participants WHERE event_id = @event_id AND user_id = @user_id AND boardgame_id IS NOT NULL
+	AND (@boardgame_id IS NULL OR boardgame_id = @boardgame_id) RETURNING uuid, boardgame_id;`
+	rows, err := d.conn().Query(query,
+		NamedArgs(map[string]any{
+			"event_id":     eventID,
+			"user_id":      userID,
+			"boardgame_id": boardgameID,
+		})...,
+	)
+	if err != nil {
+		return "", 0, err
+	}
+	defer rows.Close()
+
 	var id string
-	var boardgameID int64
-	if err := d.db.QueryRow(query,
+	var gameID int64
+	for rows.Next() {
+		var seatID string
+		var seatGameID int64
+		if err = rows.Scan(&seatID, &seatGameID); err != nil {
+			return "", 0, err
+		}
+		if id == "" {
+			id, gameID = seatID, seatGameID
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return "", 0, err
+	}
+
+	if id == "" {
+		return "", 0, ErrNoRows
+	}
+
+	return id, gameID, nil
+}
+
+// MoveParticipant moves the seat of userID from a game to another game of the
+// event, at the end of its list like a new participant. Guests move along.
+func (d *Database) MoveParticipant(eventID string, userID, fromBoardgameID, toBoardgameID int64) error {
+	query := `UPDATE participants SET boardgame_id = @to_boardgame_id, created_at = datetime('now')
+	WHERE event_id = @event_id AND user_id = @user_id AND boardgame_id = @from_boardgame_id;`
+	result, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"event_id":          eventID,
+			"user_id":           userID,
+			"from_boardgame_id": fromBoardgameID,
+			"to_boardgame_id":   toBoardgameID,
+		})...,
+	)
+	if err != nil {
+		return err
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// RemoveRSVP removes the maybe or declined answer of userID.
+func (d *Database) RemoveRSVP(eventID string, userID int64) error {
+	query := `DELETE FROM participants WHERE event_id = @event_id AND user_id = @user_id AND boardgame_id IS NULL;`
+	result, err := d.conn().Exec(query,
 		NamedArgs(map[string]any{
 			"event_id": eventID,
 			"user_id":  userID,
 		})...,
-	).Scan(&id, &boardgameID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", 0, ErrNoRows
-		}
-		return "", 0, err
+	)
+	if err != nil {
+		return err
 	}
 
-	return id, boardgameID, nil
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNoRows
+	}
+
+	return nil
 }
 
 func (d *Database) InsertChat(chatID int64, language *string, location *string, timezone *string) error {
@@ -870,7 +1543,7 @@ func (d *Database) InsertChat(chatID int64, language *string, location *string,
 			default_timezone = COALESCE(EXCLUDED.default_timezone, default_timezone);
 	`
 
-	if _, err := d.db.Exec(query,
+	if _, err := d.conn().Exec(query,
 		NamedArgs(map[string]any{
 			"chat_id":          chatID,
 			"language":         language,
@@ -888,7 +1561,7 @@ func (d *Database) GetPreferredLanguage(chatID int64) string {
 	query := `SELECT language FROM chats WHERE chat_id = @chat_id;`
 
 	var language string
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"chat_id": chatID,
 		})...,
@@ -903,7 +1576,7 @@ func (d *Database) GetDefaultTimezoneLocation(chatID int64) *time.Location {
 	query := `SELECT default_timezone FROM chats WHERE chat_id = @chat_id;`
 
 	var locationStr pgtype.Text
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"chat_id": chatID,
 		})...,
@@ -924,17 +1597,483 @@ func (d *Database) GetDefaultTimezoneLocation(chatID int64) *time.Location {
 	return location
 }
 
-func (d *Database) InsertWebhook(chatID int64, threadID *int64, url, secret string) (*int64, *string, error) {
-	query := `INSERT INTO webhooks (uuid, chat_id, thread_id, url, secret) VALUES (@uuid, @chat_id, @thread_id, @url, @secret) RETURNING id;`
+func (d *Database) SetAutoPin(chatID int64, enabled bool) error {
+	query := `
+		INSERT INTO chats (chat_id, language, auto_pin)
+		VALUES (@chat_id, 'en', @auto_pin)
+		ON CONFLICT(chat_id) DO UPDATE SET
+			auto_pin = EXCLUDED.auto_pin;
+	`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"chat_id":  chatID,
+			"auto_pin": enabled,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *Database) GetAutoPin(chatID int64) bool {
+	query := `SELECT auto_pin FROM chats WHERE chat_id = @chat_id;`
+
+	var autoPin bool
+	if err := d.conn().QueryRow(query,
+		NamedArgs(map[string]any{
+			"chat_id": chatID,
+		})...,
+	).Scan(&autoPin); err != nil {
+		return false
+	}
+
+	return autoPin
+}
+
+func (d *Database) UpdateEventPinned(eventID string, pinned bool) error {
+	query := `UPDATE events SET pinned = @pinned WHERE id = @id;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id":     eventID,
+			"pinned": pinned,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SelectPinnedEvents returns the events whose message has been pinned by the bot.
+func (d *Database) SelectPinnedEvents() ([]models.Event, error) {
+	query := `SELECT id FROM events WHERE pinned = 1;`
+	return d.selectEventsByIDQuery(query, map[string]any{})
+}
+
+func (d *Database) SetEventTopics(chatID int64, enabled bool) error {
+	query := `
+		INSERT INTO chats (chat_id, language, event_topics)
+		VALUES (@chat_id, 'en', @event_topics)
+		ON CONFLICT(chat_id) DO UPDATE SET
+			event_topics = EXCLUDED.event_topics;
+	`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"chat_id":      chatID,
+			"event_topics": enabled,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *Database) GetEventTopics(chatID int64) bool {
+	query := `SELECT event_topics FROM chats WHERE chat_id = @chat_id;`
+
+	var eventTopics bool
+	if err := d.conn().QueryRow(query,
+		NamedArgs(map[string]any{
+			"chat_id": chatID,
+		})...,
+	).Scan(&eventTopics); err != nil {
+		return false
+	}
+
+	return eventTopics
+}
+
+func (d *Database) UpdateEventTopic(eventID string, topicID int64) error {
+	query := `UPDATE events SET topic_id = @topic_id, topic_closed = 0 WHERE id = @id;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id":       eventID,
+			"topic_id": topicID,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *Database) CloseEventTopic(eventID string) error {
+	query := `UPDATE events SET topic_closed = 1 WHERE id = @id;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id": eventID,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SelectMaybeEventsToRemind returns the dated events whose tentative users
+// have not been reminded yet.
+func (d *Database) SelectMaybeEventsToRemind() ([]models.Event, error) {
+	query := `SELECT e.id FROM events e
+	WHERE e.maybe_reminded = 0 AND e.starts_at IS NOT NULL
+	AND EXISTS (SELECT 1 FROM participants p WHERE p.event_id = e.id AND p.status = 'maybe');`
+	return d.selectEventsByIDQuery(query, map[string]any{})
+}
+
+func (d *Database) SetMaybeReminded(eventID string) error {
+	query := `UPDATE events SET maybe_reminded = 1 WHERE id = @id;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id": eventID,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateEventLocked locks or unlocks the event.
+func (d *Database) UpdateEventLocked(eventID string, locked bool) error {
+	query := `UPDATE events SET locked = @locked WHERE id = @id;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id":     eventID,
+			"locked": locked,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AddEventCoHost makes the user a co-host of the event, adding them again
+// only refreshes the stored name.
+func (d *Database) AddEventCoHost(eventID string, userID int64, userName string) error {
+	query := `INSERT INTO event_cohosts (event_id, user_id, user_name)
+	VALUES (@event_id, @user_id, @user_name)
+	ON CONFLICT(event_id, user_id) DO UPDATE SET user_name = excluded.user_name;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"event_id":  eventID,
+			"user_id":   userID,
+			"user_name": userName,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemoveEventCoHost removes the user from the co-hosts of the event and
+// reports whether they were one.
+func (d *Database) RemoveEventCoHost(eventID string, userID int64) (bool, error) {
+	query := `DELETE FROM event_cohosts WHERE event_id = @event_id AND user_id = @user_id;`
+
+	result, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"event_id": eventID,
+			"user_id":  userID,
+		})...,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
+
+// UpdateEventRSVPDeadline sets the RSVP deadline of the event, nil removes it.
+// The final lineup is posted again once the new deadline passes.
+func (d *Database) UpdateEventRSVPDeadline(eventID string, deadline *time.Time) error {
+	query := `UPDATE events SET rsvp_deadline = @rsvp_deadline, lineup_posted = 0 WHERE id = @id;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id":            eventID,
+			"rsvp_deadline": deadline,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SelectPendingLineupEvents returns the events with an RSVP deadline whose
+// final lineup has not been posted yet.
+func (d *Database) SelectPendingLineupEvents() ([]models.Event, error) {
+	query := `SELECT id FROM events WHERE rsvp_deadline IS NOT NULL AND lineup_posted = 0;`
+	return d.selectEventsByIDQuery(query, map[string]any{})
+}
+
+func (d *Database) SetLineupPosted(eventID string) error {
+	query := `UPDATE events SET lineup_posted = 1 WHERE id = @id;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id": eventID,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SelectOpenTopicEvents returns the events whose forum topic has not been
+// closed yet.
+func (d *Database) SelectOpenTopicEvents() ([]models.Event, error) {
+	query := `SELECT id FROM events WHERE topic_id IS NOT NULL AND topic_closed = 0;`
+	return d.selectEventsByIDQuery(query, map[string]any{})
+}
+
+// SelectEventsToArchive returns the dated events that have not been marked
+// as finished yet.
+func (d *Database) SelectEventsToArchive() ([]models.Event, error) {
+	query := `SELECT id FROM events WHERE finished = 0 AND starts_at IS NOT NULL;`
+	return d.selectEventsByIDQuery(query, map[string]any{})
+}
+
+// SetEventFinished archives the event: it no longer accepts changes.
+func (d *Database) SetEventFinished(eventID string) error {
+	query := `UPDATE events SET finished = 1 WHERE id = @id;`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"id": eventID,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// retentionDate formats a cutoff like the timestamps stored by SQLite, so
+// that it can be compared with the start or the creation date of an event.
+func retentionDate(cutoff time.Time) string {
+	return cutoff.UTC().Format("2006-01-02 15:04:05")
+}
+
+// AnonymizeEventsBefore replaces the names of the participants, guests,
+// co-hosts and creators of the events held before cutoff with name, and
+// returns how many events were anonymized. Undated events count from their
+// creation. Participant user IDs are made negative so that they stay unique.
+func (d *Database) AnonymizeEventsBefore(cutoff time.Time, name string) (int64, error) {
+	var anonymized int64
+	args := map[string]any{
+		"cutoff": retentionDate(cutoff),
+		"name":   name,
+	}
+
+	err := d.inTransaction(func(tx *Database) error {
+		queries := []string{
+			`UPDATE participants SET user_id = -id, user_name = @name, is_telegram_username = 0,
+			guests = (SELECT json_group_array('') FROM json_each(participants.guests))
+			WHERE user_id > 0 AND event_id IN (SELECT id FROM events WHERE COALESCE(starts_at, created_at) < @cutoff);`,
+			`DELETE FROM event_cohosts WHERE event_id IN (SELECT id FROM events WHERE COALESCE(starts_at, created_at) < @cutoff);`,
+		}
+		for _, query := range queries {
+			if _, err := tx.conn().Exec(query, NamedArgs(args)...); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.conn().Exec(`UPDATE events SET user_id = 0, user_name = @name
+		WHERE user_id != 0 AND COALESCE(starts_at, created_at) < @cutoff;`, NamedArgs(args)...)
+		if err != nil {
+			return err
+		}
+
+		anonymized, err = result.RowsAffected()
+		return err
+	})
+
+	return anonymized, err
+}
+
+// DeleteEventsBefore deletes the events held before cutoff and returns how
+// many were deleted. Undated events count from their creation.
+func (d *Database) DeleteEventsBefore(cutoff time.Time) (int64, error) {
+	return d.deleteEvents(`COALESCE(starts_at, created_at) < @cutoff`, map[string]any{
+		"cutoff": retentionDate(cutoff),
+	})
+}
+
+// deleteEvents deletes the events matching where, together with their games,
+// participants and co-hosts, and returns how many were deleted.
+func (d *Database) deleteEvents(where string, args map[string]any) (int64, error) {
+	var deleted int64
+	err := d.inTransaction(func(tx *Database) error {
+		for _, table := range []string{"participants", "event_cohosts", "boardgames"} {
+			query := `DELETE FROM ` + table + ` WHERE event_id IN (SELECT id FROM events WHERE ` + where + `);`
+			if _, err := tx.conn().Exec(query, NamedArgs(args)...); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.conn().Exec(`DELETE FROM events WHERE `+where+`;`, NamedArgs(args)...)
+		if err != nil {
+			return err
+		}
+
+		deleted, err = result.RowsAffected()
+		return err
+	})
+
+	return deleted, err
+}
+
+// ForgetUser erases the user from every chat: their seats, answers and guests,
+// their co-host roles and their preferences. The events they created are kept
+// without their name. It returns the IDs of the events that changed.
+func (d *Database) ForgetUser(userID int64, name string) ([]string, error) {
+	eventIDs := []string{}
+	args := map[string]any{
+		"user_id": userID,
+		"name":    name,
+	}
+
+	err := d.inTransaction(func(tx *Database) error {
+		rows, err := tx.conn().Query(`SELECT event_id FROM participants WHERE user_id = @user_id
+		UNION SELECT event_id FROM event_cohosts WHERE user_id = @user_id
+		UNION SELECT id FROM events WHERE user_id = @user_id;`, NamedArgs(args)...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var eventID string
+			if err = rows.Scan(&eventID); err != nil {
+				rows.Close()
+				return err
+			}
+			eventIDs = append(eventIDs, eventID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		queries := []string{
+			`DELETE FROM participants WHERE user_id = @user_id;`,
+			`DELETE FROM event_cohosts WHERE user_id = @user_id;`,
+			`UPDATE events SET user_id = 0, user_name = @name WHERE user_id = @user_id;`,
+			`DELETE FROM users WHERE user_id = @user_id;`,
+		}
+		for _, query := range queries {
+			if _, err = tx.conn().Exec(query, NamedArgs(args)...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return eventIDs, nil
+}
+
+// PurgeChat deletes the events, the webhooks and the settings of the chat and
+// returns how many events were deleted.
+func (d *Database) PurgeChat(chatID int64) (int64, error) {
+	var deleted int64
+	args := map[string]any{
+		"chat_id": chatID,
+	}
+
+	err := d.inTransaction(func(tx *Database) error {
+		var err error
+		if deleted, err = tx.deleteEvents(`chat_id = @chat_id`, args); err != nil {
+			return err
+		}
+
+		for _, table := range []string{"webhooks", "chats"} {
+			if _, err = tx.conn().Exec(`DELETE FROM `+table+` WHERE chat_id = @chat_id;`, NamedArgs(args)...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// GetNotificationPreferences returns the preferences of the user, every
+// notification is enabled until the user changes it.
+func (d *Database) GetNotificationPreferences(userID int64) models.NotificationPreferences {
+	query := `SELECT notify_waitlist, notify_cancellations, notify_reminders FROM users WHERE user_id = @user_id;`
+
+	var preferences models.NotificationPreferences
+	if err := d.conn().QueryRow(query,
+		NamedArgs(map[string]any{
+			"user_id": userID,
+		})...,
+	).Scan(&preferences.Waitlist, &preferences.Cancellations, &preferences.Reminders); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Default().Println("failed to load notification preferences:", err)
+		}
+		return models.DefaultNotificationPreferences()
+	}
+
+	return preferences
+}
+
+func (d *Database) SetNotificationPreferences(userID int64, preferences models.NotificationPreferences) error {
+	query := `
+		INSERT INTO users (user_id, notify_waitlist, notify_cancellations, notify_reminders)
+		VALUES (@user_id, @notify_waitlist, @notify_cancellations, @notify_reminders)
+		ON CONFLICT(user_id) DO UPDATE SET
+			notify_waitlist = EXCLUDED.notify_waitlist,
+			notify_cancellations = EXCLUDED.notify_cancellations,
+			notify_reminders = EXCLUDED.notify_reminders;
+	`
+
+	if _, err := d.conn().Exec(query,
+		NamedArgs(map[string]any{
+			"user_id":              userID,
+			"notify_waitlist":      preferences.Waitlist,
+			"notify_cancellations": preferences.Cancellations,
+			"notify_reminders":     preferences.Reminders,
+		})...,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *Database) InsertWebhook(chatID int64, threadID *int64, url, secret string, format models.WebhookFormat) (*int64, *string, error) {
+	query := `INSERT INTO webhooks (uuid, chat_id, thread_id, url, secret, format) VALUES (@uuid, @chat_id, @thread_id, @url, @secret, @format) RETURNING id;`
 	var id int64
 	uuidV := uuid.New().String()
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"uuid":      uuidV,
 			"chat_id":   chatID,
 			"thread_id": threadID,
 			"url":       url,
 			"secret":    secret,
+			"format":    format,
 		})...,
 	).Scan(&id); err != nil {
 		return nil, nil, err
@@ -946,7 +2085,7 @@ func (d *Database) InsertWebhook(chatID int64, threadID *int64, url, secret stri
 func (d *Database) RemoveWebhook(webhookID int64) error {
 	query := `DELETE FROM webhooks WHERE id = @id;`
 
-	if _, err := d.db.Exec(query,
+	if _, err := d.conn().Exec(query,
 		NamedArgs(map[string]any{
 			"id": webhookID,
 		})...,
@@ -958,9 +2097,9 @@ func (d *Database) RemoveWebhook(webhookID int64) error {
 }
 
 func (d *Database) GetWebhooksByChatID(chatID int64) ([]models.Webhook, error) {
-	query := `SELECT id, uuid, chat_id, thread_id, url, secret, created_at FROM webhooks WHERE chat_id = @chat_id;`
+	query := `SELECT id, uuid, chat_id, thread_id, url, secret, format, created_at FROM webhooks WHERE chat_id = @chat_id;`
 
-	rows, err := d.db.Query(query,
+	rows, err := d.conn().Query(query,
 		NamedArgs(map[string]any{
 			"chat_id": chatID,
 		})...,
@@ -985,6 +2124,7 @@ func (d *Database) GetWebhooksByChatID(chatID int64) ([]models.Webhook, error) {
 			&webhook.ThreadID,
 			&webhook.Url,
 			&webhook.Secret,
+			&webhook.Format,
 			&webhook.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -1001,10 +2141,10 @@ func (d *Database) GetWebhooksByChatID(chatID int64) ([]models.Webhook, error) {
 }
 
 func (d *Database) GetWebhookByWebhookID(webhookID string) (*models.Webhook, error) {
-	query := `SELECT id, uuid, chat_id, thread_id, url, secret, created_at FROM webhooks WHERE uuid = @uuid;`
+	query := `SELECT id, uuid, chat_id, thread_id, url, secret, format, created_at FROM webhooks WHERE uuid = @uuid;`
 
 	var webhook models.Webhook
-	if err := d.db.QueryRow(query,
+	if err := d.conn().QueryRow(query,
 		NamedArgs(map[string]any{
 			"uuid": webhookID,
 		})...,
@@ -1015,6 +2155,7 @@ func (d *Database) GetWebhookByWebhookID(webhookID string) (*models.Webhook, err
 		&webhook.ThreadID,
 		&webhook.Url,
 		&webhook.Secret,
+		&webhook.Format,
 		&webhook.CreatedAt,
 	); err != nil {
 		return nil, ParseError(err)
@@ -0,0 +1,96 @@
+package hooks
+
+import (
+	"boardgame-night-bot/src/models"
+	"encoding/json"
+	"time"
+)
+
+// webhookMessage is an encoded delivery, ready to be signed and sent.
+type webhookMessage struct {
+	body        []byte
+	contentType string
+	headers     map[string]string
+}
+
+// encodeWebhook serialises payload in the format chosen by the webhook. The
+// CloudEvents id reuses the delivery idempotency key, so retries of the same
+// delivery carry the same event id.
+func encodeWebhook(format models.WebhookFormat, source, id string, at time.Time, payload models.HookWebhookEnvelope) (*webhookMessage, error) {
+	switch format {
+	case models.WebhookFormatCloudEvents:
+		body, err := json.Marshal(NewCloudEvent(source, id, at, payload))
+		if err != nil {
+			return nil, err
+		}
+
+		return &webhookMessage{body: body, contentType: "application/cloudevents+json"}, nil
+	case models.WebhookFormatCloudEventsBinary:
+		event := NewCloudEvent(source, id, at, payload)
+		body, err := json.Marshal(event.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		headers := map[string]string{
+			"ce-specversion": event.SpecVersion,
+			"ce-id":          event.ID,
+			"ce-source":      event.Source,
+			"ce-type":        event.Type,
+			"ce-time":        event.Time.Format(time.RFC3339Nano),
+		}
+		if event.Subject != "" {
+			headers["ce-subject"] = event.Subject
+		}
+
+		return &webhookMessage{body: body, contentType: event.DataContentType, headers: headers}, nil
+	default:
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		return &webhookMessage{body: body, contentType: "application/json"}, nil
+	}
+}
+
+// NewCloudEvent wraps payload in a CloudEvents 1.0 event. The subject is the
+// ID of the event the notification refers to, when there is one.
+func NewCloudEvent(source, id string, at time.Time, payload models.HookWebhookEnvelope) models.CloudEvent {
+	return models.CloudEvent{
+		SpecVersion:     models.CloudEventsSpecVersion,
+		ID:              id,
+		Source:          source,
+		Type:            models.CloudEventsTypePrefix + string(payload.Type),
+		Subject:         eventSubject(payload),
+		Time:            at.UTC(),
+		DataContentType: "application/json",
+		Data:            payload.Data,
+	}
+}
+
+// eventSubject extracts the event ID from the payload data: event_id for
+// game and participant payloads, id for the event payloads themselves.
+func eventSubject(payload models.HookWebhookEnvelope) string {
+	raw, err := json.Marshal(payload.Data)
+	if err != nil {
+		return ""
+	}
+
+	var fields map[string]any
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return ""
+	}
+
+	if eventID, ok := fields["event_id"].(string); ok {
+		return eventID
+	}
+
+	if payload.Type == models.HookWebhookTypeNewEvent {
+		if eventID, ok := fields["id"].(string); ok {
+			return eventID
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,110 @@
+package hooks
+
+import (
+	"boardgame-night-bot/src/models"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+var testPayload = models.HookWebhookEnvelope{
+	Type: models.HookWebhookTypeAddParticipant,
+	Data: models.HookAddParticipantPayload{
+		ID:       "participant-id",
+		EventID:  "event-id",
+		UserID:   789,
+		GameID:   "game-id",
+		UserName: "user",
+	},
+}
+
+var testTime = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestEncodeDefaultFormat(t *testing.T) {
+	msg, err := encodeWebhook(models.WebhookFormatDefault, "https://example.com", "delivery-id", testTime, testPayload)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if msg.contentType != "application/json" {
+		t.Errorf("Expected content type application/json, got %s", msg.contentType)
+	}
+
+	var envelope models.HookWebhookEnvelope
+	if err = json.Unmarshal(msg.body, &envelope); err != nil {
+		t.Fatalf("Expected envelope body, got %v", err)
+	}
+	if envelope.Type != models.HookWebhookTypeAddParticipant {
+		t.Errorf("Expected type add_participant, got %s", envelope.Type)
+	}
+}
+
+func TestEncodeCloudEventsStructured(t *testing.T) {
+	msg, err := encodeWebhook(models.WebhookFormatCloudEvents, "https://example.com", "delivery-id", testTime, testPayload)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if msg.contentType != "application/cloudevents+json" {
+		t.Errorf("Expected content type application/cloudevents+json, got %s", msg.contentType)
+	}
+
+	var event models.CloudEvent
+	if err = json.Unmarshal(msg.body, &event); err != nil {
+		t.Fatalf("Expected cloud event body, got %v", err)
+	}
+	if event.SpecVersion != "1.0" {
+		t.Errorf("Expected specversion 1.0, got %s", event.SpecVersion)
+	}
+	if event.ID != "delivery-id" {
+		t.Errorf("Expected id delivery-id, got %s", event.ID)
+	}
+	if event.Source != "https://example.com" {
+		t.Errorf("Expected source https://example.com, got %s", event.Source)
+	}
+	if event.Type != "org.boardgamenight.add_participant" {
+		t.Errorf("Expected type org.boardgamenight.add_participant, got %s", event.Type)
+	}
+	if event.Subject != "event-id" {
+		t.Errorf("Expected subject event-id, got %s", event.Subject)
+	}
+	if !event.Time.Equal(testTime) {
+		t.Errorf("Expected time %v, got %v", testTime, event.Time)
+	}
+}
+
+func TestEncodeCloudEventsBinary(t *testing.T) {
+	msg, err := encodeWebhook(models.WebhookFormatCloudEventsBinary, "https://example.com", "delivery-id", testTime, testPayload)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if msg.headers["ce-type"] != "org.boardgamenight.add_participant" {
+		t.Errorf("Expected ce-type header, got %q", msg.headers["ce-type"])
+	}
+	if msg.headers["ce-subject"] != "event-id" {
+		t.Errorf("Expected ce-subject event-id, got %q", msg.headers["ce-subject"])
+	}
+	if msg.headers["ce-time"] != "2025-01-02T03:04:05Z" {
+		t.Errorf("Expected ce-time 2025-01-02T03:04:05Z, got %q", msg.headers["ce-time"])
+	}
+
+	var data models.HookAddParticipantPayload
+	if err = json.Unmarshal(msg.body, &data); err != nil {
+		t.Fatalf("Expected payload body, got %v", err)
+	}
+	if data.EventID != "event-id" {
+		t.Errorf("Expected event_id event-id, got %s", data.EventID)
+	}
+}
+
+func TestNewEventSubjectIsEventID(t *testing.T) {
+	event := NewCloudEvent("https://example.com", "delivery-id", testTime, models.HookWebhookEnvelope{
+		Type: models.HookWebhookTypeNewEvent,
+		Data: models.HookNewEventPayload{ID: "event-id", Name: "event"},
+	})
+
+	if event.Subject != "event-id" {
+		t.Errorf("Expected subject event-id, got %s", event.Subject)
+	}
+}
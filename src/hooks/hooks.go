@@ -9,7 +9,6 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -17,6 +16,7 @@ import (
 	"time"
 
 	"github.com/bluele/gcache"
+	"github.com/google/uuid"
 )
 
 // maxConcurrentDispatches is the maximum number of webhook HTTP calls that can
@@ -24,9 +24,23 @@ import (
 // issuing requests immediately, bounding memory and connection usage.
 const maxConcurrentDispatches = 20
 
+// IdempotencyKeyHeader carries a unique key per webhook delivery. It is signed
+// together with the date and the content hash by the v2 signature.
+const IdempotencyKeyHeader = "X-BGNB-Idempotency-Key"
+
+const (
+	// SignatureHeader carries the original signature, over the date and the
+	// content hash only, so that existing receivers keep verifying it.
+	SignatureHeader = "X-BGNB-Signature"
+	// SignatureV2Header carries the signature that also covers the
+	// idempotency key. Receivers opt in by verifying it instead.
+	SignatureV2Header = "X-BGNB-Signature-V2"
+)
+
 // WebhookClient wraps an HTTP client with configurable timeout and retry logic.
 type WebhookClient struct {
 	DB                 *database.Database
+	Source             string
 	Client             *http.Client
 	MaxAttempt         int
 	FailureCache       gcache.Cache
@@ -35,10 +49,12 @@ type WebhookClient struct {
 }
 
 // NewWebhookClient creates a new WebhookClient with the given timeout (TTL) and max attempts.
-func NewWebhookClient(db *database.Database, timeout time.Duration, maxAttempt int, failureExpiration time.Duration, maxFailureAttempt int) *WebhookClient {
+// source is the bot base URL, used as CloudEvents source.
+func NewWebhookClient(db *database.Database, source string, timeout time.Duration, maxAttempt int, failureExpiration time.Duration, maxFailureAttempt int) *WebhookClient {
 	failureCache := gcache.New(1000).LRU().Expiration(failureExpiration).Build()
 	return &WebhookClient{
 		DB:                 db,
+		Source:             source,
 		Client:             &http.Client{Timeout: timeout},
 		MaxAttempt:         maxAttempt,
 		FailureCache:       failureCache,
@@ -69,11 +85,20 @@ func (wc *WebhookClient) SendWebhookAsync(ctx context.Context, chatID int64, w m
 }
 
 // SendWebhookWithRetry sends the webhook event with retry logic, signing the payload with the new signature scheme.
+// The payload is encoded once in the webhook format and all attempts share the same idempotency key, so the
+// receiver can discard duplicates.
 func (wc *WebhookClient) SendWebhookWithRetry(ctx context.Context, chatID int64, w models.Webhook, payload models.HookWebhookEnvelope, secret string) error {
 	var lastErr error
+	idempotencyKey := uuid.New().String()
+	msg, err := encodeWebhook(w.Format, wc.Source, idempotencyKey, time.Now(), payload)
+	if err != nil {
+		wc.registerFailure(w.UUID)
+		return err
+	}
+
 	for attempt := 1; attempt <= wc.MaxAttempt; attempt++ {
 		log.Default().Printf("In chat %d, attempt %d to send webhook to %s", chatID, attempt, w.Url)
-		if err := wc.sendWebhook(ctx, w, payload, secret); err != nil {
+		if err := wc.sendWebhook(ctx, w, msg, secret, idempotencyKey); err != nil {
 			lastErr = err
 			time.Sleep(time.Second * time.Duration(1<<uint(attempt-1))) // Exponential backoff
 			continue
@@ -104,16 +129,12 @@ func (wc *WebhookClient) shouldDiscard(webhookID string) bool {
 }
 
 // sendWebhook performs the actual HTTP POST request, signing the payload with the new signature scheme.
-func (wc *WebhookClient) sendWebhook(ctx context.Context, w models.Webhook, payload models.HookWebhookEnvelope, secret string) error {
+func (wc *WebhookClient) sendWebhook(ctx context.Context, w models.Webhook, msg *webhookMessage, secret, idempotencyKey string) error {
 	if wc.shouldDiscard(w.UUID) {
 		log.Default().Printf("Discarding webhook %s due to repeated failures", w.UUID)
 		return errors.New("webhook discarded due to repeated failures")
 	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		wc.registerFailure(w.UUID)
-		return err
-	}
+	body := msg.body
 
 	// Compute content hash (SHA256, hex-encoded)
 	contentHashBytes := sha256.Sum256(body)
@@ -122,11 +143,9 @@ func (wc *WebhookClient) sendWebhook(ctx context.Context, w models.Webhook, payl
 	// Use current UTC time in RFC1123 format for x-ms-date
 	date := time.Now().UTC().Format(http.TimeFormat)
 
-	// Build string to sign
-	stringToSign := fmt.Sprintf("%s;%s", date, contentHash)
-
-	// Compute HMAC signature (base64-encoded)
-	signature := ComputeHMACBase64(stringToSign, []byte(secret))
+	// Compute HMAC signatures (base64-encoded)
+	signature := ComputeHMACBase64(LegacyStringToSign(date, contentHash), []byte(secret))
+	signatureV2 := ComputeHMACBase64(StringToSign(date, contentHash, idempotencyKey), []byte(secret))
 
 	// Prepare request
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Url, bytes.NewReader(body))
@@ -134,10 +153,15 @@ func (wc *WebhookClient) sendWebhook(ctx context.Context, w models.Webhook, payl
 		wc.registerFailure(w.UUID)
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", msg.contentType)
+	for key, value := range msg.headers {
+		req.Header.Set(key, value)
+	}
 	req.Header.Set("x-ms-date", date)
 	req.Header.Set("x-ms-content-sha256", contentHash)
-	req.Header.Set("X-BGNB-Signature", signature)
+	req.Header.Set(SignatureHeader, signature)
+	req.Header.Set(SignatureV2Header, signatureV2)
+	req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
 
 	resp, err := wc.Client.Do(req)
 	if err != nil {
@@ -153,6 +177,17 @@ func (wc *WebhookClient) sendWebhook(ctx context.Context, w models.Webhook, payl
 	return nil
 }
 
+// LegacyStringToSign builds the string covered by the original signature.
+func LegacyStringToSign(date, contentHash string) string {
+	return fmt.Sprintf("%s;%s", date, contentHash)
+}
+
+// StringToSign builds the string covered by the v2 signature. The idempotency
+// key is part of it so that a captured request cannot be replayed with a fresh key.
+func StringToSign(date, contentHash, idempotencyKey string) string {
+	return fmt.Sprintf("%s;%s;%s", date, contentHash, idempotencyKey)
+}
+
 // ComputeHMACBase64 generates the HMAC SHA256 signature for the stringToSign and encodes it in base64.
 func ComputeHMACBase64(stringToSign string, secret []byte) string {
 	h := hmac.New(sha256.New, secret)
@@ -8,12 +8,15 @@ import (
 	"boardgame-night-bot/src/telegram"
 	"boardgame-night-bot/src/web"
 	"boardgame-night-bot/src/web/api"
+	"boardgame-night-bot/src/web/tgwebhook"
 	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"syscall"
 	_ "time/tzdata"
@@ -25,6 +28,7 @@ import (
 	"github.com/DangerBlack/gobgg"
 
 	"github.com/BurntSushi/toml"
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -33,6 +37,8 @@ import (
 	"gopkg.in/telebot.v3"
 )
 
+var telegramSecretRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
 func callHealthCheck(url string) func() {
 	return func() {
 		resp, err := http.Get(url)
@@ -65,6 +71,72 @@ func InitHealthCheck(url string) {
 	log.Default().Println("cron job started...")
 }
 
+// InitJobs schedules the periodic maintenance of the events.
+func InitJobs(service *api.Service) {
+	c := cron.New()
+	if _, err := c.AddFunc("@every 10m", func() { service.UnpinEndedEvents(time.Now()) }); err != nil {
+		log.Fatal("error scheduling unpin job:", err)
+	}
+	if _, err := c.AddFunc("@every 10m", func() { service.CloseEndedEventTopics(time.Now()) }); err != nil {
+		log.Fatal("error scheduling topic job:", err)
+	}
+	if _, err := c.AddFunc("@every 10m", func() { service.RemindMaybeUsers(time.Now()) }); err != nil {
+		log.Fatal("error scheduling maybe reminder job:", err)
+	}
+	if _, err := c.AddFunc("@every 10m", func() { service.PostFinalLineups(time.Now()) }); err != nil {
+		log.Fatal("error scheduling final lineup job:", err)
+	}
+	if _, err := c.AddFunc("@every 10m", func() { service.ArchiveEndedEvents(time.Now()) }); err != nil {
+		log.Fatal("error scheduling archive job:", err)
+	}
+	if _, err := c.AddFunc("@daily", func() { service.ApplyRetention(time.Now()) }); err != nil {
+		log.Fatal("error scheduling retention job:", err)
+	}
+
+	c.Start()
+	log.Default().Println("event jobs started...")
+}
+
+// InitBackups schedules a hot backup of the database into dir, keeping the
+// latest keep backups. A keep of 0 disables the backups.
+func InitBackups(db *database.Database, dir, schedule string, keep int) {
+	if keep == 0 {
+		log.Default().Println("database backups are disabled")
+		return
+	}
+
+	c := cron.New()
+	if _, err := c.AddFunc(schedule, func() {
+		path, err := db.Backup(dir, keep, time.Now())
+		if err != nil {
+			log.Default().Println("failed to back up the database:", err)
+			return
+		}
+		log.Default().Printf("database backed up to %s", path)
+	}); err != nil {
+		log.Fatal("error scheduling backup job:", err)
+	}
+
+	c.Start()
+	log.Default().Println("backup job started...")
+}
+
+// restore replaces the database with the backup given as argument, or with
+// the latest one, and exits: ./boardgame_night_bot restore [backup].
+func restore(args []string, dbPath, backupDir string) {
+	backupPath := ""
+	if len(args) > 0 {
+		backupPath = args[0]
+	}
+
+	restored, err := database.RestoreBackup(dbPath, backupDir, backupPath)
+	if err != nil {
+		log.Fatal("failed to restore the database:", err)
+	}
+
+	log.Default().Printf("database restored from %s", restored)
+}
+
 func StringOrDefault(s, defaultValue string) string {
 	if s == "" {
 		return defaultValue
@@ -97,6 +169,14 @@ func main() {
 		log.Default().Printf("warn loading .env file: %v", err)
 	}
 
+	dbPath := StringOrDefault(os.Getenv("DB_PATH"), "./archive")
+	backupDir := StringOrDefault(os.Getenv("BACKUP_DIR"), filepath.Join(dbPath, "backups"))
+
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		restore(os.Args[2:], dbPath, backupDir)
+		return
+	}
+
 	botToken := os.Getenv("TOKEN")
 	if botToken == "" {
 		log.Fatal("the TOKEN is not set in .env file")
@@ -150,7 +230,36 @@ func main() {
 		log.Fatal("the MAX_FAILURE_ATTEMPTS is not set in .env file or is not a valid number")
 	}
 
-	dbPath := StringOrDefault(os.Getenv("DB_PATH"), "./archive")
+	telegramWebhookSecret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	if os.Getenv("TELEGRAM_MODE") == "webhook" && !telegramSecretRegex.MatchString(telegramWebhookSecret) {
+		log.Fatal("the TELEGRAM_WEBHOOK_SECRET is not set in .env file or is not valid (1-256 characters among A-Z, a-z, 0-9, _ and -)")
+	}
+
+	refreshWindowString := StringOrDefault(os.Getenv("MESSAGE_REFRESH_WINDOW"), "1s")
+	refreshWindowDuration, err := time.ParseDuration(refreshWindowString)
+	if err != nil {
+		log.Fatal("the MESSAGE_REFRESH_WINDOW is not set in .env file or is not a valid duration")
+	}
+
+	anonymizeAfterString := StringOrDefault(os.Getenv("ANONYMIZE_AFTER_MONTHS"), "0")
+	anonymizeAfter, err := strconv.Atoi(anonymizeAfterString)
+	if err != nil || anonymizeAfter < 0 {
+		log.Fatal("the ANONYMIZE_AFTER_MONTHS is not set in .env file or is not a valid number")
+	}
+
+	deleteAfterString := StringOrDefault(os.Getenv("DELETE_AFTER_MONTHS"), "0")
+	deleteAfter, err := strconv.Atoi(deleteAfterString)
+	if err != nil || deleteAfter < 0 {
+		log.Fatal("the DELETE_AFTER_MONTHS is not set in .env file or is not a valid number")
+	}
+
+	backupSchedule := StringOrDefault(os.Getenv("BACKUP_SCHEDULE"), "@daily")
+
+	backupKeepString := StringOrDefault(os.Getenv("BACKUP_KEEP"), "7")
+	backupKeep, err := strconv.Atoi(backupKeepString)
+	if err != nil || backupKeep < 0 {
+		log.Fatal("the BACKUP_KEEP is not set in .env file or is not a valid number")
+	}
 
 	db := database.NewDatabase(dbPath)
 
@@ -158,6 +267,10 @@ func main() {
 
 	log.Default().Println("database connection established.")
 
+	if err = db.IntegrityCheck(); err != nil {
+		log.Fatalf("the database is corrupted, restore a backup with `boardgame_night_bot restore`: %v", err)
+	}
+
 	db.CreateTables()
 	db.MigrateToV1()
 	db.MigrateToV2()
@@ -165,19 +278,52 @@ func main() {
 	db.MigrateToV4()
 	db.MigrateToV5()
 	db.MigrateToV6()
+	db.MigrateToV7()
+	db.MigrateToV8()
+	db.MigrateToV9()
+	db.MigrateToV10()
+	db.MigrateToV11()
+	db.MigrateToV12()
+	db.MigrateToV13()
+	db.MigrateToV14()
+	db.MigrateToV15()
+	db.MigrateToV16()
+
+	InitBackups(db, backupDir, backupSchedule, backupKeep)
+
+	allowedUpdates := []string{"message", "callback_query", "inline_query"}
+
+	var poller telebot.Poller
+	var telegramWebhook gin.HandlerFunc
+	telegramMode := StringOrDefault(os.Getenv("TELEGRAM_MODE"), "polling")
+	switch telegramMode {
+	case "polling":
+		poller = &telebot.LongPoller{
+			Timeout:        10 * time.Second,
+			AllowedUpdates: allowedUpdates,
+		}
+	case "webhook":
+		poller = tgwebhook.NewPoller(baseUrl, telegramWebhookSecret, allowedUpdates)
+	default:
+		log.Fatal("the TELEGRAM_MODE must be either polling or webhook")
+	}
 
 	bot, err := telebot.NewBot(telebot.Settings{
 		Token:     botToken,
 		ParseMode: telebot.ModeHTML,
-		Poller: &telebot.LongPoller{
-			Timeout:        10 * time.Second,
-			AllowedUpdates: []string{"message", "callback_query"},
-		},
+		Poller:    poller,
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if telegramMode == "webhook" {
+		telegramWebhook = tgwebhook.GinHandler(bot, telegramWebhookSecret)
+	} else if err = bot.RemoveWebhook(); err != nil {
+		// getUpdates is rejected while a webhook is still registered
+		log.Default().Println("failed to remove telegram webhook:", err)
+	}
+
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 	}
@@ -188,13 +334,21 @@ func main() {
 
 	bggService := bgg.NewBGGService(bggClient)
 
-	wh := hooks.NewWebhookClient(db, httpTimeoutDuration, httpMaxAttempt, failureExpirationDuration, maxFailureAttempts)
+	wh := hooks.NewWebhookClient(db, baseUrl, httpTimeoutDuration, httpMaxAttempt, failureExpirationDuration, maxFailureAttempts)
 
-	service := api.NewService(db, bggService, bot, bundle, models.WebUrl{
+	service := api.NewService(db, bggService, bot, wh, bundle, models.WebUrl{
 		BotMiniAppURL: botMiniAppURL,
 		BaseUrl:       baseUrl,
 	})
 
+	service.Refresher = api.NewMessageRefresher(bot, refreshWindowDuration)
+	service.Retention = models.RetentionPolicy{
+		AnonymizeAfterMonths: anonymizeAfter,
+		DeleteAfterMonths:    deleteAfter,
+	}
+
+	InitJobs(service)
+
 	telegram := telegram.Telegram{
 		Bot:            bot,
 		DB:             db,
@@ -212,9 +366,13 @@ func main() {
 
 	telegram.SetupHandlers()
 
+	if err = telegram.RegisterCommands(); err != nil {
+		log.Default().Println("failed to register bot commands:", err)
+	}
+
 	go func() {
 		log.Default().Println("server started")
-		web.StartServer(port, db, bggService, bot, bundle, wh, service)
+		web.StartServer(port, db, bggService, bot, bundle, wh, service, telegramWebhook)
 		log.Default().Println("server stopped")
 	}()
 	go func() {
@@ -7,16 +7,48 @@ import (
 )
 
 type MockDatabase struct {
-	InsertEventFunc                        func(id *string, chatID, userID int64, userName, name string, messageID *int64, location *string, startsAt *time.Time) (string, error)
-	InsertEventWithOptionalGameFunc        func(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, addPlayerCounter bool) (string, error)
-	InsertBoardGameFunc                    func(eventID string, id *string, name string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error)
-	UpdateBoardGameBGGInfoByIDFunc func(ID int64, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) error
-	UpdateEventMessageIDFunc       func(eventID string, messageID int64) error
-	DeleteBoardGameByIDFunc        func(ID string) error
-	SelectEventByEventIDFunc       func(eventID string) (*models.Event, error)
-	DeleteEventFunc                func(id string) error
-	InsertParticipantFunc          func(id *string, eventID string, boardgameID, userID int64, userName string, isTelegramUsername bool) (string, error)
-	RemoveParticipantFunc          func(eventID string, userID int64) (string, int64, error)
+	InsertEventFunc                 func(id *string, chatID, userID int64, userName, name string, messageID *int64, location *string, startsAt *time.Time) (string, error)
+	InsertEventWithOptionalGameFunc func(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, locked, addPlayerCounter bool) (string, error)
+	InsertBoardGameFunc             func(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error)
+	UpdateBoardGameBGGInfoByIDFunc  func(ID int64, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) error
+	UpdateBoardGameSlotFunc         func(ID int64, slot string) error
+	UpdateEventMessageIDFunc        func(eventID string, messageID int64) error
+	UpdateEventLockedFunc           func(eventID string, locked bool) error
+	AddEventCoHostFunc              func(eventID string, userID int64, userName string) error
+	RemoveEventCoHostFunc           func(eventID string, userID int64) (bool, error)
+	DeleteBoardGameByIDFunc         func(ID string) error
+	SelectGameIDByGameUUIDFunc      func(gameUUID string) (int64, error)
+	SelectEventByEventIDFunc        func(eventID string) (*models.Event, error)
+	SelectEventsByUserIDFunc        func(userID int64, limit int) ([]models.Event, error)
+	SelectEventIDByMessageIDFunc    func(chatID, messageID int64) (string, error)
+	DeleteEventFunc                 func(id string) error
+	InsertParticipantFunc           func(id *string, eventID string, boardgameID, userID int64, userName string, isTelegramUsername bool) (string, error)
+	RemoveParticipantFunc           func(eventID string, userID int64, boardgameID *int64) (string, int64, error)
+	MoveParticipantFunc             func(eventID string, userID, fromBoardgameID, toBoardgameID int64) error
+	RemoveRSVPFunc                  func(eventID string, userID int64) error
+	WithTransactionFunc             func(fn func(db database.DatabaseService) error) error
+	SetAutoPinFunc                  func(chatID int64, enabled bool) error
+	GetAutoPinFunc                  func(chatID int64) bool
+	UpdateEventPinnedFunc           func(eventID string, pinned bool) error
+	SelectPinnedEventsFunc          func() ([]models.Event, error)
+	GetEventTopicsFunc              func(chatID int64) bool
+	UpdateEventTopicFunc            func(eventID string, topicID int64) error
+	CloseEventTopicFunc             func(eventID string) error
+	SelectOpenTopicEventsFunc       func() ([]models.Event, error)
+	GetNotificationPreferencesFunc  func(userID int64) models.NotificationPreferences
+	UpdateParticipantGuestsFunc     func(eventID string, userID, boardgameID int64, guests []string) error
+	SetParticipantRSVPFunc          func(id *string, eventID string, userID int64, userName string, isTelegramUsername bool, status models.RSVPStatus) (string, error)
+	SelectMaybeEventsToRemindFunc   func() ([]models.Event, error)
+	SetMaybeRemindedFunc            func(eventID string) error
+	UpdateEventRSVPDeadlineFunc     func(eventID string, deadline *time.Time) error
+	SelectPendingLineupEventsFunc   func() ([]models.Event, error)
+	SetLineupPostedFunc             func(eventID string) error
+	SelectEventsToArchiveFunc       func() ([]models.Event, error)
+	SetEventFinishedFunc            func(eventID string) error
+	AnonymizeEventsBeforeFunc       func(cutoff time.Time, name string) (int64, error)
+	DeleteEventsBeforeFunc          func(cutoff time.Time) (int64, error)
+	ForgetUserFunc                  func(userID int64, name string) ([]string, error)
+	PurgeChatFunc                   func(chatID int64) (int64, error)
 }
 
 func NewMockDatabase() *MockDatabase {
@@ -34,9 +66,9 @@ func (m *MockDatabase) InsertEvent(id *string, chatID, userID int64, userName, n
 	return "mock-event-id", nil
 }
 
-func (m *MockDatabase) InsertEventWithOptionalGame(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, addPlayerCounter bool) (string, error) {
+func (m *MockDatabase) InsertEventWithOptionalGame(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, locked, addPlayerCounter bool) (string, error) {
 	if m.InsertEventWithOptionalGameFunc != nil {
-		return m.InsertEventWithOptionalGameFunc(id, chatID, userID, userName, name, location, startsAt, addPlayerCounter)
+		return m.InsertEventWithOptionalGameFunc(id, chatID, userID, userName, name, location, startsAt, locked, addPlayerCounter)
 	}
 	return "mock-event-id", nil
 }
@@ -52,6 +84,20 @@ func (m *MockDatabase) SelectEventByEventID(eventID string) (*models.Event, erro
 	return &models.Event{ID: eventID, Name: "Mock Event", ChatID: 12345}, nil
 }
 
+func (m *MockDatabase) SelectEventsByUserID(userID int64, limit int) ([]models.Event, error) {
+	if m.SelectEventsByUserIDFunc != nil {
+		return m.SelectEventsByUserIDFunc(userID, limit)
+	}
+	return []models.Event{}, nil
+}
+
+func (m *MockDatabase) SelectEventIDByMessageID(chatID, messageID int64) (string, error) {
+	if m.SelectEventIDByMessageIDFunc != nil {
+		return m.SelectEventIDByMessageIDFunc(chatID, messageID)
+	}
+	return "", database.ErrNoRows
+}
+
 func (m *MockDatabase) DeleteEvent(id string) error {
 	if m.DeleteEventFunc != nil {
 		return m.DeleteEventFunc(id)
@@ -59,9 +105,9 @@ func (m *MockDatabase) DeleteEvent(id string) error {
 	return nil
 }
 
-func (m *MockDatabase) InsertBoardGame(eventID string, id *string, name string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
+func (m *MockDatabase) InsertBoardGame(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
 	if m.InsertBoardGameFunc != nil {
-		return m.InsertBoardGameFunc(eventID, id, name, maxPlayers, bggID, bggName, bggUrl, bggImageUrl)
+		return m.InsertBoardGameFunc(eventID, id, name, slot, maxPlayers, bggID, bggName, bggUrl, bggImageUrl)
 	}
 	return 1, "mock-game-uuid", nil
 }
@@ -72,6 +118,27 @@ func (m *MockDatabase) UpdateEventMessageID(eventID string, messageID int64) err
 	}
 	return nil
 }
+
+func (m *MockDatabase) UpdateEventLocked(eventID string, locked bool) error {
+	if m.UpdateEventLockedFunc != nil {
+		return m.UpdateEventLockedFunc(eventID, locked)
+	}
+	return nil
+}
+
+func (m *MockDatabase) AddEventCoHost(eventID string, userID int64, userName string) error {
+	if m.AddEventCoHostFunc != nil {
+		return m.AddEventCoHostFunc(eventID, userID, userName)
+	}
+	return nil
+}
+
+func (m *MockDatabase) RemoveEventCoHost(eventID string, userID int64) (bool, error) {
+	if m.RemoveEventCoHostFunc != nil {
+		return m.RemoveEventCoHostFunc(eventID, userID)
+	}
+	return true, nil
+}
 func (m *MockDatabase) UpdateBoardGameBGGInfoByID(ID int64, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) error {
 	if m.UpdateBoardGameBGGInfoByIDFunc != nil {
 		return m.UpdateBoardGameBGGInfoByIDFunc(ID, maxPlayers, bggID, bggName, bggUrl, bggImageUrl)
@@ -79,6 +146,13 @@ func (m *MockDatabase) UpdateBoardGameBGGInfoByID(ID int64, maxPlayers int, bggI
 	return nil
 }
 
+func (m *MockDatabase) UpdateBoardGameSlot(ID int64, slot string) error {
+	if m.UpdateBoardGameSlotFunc != nil {
+		return m.UpdateBoardGameSlotFunc(ID, slot)
+	}
+	return nil
+}
+
 func (m *MockDatabase) InsertParticipant(id *string, eventID string, boardgameID, userID int64, userName string, isTelegramUsername bool) (string, error) {
 	if m.InsertParticipantFunc != nil {
 		return m.InsertParticipantFunc(id, eventID, boardgameID, userID, userName, isTelegramUsername)
@@ -86,18 +160,35 @@ func (m *MockDatabase) InsertParticipant(id *string, eventID string, boardgameID
 	return "mock-participant-uuid", nil
 }
 
-func (m *MockDatabase) RemoveParticipant(eventID string, userID int64) (string, int64, error) {
+func (m *MockDatabase) RemoveParticipant(eventID string, userID int64, boardgameID *int64) (string, int64, error) {
 	if m.RemoveParticipantFunc != nil {
-		return m.RemoveParticipantFunc(eventID, userID)
+		return m.RemoveParticipantFunc(eventID, userID, boardgameID)
 	}
 	return "mock-participant-uuid", 0, nil
 }
 
+func (m *MockDatabase) MoveParticipant(eventID string, userID, fromBoardgameID, toBoardgameID int64) error {
+	if m.MoveParticipantFunc != nil {
+		return m.MoveParticipantFunc(eventID, userID, fromBoardgameID, toBoardgameID)
+	}
+	return nil
+}
+
+func (m *MockDatabase) RemoveRSVP(eventID string, userID int64) error {
+	if m.RemoveRSVPFunc != nil {
+		return m.RemoveRSVPFunc(eventID, userID)
+	}
+	return nil
+}
+
 func (m *MockDatabase) HasBoardGameWithMessageID(messageID int64) bool {
 	return true
 }
 
 func (m *MockDatabase) SelectGameIDByGameUUID(gameUUID string) (int64, error) {
+	if m.SelectGameIDByGameUUIDFunc != nil {
+		return m.SelectGameIDByGameUUIDFunc(gameUUID)
+	}
 	return 1, nil
 }
 
@@ -125,7 +216,177 @@ func (m *MockDatabase) GetDefaultTimezoneLocation(chatID int64) *time.Location {
 	return loc
 }
 
-func (m *MockDatabase) InsertWebhook(chatID int64, threadID *int64, url, secret string) (*int64, *string, error) {
+func (m *MockDatabase) SetAutoPin(chatID int64, enabled bool) error {
+	if m.SetAutoPinFunc != nil {
+		return m.SetAutoPinFunc(chatID, enabled)
+	}
+	return nil
+}
+
+func (m *MockDatabase) GetAutoPin(chatID int64) bool {
+	if m.GetAutoPinFunc != nil {
+		return m.GetAutoPinFunc(chatID)
+	}
+	return false
+}
+
+func (m *MockDatabase) UpdateEventPinned(eventID string, pinned bool) error {
+	if m.UpdateEventPinnedFunc != nil {
+		return m.UpdateEventPinnedFunc(eventID, pinned)
+	}
+	return nil
+}
+
+func (m *MockDatabase) SelectPinnedEvents() ([]models.Event, error) {
+	if m.SelectPinnedEventsFunc != nil {
+		return m.SelectPinnedEventsFunc()
+	}
+	return []models.Event{}, nil
+}
+
+func (m *MockDatabase) SetEventTopics(chatID int64, enabled bool) error {
+	return nil
+}
+
+func (m *MockDatabase) GetEventTopics(chatID int64) bool {
+	if m.GetEventTopicsFunc != nil {
+		return m.GetEventTopicsFunc(chatID)
+	}
+	return false
+}
+
+func (m *MockDatabase) UpdateEventTopic(eventID string, topicID int64) error {
+	if m.UpdateEventTopicFunc != nil {
+		return m.UpdateEventTopicFunc(eventID, topicID)
+	}
+	return nil
+}
+
+func (m *MockDatabase) CloseEventTopic(eventID string) error {
+	if m.CloseEventTopicFunc != nil {
+		return m.CloseEventTopicFunc(eventID)
+	}
+	return nil
+}
+
+func (m *MockDatabase) SelectOpenTopicEvents() ([]models.Event, error) {
+	if m.SelectOpenTopicEventsFunc != nil {
+		return m.SelectOpenTopicEventsFunc()
+	}
+	return []models.Event{}, nil
+}
+
+func (m *MockDatabase) SelectEventsByParticipant(userID int64, limit int) ([]models.Event, error) {
+	return []models.Event{}, nil
+}
+
+func (m *MockDatabase) SelectEventsByChatID(chatID int64) ([]models.Event, error) {
+	return []models.Event{}, nil
+}
+
+func (m *MockDatabase) GetNotificationPreferences(userID int64) models.NotificationPreferences {
+	if m.GetNotificationPreferencesFunc != nil {
+		return m.GetNotificationPreferencesFunc(userID)
+	}
+	return models.DefaultNotificationPreferences()
+}
+
+func (m *MockDatabase) UpdateParticipantGuests(eventID string, userID, boardgameID int64, guests []string) error {
+	if m.UpdateParticipantGuestsFunc != nil {
+		return m.UpdateParticipantGuestsFunc(eventID, userID, boardgameID, guests)
+	}
+	return nil
+}
+
+func (m *MockDatabase) SetParticipantRSVP(id *string, eventID string, userID int64, userName string, isTelegramUsername bool, status models.RSVPStatus) (string, error) {
+	if m.SetParticipantRSVPFunc != nil {
+		return m.SetParticipantRSVPFunc(id, eventID, userID, userName, isTelegramUsername, status)
+	}
+	return "mock-participant-uuid", nil
+}
+
+func (m *MockDatabase) SelectMaybeEventsToRemind() ([]models.Event, error) {
+	if m.SelectMaybeEventsToRemindFunc != nil {
+		return m.SelectMaybeEventsToRemindFunc()
+	}
+	return []models.Event{}, nil
+}
+
+func (m *MockDatabase) SetMaybeReminded(eventID string) error {
+	if m.SetMaybeRemindedFunc != nil {
+		return m.SetMaybeRemindedFunc(eventID)
+	}
+	return nil
+}
+
+func (m *MockDatabase) UpdateEventRSVPDeadline(eventID string, deadline *time.Time) error {
+	if m.UpdateEventRSVPDeadlineFunc != nil {
+		return m.UpdateEventRSVPDeadlineFunc(eventID, deadline)
+	}
+	return nil
+}
+
+func (m *MockDatabase) SelectPendingLineupEvents() ([]models.Event, error) {
+	if m.SelectPendingLineupEventsFunc != nil {
+		return m.SelectPendingLineupEventsFunc()
+	}
+	return []models.Event{}, nil
+}
+
+func (m *MockDatabase) SetLineupPosted(eventID string) error {
+	if m.SetLineupPostedFunc != nil {
+		return m.SetLineupPostedFunc(eventID)
+	}
+	return nil
+}
+
+func (m *MockDatabase) SelectEventsToArchive() ([]models.Event, error) {
+	if m.SelectEventsToArchiveFunc != nil {
+		return m.SelectEventsToArchiveFunc()
+	}
+	return []models.Event{}, nil
+}
+
+func (m *MockDatabase) SetEventFinished(eventID string) error {
+	if m.SetEventFinishedFunc != nil {
+		return m.SetEventFinishedFunc(eventID)
+	}
+	return nil
+}
+
+func (m *MockDatabase) AnonymizeEventsBefore(cutoff time.Time, name string) (int64, error) {
+	if m.AnonymizeEventsBeforeFunc != nil {
+		return m.AnonymizeEventsBeforeFunc(cutoff, name)
+	}
+	return 0, nil
+}
+
+func (m *MockDatabase) DeleteEventsBefore(cutoff time.Time) (int64, error) {
+	if m.DeleteEventsBeforeFunc != nil {
+		return m.DeleteEventsBeforeFunc(cutoff)
+	}
+	return 0, nil
+}
+
+func (m *MockDatabase) ForgetUser(userID int64, name string) ([]string, error) {
+	if m.ForgetUserFunc != nil {
+		return m.ForgetUserFunc(userID, name)
+	}
+	return []string{}, nil
+}
+
+func (m *MockDatabase) PurgeChat(chatID int64) (int64, error) {
+	if m.PurgeChatFunc != nil {
+		return m.PurgeChatFunc(chatID)
+	}
+	return 0, nil
+}
+
+func (m *MockDatabase) SetNotificationPreferences(userID int64, preferences models.NotificationPreferences) error {
+	return nil
+}
+
+func (m *MockDatabase) InsertWebhook(chatID int64, threadID *int64, url, secret string, format models.WebhookFormat) (*int64, *string, error) {
 	id := int64(1)
 	uuid := "mock-webhook-uuid"
 	return &id, &uuid, nil
@@ -143,4 +404,11 @@ func (m *MockDatabase) GetWebhookByWebhookID(webhookID string) (*models.Webhook,
 	return &models.Webhook{ID: 1, UUID: "mock-webhook-uuid", ChatID: 123, Url: "mock-url", Secret: "mock-secret"}, nil
 }
 
+func (m *MockDatabase) WithTransaction(fn func(db database.DatabaseService) error) error {
+	if m.WithTransactionFunc != nil {
+		return m.WithTransactionFunc(fn)
+	}
+	return fn(m)
+}
+
 var _ database.DatabaseService = &MockDatabase{}
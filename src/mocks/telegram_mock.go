@@ -9,9 +9,15 @@ import (
 )
 
 type MockTelegramService struct {
-	SendFunc   func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error)
-	EditFunc   func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error)
-	DeleteFunc func(msg telebot.Editable) error
+	SendFunc        func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error)
+	EditFunc        func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error)
+	DeleteFunc      func(msg telebot.Editable) error
+	PinFunc         func(msg telebot.Editable, opts ...interface{}) error
+	UnpinFunc       func(chat telebot.Recipient, messageID ...int) error
+	CreateTopicFunc func(chat *telebot.Chat, topic *telebot.Topic) (*telebot.Topic, error)
+	CloseTopicFunc  func(chat *telebot.Chat, topic *telebot.Topic) error
+	AdminsOfFunc    func(chat *telebot.Chat) ([]telebot.ChatMember, error)
+	EditMarkupFunc  func(msg telebot.Editable, markup *telebot.ReplyMarkup) (*telebot.Message, error)
 }
 
 func NewMockTelegramService() *MockTelegramService {
@@ -27,6 +33,9 @@ func (m *MockTelegramService) AddStickerToSet(of telebot.Recipient, name string,
 }
 
 func (m *MockTelegramService) AdminsOf(chat *telebot.Chat) ([]telebot.ChatMember, error) {
+	if m.AdminsOfFunc != nil {
+		return m.AdminsOfFunc(chat)
+	}
 	return []telebot.ChatMember{}, nil
 }
 
@@ -71,6 +80,9 @@ func (m *MockTelegramService) CloseGeneralTopic(chat *telebot.Chat) error {
 }
 
 func (m *MockTelegramService) CloseTopic(chat *telebot.Chat, topic *telebot.Topic) error {
+	if m.CloseTopicFunc != nil {
+		return m.CloseTopicFunc(chat, topic)
+	}
 	return nil
 }
 
@@ -99,6 +111,9 @@ func (m *MockTelegramService) CreateStickerSet(of telebot.Recipient, set *telebo
 }
 
 func (m *MockTelegramService) CreateTopic(chat *telebot.Chat, topic *telebot.Topic) (*telebot.Topic, error) {
+	if m.CreateTopicFunc != nil {
+		return m.CreateTopicFunc(chat, topic)
+	}
 	return &telebot.Topic{}, nil
 }
 
@@ -177,6 +192,9 @@ func (m *MockTelegramService) EditMedia(msg telebot.Editable, media telebot.Inpu
 }
 
 func (m *MockTelegramService) EditReplyMarkup(msg telebot.Editable, markup *telebot.ReplyMarkup) (*telebot.Message, error) {
+	if m.EditMarkupFunc != nil {
+		return m.EditMarkupFunc(msg, markup)
+	}
 	return &telebot.Message{}, nil
 }
 
@@ -262,6 +280,9 @@ func (m *MockTelegramService) Notify(to telebot.Recipient, action telebot.ChatAc
 func (m *MockTelegramService) OnError(err error, c telebot.Context) {}
 
 func (m *MockTelegramService) Pin(msg telebot.Editable, opts ...interface{}) error {
+	if m.PinFunc != nil {
+		return m.PinFunc(msg, opts...)
+	}
 	return nil
 }
 
@@ -426,6 +447,9 @@ func (m *MockTelegramService) UnhideGeneralTopic(chat *telebot.Chat) error {
 }
 
 func (m *MockTelegramService) Unpin(chat telebot.Recipient, messageID ...int) error {
+	if m.UnpinFunc != nil {
+		return m.UnpinFunc(chat, messageID...)
+	}
 	return nil
 }
 
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChatExportVersion is the version of the export format, bumped on breaking
+// changes.
+const ChatExportVersion = 1
+
+// ChatExport holds every event of a chat with its games and participants, so
+// that they can be recreated in another instance of the bot. The participants
+// seated at a game are the plays of the chat.
+type ChatExport struct {
+	Version      int                 `json:"version"`
+	ChatID       int64               `json:"chat_id"`
+	ExportedAt   time.Time           `json:"exported_at"`
+	Events       []ExportEvent       `json:"events"`
+	Games        []ExportGame        `json:"games"`
+	Participants []ExportParticipant `json:"participants"`
+}
+
+type ExportEvent struct {
+	ID           string     `json:"id"`
+	UserID       int64      `json:"user_id"`
+	UserName     string     `json:"user_name"`
+	Name         string     `json:"name"`
+	Location     *string    `json:"location"`
+	StartsAt     *time.Time `json:"starts_at"`
+	Locked       bool       `json:"locked"`
+	RSVPDeadline *time.Time `json:"rsvp_deadline,omitempty"`
+	Finished     bool       `json:"finished"`
+	CoHosts      []CoHost   `json:"cohosts,omitempty"`
+}
+
+type ExportGame struct {
+	ID          string  `json:"id"`
+	EventID     string  `json:"event_id"`
+	Name        string  `json:"name"`
+	Slot        string  `json:"slot"`
+	MaxPlayers  int64   `json:"max_players"`
+	BggID       *int64  `json:"bgg_id"`
+	BggName     *string `json:"bgg_name"`
+	BggUrl      *string `json:"bgg_url"`
+	BggImageUrl *string `json:"bgg_image_url"`
+}
+
+// ExportParticipant is a seat at a game, or an answer to the event when
+// GameID is empty.
+type ExportParticipant struct {
+	ID                 string     `json:"id"`
+	EventID            string     `json:"event_id"`
+	GameID             string     `json:"game_id,omitempty"`
+	UserID             int64      `json:"user_id"`
+	UserName           string     `json:"user_name"`
+	IsTelegramUsername bool       `json:"is_telegram_username"`
+	Status             RSVPStatus `json:"status"`
+	Guests             []string   `json:"guests,omitempty"`
+	CreatedAt          *time.Time `json:"created_at,omitempty"`
+}
+
+// NewChatExport describes the events of chatID, as loaded from the database.
+func NewChatExport(chatID int64, events []Event, at time.Time) ChatExport {
+	export := ChatExport{
+		Version:      ChatExportVersion,
+		ChatID:       chatID,
+		ExportedAt:   at,
+		Events:       []ExportEvent{},
+		Games:        []ExportGame{},
+		Participants: []ExportParticipant{},
+	}
+
+	for _, event := range events {
+		export.Events = append(export.Events, ExportEvent{
+			ID:           event.ID,
+			UserID:       event.UserID,
+			UserName:     event.UserName,
+			Name:         event.Name,
+			Location:     event.Location,
+			StartsAt:     event.StartsAt,
+			Locked:       event.Locked,
+			RSVPDeadline: event.RSVPDeadline,
+			Finished:     event.Finished,
+			CoHosts:      event.CoHosts,
+		})
+
+		for _, bg := range event.BoardGames {
+			export.Games = append(export.Games, ExportGame{
+				ID:          bg.UUID,
+				EventID:     event.ID,
+				Name:        bg.Name,
+				Slot:        bg.Slot,
+				MaxPlayers:  bg.MaxPlayers,
+				BggID:       bg.BggID,
+				BggName:     bg.BggName,
+				BggUrl:      bg.BggUrl,
+				BggImageUrl: bg.BggImageUrl,
+			})
+
+			for _, p := range bg.Participants {
+				export.Participants = append(export.Participants, newExportParticipant(event.ID, bg.UUID, RSVPGoing, p))
+			}
+		}
+
+		for _, p := range event.Tentative {
+			export.Participants = append(export.Participants, newExportParticipant(event.ID, "", RSVPMaybe, p))
+		}
+		for _, p := range event.Declined {
+			export.Participants = append(export.Participants, newExportParticipant(event.ID, "", RSVPDeclined, p))
+		}
+	}
+
+	return export
+}
+
+func newExportParticipant(eventID, gameID string, status RSVPStatus, p Participant) ExportParticipant {
+	return ExportParticipant{
+		ID:                 p.UUID,
+		EventID:            eventID,
+		GameID:             gameID,
+		UserID:             p.UserID,
+		UserName:           p.UserName,
+		IsTelegramUsername: p.IsTelegramUsername,
+		Status:             status,
+		Guests:             p.Guests,
+		CreatedAt:          p.CreatedAt,
+	}
+}
+
+// WriteZip writes the export as a zip archive: export.json holds the whole
+// export, ready to be imported, and a CSV file per table holds the events,
+// games and participants for spreadsheets.
+func (e ChatExport) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.Create("export.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(e); err != nil {
+		return err
+	}
+
+	events := [][]string{{"id", "user_id", "user_name", "name", "location", "starts_at", "locked", "rsvp_deadline", "finished", "cohosts"}}
+	for _, event := range e.Events {
+		coHosts := []string{}
+		for _, coHost := range event.CoHosts {
+			coHosts = append(coHosts, strconv.FormatInt(coHost.UserID, 10))
+		}
+		events = append(events, []string{
+			event.ID,
+			strconv.FormatInt(event.UserID, 10),
+			event.UserName,
+			event.Name,
+			csvString(event.Location),
+			csvTime(event.StartsAt),
+			strconv.FormatBool(event.Locked),
+			csvTime(event.RSVPDeadline),
+			strconv.FormatBool(event.Finished),
+			strings.Join(coHosts, ";"),
+		})
+	}
+
+	games := [][]string{{"id", "event_id", "name", "slot", "max_players", "bgg_id", "bgg_name", "bgg_url", "bgg_image_url"}}
+	for _, game := range e.Games {
+		bggID := ""
+		if game.BggID != nil {
+			bggID = strconv.FormatInt(*game.BggID, 10)
+		}
+		games = append(games, []string{
+			game.ID,
+			game.EventID,
+			game.Name,
+			game.Slot,
+			strconv.FormatInt(game.MaxPlayers, 10),
+			bggID,
+			csvString(game.BggName),
+			csvString(game.BggUrl),
+			csvString(game.BggImageUrl),
+		})
+	}
+
+	participants := [][]string{{"id", "event_id", "game_id", "user_id", "user_name", "is_telegram_username", "status", "guests", "created_at"}}
+	for _, p := range e.Participants {
+		participants = append(participants, []string{
+			p.ID,
+			p.EventID,
+			p.GameID,
+			strconv.FormatInt(p.UserID, 10),
+			p.UserName,
+			strconv.FormatBool(p.IsTelegramUsername),
+			string(p.Status),
+			strings.Join(p.Guests, ";"),
+			csvTime(p.CreatedAt),
+		})
+	}
+
+	for _, table := range []struct {
+		name    string
+		records [][]string
+	}{
+		{"events.csv", events},
+		{"games.csv", games},
+		{"participants.csv", participants},
+	} {
+		if file, err = archive.Create(table.name); err != nil {
+			return err
+		}
+		if err = csv.NewWriter(file).WriteAll(table.records); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func csvString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
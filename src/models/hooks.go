@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type HookWebhookType string
 
@@ -14,6 +17,18 @@ const (
 	HookWebhookTypeRemoveParticipant HookWebhookType = "remove_participant"
 	HookWebhookTypeTestWebhook       HookWebhookType = "test"
 	HookWebhookTypeSendMessage       HookWebhookType = "send_message"
+	HookWebhookTypeBatch             HookWebhookType = "batch"
+	HookWebhookTypeUpdateEvent       HookWebhookType = "update_event"
+	HookWebhookTypeLockEvent         HookWebhookType = "lock_event"
+	HookWebhookTypeUnlockEvent       HookWebhookType = "unlock_event"
+	HookWebhookTypeUpdateChat        HookWebhookType = "update_chat_settings"
+	HookWebhookTypeUpdateWaitlist    HookWebhookType = "update_waitlist"
+	HookWebhookTypeUpdateGuests      HookWebhookType = "update_guests"
+	HookWebhookTypeUpdateRSVP        HookWebhookType = "update_rsvp"
+	HookWebhookTypeUpdateCoHosts     HookWebhookType = "update_cohosts"
+	HookWebhookTypeKickParticipant   HookWebhookType = "kick_participant"
+	HookWebhookTypeMoveParticipant   HookWebhookType = "move_participant"
+	HookWebhookTypeImportChat        HookWebhookType = "import_chat"
 )
 
 type HookWebhookEnvelope struct {
@@ -31,9 +46,35 @@ type HookNewEventPayload struct {
 	MessageID *int64     `json:"message_id"`
 	Location  *string    `json:"location"`
 	StartsAt  *time.Time `json:"starts_at"`
+	Locked    bool       `json:"locked"`
 	CreatedAt time.Time  `json:"created_at"`
 }
 
+// HookUpdateEventPayload is a snapshot of the event, sent every time its
+// Telegram message is edited to reflect a change.
+type HookUpdateEventPayload struct {
+	ID           string     `json:"id"`
+	ChatID       int64      `json:"chat_id"`
+	UserID       int64      `json:"user_id"`
+	UserName     string     `json:"user_name"`
+	Name         string     `json:"name"`
+	MessageID    *int64     `json:"message_id"`
+	Location     *string    `json:"location"`
+	StartsAt     *time.Time `json:"starts_at"`
+	Locked       bool       `json:"locked"`
+	RSVPDeadline *time.Time `json:"rsvp_deadline,omitempty"`
+	CoHosts      []CoHost   `json:"cohosts,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type HookLockEventPayload struct {
+	EventID   string    `json:"event_id"`
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Locked    bool      `json:"locked"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
 type HookDeleteEventPayload struct {
 	EventID   string `json:"event_id"`
 	UserID    *int64 `json:"user_id"`
@@ -57,6 +98,7 @@ type HookNewGamePayload struct {
 	UserName   string      `json:"user_name"`
 	Name       string      `json:"name"`
 	MaxPlayers int         `json:"max_players"`
+	Slot       string      `json:"slot"`
 	MessageID  *int64      `json:"message_id"`
 	BGG        HookBGGInfo `json:"bgg"`
 	CreatedAt  time.Time   `json:"created_at"`
@@ -78,11 +120,36 @@ type HookUpdateGamePayload struct {
 	UserName   string      `json:"user_name"`
 	Name       string      `json:"name"`
 	MaxPlayers int         `json:"max_players"`
+	Slot       *string     `json:"slot,omitempty"`
 	MessageID  *int64      `json:"message_id"`
 	BGG        HookBGGInfo `json:"bgg"`
 	UpdatedAt  time.Time   `json:"updated_at"`
 }
 
+// NewHookUpdateGamePayload describes game as stored after an update, so that
+// every path editing a game (commands, mini app, BGG re-link, inbound
+// webhooks) reports the same fields.
+func NewHookUpdateGamePayload(eventID string, game BoardGame, userID int64, userName string) HookUpdateGamePayload {
+	return HookUpdateGamePayload{
+		ID:         game.UUID,
+		EventID:    eventID,
+		UserID:     userID,
+		UserName:   userName,
+		Name:       game.Name,
+		MaxPlayers: int(game.MaxPlayers),
+		Slot:       &game.Slot,
+		MessageID:  game.MessageID,
+		BGG: HookBGGInfo{
+			IsSet:    game.BggID != nil,
+			ID:       game.BggID,
+			Name:     game.BggName,
+			URL:      game.BggUrl,
+			ImageURL: game.BggImageUrl,
+		},
+		UpdatedAt: time.Now(),
+	}
+}
+
 // --- Participant payloads ---
 type HookAddParticipantPayload struct {
 	ID       string    `json:"id"`
@@ -102,15 +169,328 @@ type HookRemoveParticipantPayload struct {
 	RemovedAt time.Time `json:"removed_at"`
 }
 
+// HookKickParticipantPayload reports a host removing a participant from an
+// event, GameID is empty when they only answered maybe or declined.
+type HookKickParticipantPayload struct {
+	EventID  string    `json:"event_id"`
+	GameID   string    `json:"game_id,omitempty"`
+	UserID   int64     `json:"user_id"`
+	UserName string    `json:"user_name"`
+	HostID   int64     `json:"host_id"`
+	HostName string    `json:"host_name"`
+	KickedAt time.Time `json:"kicked_at"`
+}
+
+// HookMoveParticipantPayload reports a host moving a participant to another
+// game of the event.
+type HookMoveParticipantPayload struct {
+	EventID    string    `json:"event_id"`
+	FromGameID string    `json:"from_game_id"`
+	ToGameID   string    `json:"to_game_id"`
+	UserID     int64     `json:"user_id"`
+	UserName   string    `json:"user_name"`
+	HostID     int64     `json:"host_id"`
+	HostName   string    `json:"host_name"`
+	MovedAt    time.Time `json:"moved_at"`
+}
+
+type HookWaitlistStatus string
+
+const (
+	// HookWaitlistStatusJoined: the participant joined a full game and is queued.
+	HookWaitlistStatusJoined HookWaitlistStatus = "joined"
+	// HookWaitlistStatusLeft: a queued participant left the game.
+	HookWaitlistStatusLeft HookWaitlistStatus = "left"
+	// HookWaitlistStatusPromoted: a queued participant got a seat.
+	HookWaitlistStatusPromoted HookWaitlistStatus = "promoted"
+	// HookWaitlistStatusDemoted: a seated participant was moved to the queue,
+	// e.g. because the maximum number of players was lowered.
+	HookWaitlistStatusDemoted HookWaitlistStatus = "demoted"
+)
+
+type HookWaitlistPayload struct {
+	EventID   string             `json:"event_id"`
+	GameID    string             `json:"game_id"`
+	UserID    int64              `json:"user_id"`
+	UserName  string             `json:"user_name"`
+	Status    HookWaitlistStatus `json:"status"`
+	Position  int                `json:"position,omitempty"`
+	ChangedAt time.Time          `json:"changed_at"`
+}
+
+// HookGuestsPayload reports the guests a participant brings after a change.
+type HookGuestsPayload struct {
+	EventID   string    `json:"event_id"`
+	GameID    string    `json:"game_id"`
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Guests    []string  `json:"guests"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// HookRSVPPayload reports a user answering maybe or that they cannot make it
+// to an event.
+type HookRSVPPayload struct {
+	EventID   string     `json:"event_id"`
+	UserID    int64      `json:"user_id"`
+	UserName  string     `json:"user_name"`
+	Status    RSVPStatus `json:"status"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// HookCoHostsPayload reports the co-hosts of an event after the owner changed
+// them.
+type HookCoHostsPayload struct {
+	EventID   string    `json:"event_id"`
+	UserID    int64     `json:"user_id"`
+	CoHosts   []CoHost  `json:"cohosts"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type waitlistEntry struct {
+	userName string
+	position int // 0 when seated
+}
+
+func waitlistState(event *Event) map[string]map[int64]waitlistEntry {
+	state := map[string]map[int64]waitlistEntry{}
+	if event == nil {
+		return state
+	}
+
+	for _, bg := range event.BoardGames {
+		players := map[int64]waitlistEntry{}
+		for _, seat := range bg.Seats() {
+			if seat.Guest < 0 {
+				players[seat.Participant.UserID] = waitlistEntry{userName: seat.Participant.UserName, position: seat.Queue}
+			}
+		}
+		state[bg.UUID] = players
+	}
+
+	return state
+}
+
+// WaitlistChanges compares the queues of two snapshots of the same event and
+// reports every participant who entered or left a queue. Moving up within a
+// queue is not reported.
+func WaitlistChanges(before, after *Event, at time.Time) []HookWaitlistPayload {
+	eventID := ""
+	games := []string{}
+	seen := map[string]bool{}
+	for _, event := range []*Event{after, before} {
+		if event == nil {
+			continue
+		}
+		eventID = event.ID
+		for _, bg := range event.BoardGames {
+			if !seen[bg.UUID] {
+				seen[bg.UUID] = true
+				games = append(games, bg.UUID)
+			}
+		}
+	}
+
+	beforeState := waitlistState(before)
+	afterState := waitlistState(after)
+
+	changes := []HookWaitlistPayload{}
+	for _, gameID := range games {
+		for _, userID := range sortedUserIDs(beforeState[gameID], afterState[gameID]) {
+			old, wasIn := beforeState[gameID][userID]
+			cur, isIn := afterState[gameID][userID]
+			wasQueued := wasIn && old.position > 0
+			isQueued := isIn && cur.position > 0
+
+			var status HookWaitlistStatus
+			switch {
+			case isQueued && !wasQueued && wasIn:
+				status = HookWaitlistStatusDemoted
+			case isQueued && !wasQueued:
+				status = HookWaitlistStatusJoined
+			case wasQueued && !isQueued && isIn:
+				status = HookWaitlistStatusPromoted
+			case wasQueued && !isQueued:
+				status = HookWaitlistStatusLeft
+			default:
+				continue
+			}
+
+			userName := cur.userName
+			if !isIn {
+				userName = old.userName
+			}
+
+			changes = append(changes, HookWaitlistPayload{
+				EventID:   eventID,
+				GameID:    gameID,
+				UserID:    userID,
+				UserName:  userName,
+				Status:    status,
+				Position:  cur.position,
+				ChangedAt: at,
+			})
+		}
+	}
+
+	return changes
+}
+
+func sortedUserIDs(states ...map[int64]waitlistEntry) []int64 {
+	ids := []int64{}
+	seen := map[int64]bool{}
+	for _, state := range states {
+		for id := range state {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// --- Chat payloads ---
+
+// HookChatSettingsPayload reports a change of the chat settings, only the
+// changed settings are set.
+type HookChatSettingsPayload struct {
+	ChatID      int64     `json:"chat_id"`
+	UserID      int64     `json:"user_id"`
+	UserName    string    `json:"user_name"`
+	Language    *string   `json:"language,omitempty"`
+	Location    *string   `json:"location,omitempty"`
+	Timezone    *string   `json:"timezone,omitempty"`
+	AutoPin     *bool     `json:"auto_pin,omitempty"`
+	EventTopics *bool     `json:"event_topics,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // --- Message payloads ---
 type HookSendMessagePayload struct {
-	UserID   *int64     `json:"user_id"`
-	UserName *string    `json:"user_name"`
-	Message  string     `json:"message"`
-	SentAt   *time.Time `json:"sent_at"`
+	UserID    *int64     `json:"user_id"`
+	UserName  *string    `json:"user_name"`
+	Message   string     `json:"message"`
+	MessageID *int64     `json:"message_id,omitempty"`
+	SentAt    *time.Time `json:"sent_at"`
 }
 
 type HookTestPayload struct {
 	Message   string     `json:"message"`
 	Timestamp *time.Time `json:"timestamp"`
 }
+
+// --- Import payloads ---
+
+// HookImportChatResult reports what an import_chat webhook recreated, its
+// payload is a ChatExport.
+type HookImportChatResult struct {
+	Events       int       `json:"events"`
+	Games        int       `json:"games"`
+	Participants int       `json:"participants"`
+	ImportedAt   time.Time `json:"imported_at"`
+}
+
+// --- Batch payloads ---
+
+// HookBatchMaxOperations bounds the number of operations in a single batch.
+const HookBatchMaxOperations = 100
+
+// HookBatchPayload carries an ordered list of operations applied atomically:
+// either all of them succeed or none is stored.
+type HookBatchPayload struct {
+	Operations []HookWebhookEnvelope `json:"operations"`
+}
+
+type HookBatchOperationStatus string
+
+const (
+	HookBatchOperationStatusApplied    HookBatchOperationStatus = "applied"
+	HookBatchOperationStatusRolledBack HookBatchOperationStatus = "rolled_back"
+	HookBatchOperationStatusFailed     HookBatchOperationStatus = "failed"
+	HookBatchOperationStatusSkipped    HookBatchOperationStatus = "skipped"
+)
+
+type HookBatchOperationResult struct {
+	Index  int                      `json:"index"`
+	Type   HookWebhookType          `json:"type"`
+	Status HookBatchOperationStatus `json:"status"`
+	Data   any                      `json:"data,omitempty"`
+	Error  *HookError               `json:"error,omitempty"`
+}
+
+type HookBatchResultPayload struct {
+	Results []HookBatchOperationResult `json:"results"`
+}
+
+// --- CloudEvents ---
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsTypePrefix is prepended to the HookWebhookType to build the
+	// CloudEvents type, e.g. org.boardgamenight.add_participant.
+	CloudEventsTypePrefix = "org.boardgamenight."
+)
+
+// CloudEvent is a CloudEvents 1.0 event in structured JSON mode.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            any       `json:"data"`
+}
+
+// --- Response envelope ---
+type HookErrorCode string
+
+const (
+	HookErrorCodeInvalidWebhook      HookErrorCode = "invalid_webhook"
+	HookErrorCodeInvalidPayload      HookErrorCode = "invalid_payload"
+	HookErrorCodeMissingHeader       HookErrorCode = "missing_header"
+	HookErrorCodeHashMismatch        HookErrorCode = "hash_mismatch"
+	HookErrorCodeInvalidSignature    HookErrorCode = "invalid_signature"
+	HookErrorCodeInvalidDate         HookErrorCode = "invalid_date"
+	HookErrorCodeRequestExpired      HookErrorCode = "request_expired"
+	HookErrorCodeRateLimited         HookErrorCode = "rate_limited"
+	HookErrorCodeIdempotencyConflict HookErrorCode = "idempotency_conflict"
+	HookErrorCodeInvalidEvent        HookErrorCode = "invalid_event"
+	HookErrorCodeInvalidGame         HookErrorCode = "invalid_game"
+	HookErrorCodeForbidden           HookErrorCode = "forbidden"
+	HookErrorCodeUnsupportedType     HookErrorCode = "unsupported_type"
+	HookErrorCodeOperationFailed     HookErrorCode = "operation_failed"
+	HookErrorCodeRSVPClosed          HookErrorCode = "rsvp_closed"
+	HookErrorCodeEventFinished       HookErrorCode = "event_finished"
+	HookErrorCodeAlreadyExists       HookErrorCode = "already_exists"
+	HookErrorCodeEventLocked         HookErrorCode = "event_locked"
+	HookErrorCodeGameNotFound        HookErrorCode = "game_not_found"
+)
+
+type HookError struct {
+	Code    HookErrorCode `json:"code"`
+	Message string        `json:"message"`
+}
+
+// HookWebhookResponse is the body returned to inbound webhook calls. On success
+// Data holds the resulting object (e.g. the created event with its generated ID),
+// on failure Error holds a machine readable code.
+type HookWebhookResponse struct {
+	Type    HookWebhookType `json:"type,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Data    any             `json:"data,omitempty"`
+	Error   *HookError      `json:"error,omitempty"`
+}
+
+func NewHookErrorResponse(code HookErrorCode, message string) HookWebhookResponse {
+	return HookWebhookResponse{
+		Error: &HookError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"log"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/google/uuid"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -16,6 +19,26 @@ import (
 const PLAYER_COUNTER = "_PLAYER_COUNTER_"
 const UnlimitedPlayers = -1
 
+// Telegram limits for a single message. The length is counted in UTF-16 code
+// units on the HTML source, which is never shorter than the visible text.
+const (
+	MaxMessageLength   = 4096
+	MaxKeyboardButtons = 100
+)
+
+// MaxTopicNameLength is the longest name Telegram accepts for a forum topic.
+const MaxTopicNameLength = 128
+
+// EventDuration is the assumed length of an event, which has only a start time.
+const EventDuration = 2 * time.Hour
+
+// MaybeReminderLead is how long before the start of an event the users who
+// answered maybe are asked to decide.
+const MaybeReminderLead = 24 * time.Hour
+
+// allParticipants lists every participant of a game.
+const allParticipants = -1
+
 type Event struct {
 	ID         string
 	ChatID     int64
@@ -27,6 +50,38 @@ type Event struct {
 	Locked     bool
 	Location   *string
 	StartsAt   *time.Time
+	Pinned     bool
+	TopicID    *int64
+	// RSVPDeadline freezes the lineup: nobody can join once it passed.
+	RSVPDeadline *time.Time
+	// Tentative and Declined are the users who answered maybe or that they
+	// cannot make it: they take no seat in any game.
+	Tentative []Participant
+	Declined  []Participant
+	// CoHosts can manage games and participants of the event like its owner.
+	CoHosts []CoHost
+	// Finished events are over: they are archived and accept no changes.
+	Finished bool
+}
+
+// CoHost is a user appointed by the owner to help hosting an event.
+type CoHost struct {
+	UserID   int64  `json:"user_id"`
+	UserName string `json:"user_name"`
+}
+
+// IsCoHost reports whether the user is the owner or a co-host of the event.
+// Chat admins are co-hosts too, but that is checked against Telegram.
+func (e Event) IsCoHost(userID int64) bool {
+	if e.UserID == userID {
+		return true
+	}
+	for _, coHost := range e.CoHosts {
+		if coHost.UserID == userID {
+			return true
+		}
+	}
+	return false
 }
 
 type AddPlayerRequest struct {
@@ -36,6 +91,28 @@ type AddPlayerRequest struct {
 	IsTelegramUsername bool   `json:"is_telegram_username"`
 }
 
+type AddGuestRequest struct {
+	UserID int64  `json:"user_id" binding:"required"`
+	Name   string `json:"name" binding:"max=64"`
+}
+
+// HostActionRequest is sent by a host of the event, identified by the init
+// data of the mini app, to kick or move the participant ParticipantID. GameID
+// is the destination of a move.
+type HostActionRequest struct {
+	ParticipantID int64 `json:"participant_id" binding:"required"`
+	GameID        int64 `json:"game_id"`
+}
+
+// RSVPRequest answers maybe or that the user cannot make it, going is
+// answered by joining a game.
+type RSVPRequest struct {
+	UserID             int64      `json:"user_id" binding:"required"`
+	UserName           string     `json:"user_name" binding:"required"`
+	IsTelegramUsername bool       `json:"is_telegram_username"`
+	Status             RSVPStatus `json:"status" binding:"required,oneof=maybe declined"`
+}
+
 type BoardGame struct {
 	ID           int64         `json:"id"`
 	UUID         string        `json:"uuid"`
@@ -47,6 +124,9 @@ type BoardGame struct {
 	BggName      *string       `json:"bgg_name"`
 	BggUrl       *string       `json:"bgg_url"`
 	BggImageUrl  *string       `json:"bgg_image_url"`
+	// Slot is the time slot of the game, e.g. "20:00" or "late", empty when
+	// the game lasts the whole event. A user joins one game per slot.
+	Slot string `json:"slot"`
 }
 
 type CreateEventRequest struct {
@@ -61,6 +141,12 @@ type CreateEventRequest struct {
 	AllowGeneralJoin BoolOn     `json:"allow_general_join" form:"allow_general_join"`
 }
 
+// CloneEventRequest is the mini app form duplicating an event on a new date.
+type CloneEventRequest struct {
+	StartsAt     *time.Time `json:"starts_at" form:"starts_at" time_format:"2006-01-02T15:04" binding:"required"`
+	Participants BoolOn     `json:"participants" form:"participants"`
+}
+
 // BoolOn is a custom bool type that parses "on" as true (for HTML form checkboxes)
 type BoolOn bool
 
@@ -89,12 +175,14 @@ type AddGameRequest struct {
 	Name       string  `json:"name" form:"name" binding:"required"`
 	MaxPlayers *int    `json:"max_players" form:"max_players"`
 	BggUrl     *string `json:"bgg_url" form:"bgg_url"`
+	Slot       string  `json:"slot" form:"slot"`
 	UserID     int64   `json:"user_id" form:"user_id"`
 }
 
 type UpdateGameRequest struct {
 	MaxPlayers *int    `json:"max_players" form:"max_players"`
 	BggUrl     *string `json:"bgg_url" form:"bgg_url"`
+	Slot       *string `json:"slot" form:"slot"`
 	UserID     int64   `json:"user_id" form:"user_id"`
 	UserName   string  `json:"user_name" form:"user_name"`
 	Unlink     string  `json:"unlink" form:"unlink"`
@@ -106,9 +194,21 @@ type Participant struct {
 	UserID             int64      `json:"user_id"`
 	UserName           string     `json:"user_name"`
 	IsTelegramUsername bool       `json:"is_telegram_username"`
+	Guests             []string   `json:"guests,omitempty"` // names of the guests, may be empty
+	Status             RSVPStatus `json:"status,omitempty"`
 	CreatedAt          *time.Time `json:"created_at,omitempty"`
 }
 
+// RSVPStatus is the answer of a user to an event.
+type RSVPStatus string
+
+const (
+	// RSVPGoing users take a seat in a game.
+	RSVPGoing    RSVPStatus = "going"
+	RSVPMaybe    RSVPStatus = "maybe"
+	RSVPDeclined RSVPStatus = "declined"
+)
+
 // create enum with value add_player
 type EventAction string
 
@@ -116,13 +216,79 @@ const (
 	AddPlayer  EventAction = "$add_player"
 	Cancel     EventAction = "$cancel"
 	Unregister EventAction = "$unregister"
+	// MyLeave and MyPreference are the buttons of the /my panel.
+	MyLeave      EventAction = "$my_leave"
+	MyPreference EventAction = "$my_pref"
+	AddGuest     EventAction = "$add_guest"
+	Maybe        EventAction = "$maybe"
+	// MoveTo is a button of the game selector shown to hosts by /move, kept
+	// short to fit the event, user and game ids in the callback data.
+	MoveTo EventAction = "$mv"
 )
 
+// MaxGuests is the number of guests a participant can bring to a game.
+const MaxGuests = 10
+
+// NotificationPreferences are the direct messages a user accepts from the bot.
+type NotificationPreferences struct {
+	// Waitlist notifies when the user gets a spot freed up on a waitlist.
+	Waitlist bool
+	// Cancellations notifies when an event joined by the user is deleted.
+	Cancellations bool
+	// Reminders asks the user to decide when they answered maybe to an event
+	// starting soon.
+	Reminders bool
+}
+
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{Waitlist: true, Cancellations: true, Reminders: true}
+}
+
+// AnonymousUserName replaces the name of the users whose data has been erased.
+const AnonymousUserName = "anonymous"
+
+// RetentionPolicy tells how long the data of past events is kept, counted in
+// months from the start of the event. Zero keeps it forever.
+type RetentionPolicy struct {
+	// AnonymizeAfterMonths replaces the names of the participants with
+	// AnonymousUserName.
+	AnonymizeAfterMonths int
+	// DeleteAfterMonths deletes the event with its games and participants.
+	DeleteAfterMonths int
+}
+
 type WebUrl struct {
 	BaseUrl       string
 	BotMiniAppURL string
 }
 
+// WebhookFormat selects how outbound notifications are encoded.
+type WebhookFormat string
+
+const (
+	// WebhookFormatDefault sends the HookWebhookEnvelope as is.
+	WebhookFormatDefault WebhookFormat = "bgnb"
+	// WebhookFormatCloudEvents sends a CloudEvents 1.0 structured mode event.
+	WebhookFormatCloudEvents WebhookFormat = "cloudevents"
+	// WebhookFormatCloudEventsBinary sends the payload as body and the
+	// CloudEvents attributes as ce-* headers.
+	WebhookFormatCloudEventsBinary WebhookFormat = "cloudevents-binary"
+)
+
+// ParseWebhookFormat validates a format name, an empty name selects the default one.
+func ParseWebhookFormat(format string) (WebhookFormat, bool) {
+	switch WebhookFormat(strings.ToLower(format)) {
+	case "", WebhookFormatDefault:
+		return WebhookFormatDefault, true
+	case WebhookFormatCloudEvents:
+		return WebhookFormatCloudEvents, true
+	case WebhookFormatCloudEventsBinary:
+		return WebhookFormatCloudEventsBinary, true
+	}
+
+	return "", false
+}
+
 type Webhook struct {
 	ID        int64
 	UUID      string
@@ -130,6 +296,7 @@ type Webhook struct {
 	ThreadID  *int64
 	Url       string
 	Secret    string
+	Format    WebhookFormat
 	CreatedAt time.Time
 }
 
@@ -140,11 +307,155 @@ type BggInfo struct {
 	ImageUrl   *string
 }
 
+// queuePosition returns the waitlist position of the i-th seat of the game,
+// starting from 1, or 0 when it is confirmed.
+func (bg BoardGame) queuePosition(i int) int {
+	if bg.MaxPlayers == UnlimitedPlayers || i < int(bg.MaxPlayers) {
+		return 0
+	}
+	return i - int(bg.MaxPlayers) + 1
+}
+
+// Seat is a place at a game, taken by a participant or by one of their guests.
+type Seat struct {
+	Participant Participant
+	// Guest is the index of the guest in Participant.Guests, -1 for the
+	// participant.
+	Guest int
+	// Queue is the waitlist position of the seat, 0 when it is confirmed.
+	Queue int
+}
+
+// Seats returns the seats of the game in order of arrival: every participant
+// is followed by their guests.
+func (bg BoardGame) Seats() []Seat {
+	seats := []Seat{}
+	for _, p := range bg.Participants {
+		for guest := -1; guest < len(p.Guests); guest++ {
+			seats = append(seats, Seat{Participant: p, Guest: guest, Queue: bg.queuePosition(len(seats))})
+		}
+	}
+	return seats
+}
+
+// SeatCount returns the number of seats taken, guests included.
+func (bg BoardGame) SeatCount() int {
+	count := 0
+	for _, p := range bg.Participants {
+		count += 1 + len(p.Guests)
+	}
+	return count
+}
+
+// WaitlistPosition returns the waitlist position of userID, 0 when they have
+// a seat, and false when they do not take part in the game.
+func (bg BoardGame) WaitlistPosition(userID int64) (int, bool) {
+	for _, seat := range bg.Seats() {
+		if seat.Guest < 0 && seat.Participant.UserID == userID {
+			return seat.Queue, true
+		}
+	}
+	return 0, false
+}
+
+// Display returns the name shown for the seat, followed by its waitlist
+// position when it is queued.
+func (s Seat) Display(localizer *i18n.Localizer) string {
+	display := s.Participant.UserName
+	if s.Guest >= 0 {
+		name := s.Participant.Guests[s.Guest]
+		id := "Guest"
+		if name == "" {
+			id = "UnnamedGuest"
+		}
+		display = localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: id,
+			},
+			TemplateData: map[string]string{
+				"Name": name,
+				"Host": s.Participant.UserName,
+			},
+		})
+	}
+
+	if s.Queue > 0 {
+		queuedText := localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "Queued",
+				Other: "(queued {{.Number}})",
+			},
+			TemplateData: map[string]string{
+				"Number": fmt.Sprintf("%d", s.Queue),
+			},
+		})
+		display = fmt.Sprintf("%s %s", display, queuedText)
+	}
+
+	return display
+}
+
+// Title is the name of the event, marked with 🔒 when it is locked.
+func (e Event) Title() string {
+	if e.Locked {
+		return "🔒 " + e.Name
+	}
+	return e.Name
+}
+
+// GamesBySlot returns the games grouped by time slot, the games without one
+// first. Games keep their order within a slot.
+func (e Event) GamesBySlot() []BoardGame {
+	games := append([]BoardGame{}, e.BoardGames...)
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].Slot < games[j].Slot
+	})
+	return games
+}
+
+// slotHeader renders the title of the time slot of games[i] when the game
+// opens a new slot, and nothing otherwise.
+func slotHeader(games []BoardGame, i int) string {
+	if games[i].Slot == "" || (i > 0 && games[i-1].Slot == games[i].Slot) {
+		return ""
+	}
+	return "🕒 <b>" + games[i].Slot + "</b>\n"
+}
+
+// ParticipantIDs returns the users taking part in any game of the event,
+// followed by the ones who answered maybe.
+func (e Event) ParticipantIDs() []int64 {
+	seen := map[int64]bool{}
+	ids := []int64{}
+	add := func(participants []Participant) {
+		for _, p := range participants {
+			if !seen[p.UserID] {
+				seen[p.UserID] = true
+				ids = append(ids, p.UserID)
+			}
+		}
+	}
+	for _, bg := range e.BoardGames {
+		add(bg.Participants)
+	}
+	add(e.Tentative)
+	return ids
+}
+
+// FormatBG renders a game with all its participants and its join button.
 func (e Event) FormatBG(localizer *i18n.Localizer, url WebUrl, bg BoardGame) (string, telebot.InlineButton, error) {
+	return e.formatBG(localizer, url, bg, allParticipants)
+}
+
+// formatBG renders a game listing at most maxListed participants, or all of
+// them when maxListed is allParticipants.
+func (e Event) formatBG(localizer *i18n.Localizer, url WebUrl, bg BoardGame, maxListed int) (string, telebot.InlineButton, error) {
 	msg := ""
 
+	seats := bg.Seats()
+
 	complete := ""
-	isComplete := bg.MaxPlayers != UnlimitedPlayers && len(bg.Participants) >= int(bg.MaxPlayers)
+	isComplete := bg.MaxPlayers != UnlimitedPlayers && len(seats) >= int(bg.MaxPlayers)
 	if isComplete {
 		complete = "🚫"
 	}
@@ -160,30 +471,27 @@ func (e Event) FormatBG(localizer *i18n.Localizer, url WebUrl, bg BoardGame) (st
 	}
 
 	maxPlayer := bg.MaxPlayers
-	players := fmt.Sprintf("(%d/%d %s)", len(bg.Participants), bg.MaxPlayers, localizer.MustLocalizeMessage(&i18n.Message{ID: "Players"}))
+	players := fmt.Sprintf("(%d/%d %s)", len(seats), bg.MaxPlayers, localizer.MustLocalizeMessage(&i18n.Message{ID: "Players"}))
 	if maxPlayer == UnlimitedPlayers {
-		players = fmt.Sprintf("(%d %s)", len(bg.Participants), localizer.MustLocalizeMessage(&i18n.Message{ID: "Players"}))
+		players = fmt.Sprintf("(%d %s)", len(seats), localizer.MustLocalizeMessage(&i18n.Message{ID: "Players"}))
 	}
 
 	msg += fmt.Sprintf("🎲 <b>%s [%s]</b> %s %s\n", link, name, players, complete)
-	for i, p := range bg.Participants {
-		display := p.UserName
-		// Calculate queue position for players beyond max capacity
-		// Skip if MaxPlayers is UnlimitedPlayers (-1)
-		if maxPlayers := int(bg.MaxPlayers); maxPlayers != UnlimitedPlayers && i >= maxPlayers {
-			queueNum := i - maxPlayers + 1
-			queuedText := localizer.MustLocalize(&i18n.LocalizeConfig{
+	for i, seat := range seats {
+		if maxListed != allParticipants && i >= maxListed {
+			msg += " - " + localizer.MustLocalize(&i18n.LocalizeConfig{
 				DefaultMessage: &i18n.Message{
-					ID:    "Queued",
-					Other: "(queued {{.Number}})",
+					ID: "MorePlayers",
 				},
 				TemplateData: map[string]string{
-					"Number": fmt.Sprintf("%d", queueNum),
+					"Count": strconv.Itoa(len(seats) - maxListed),
 				},
-			})
-			display = fmt.Sprintf("%s %s", display, queuedText)
+			}) + "\n"
+			break
 		}
-		if p.IsTelegramUsername {
+
+		display := seat.Display(localizer)
+		if seat.Guest < 0 && seat.Participant.IsTelegramUsername {
 			msg += " - @" + display + "\n"
 			continue
 		}
@@ -213,17 +521,69 @@ func (e Event) FormatBG(localizer *i18n.Localizer, url WebUrl, bg BoardGame) (st
 	return msg, btn, nil
 }
 
+// FormatMsg renders the event message and its keyboard. Big events are
+// shortened to stay within Telegram limits: participant lists are collapsed
+// first, then the games that do not fit are left out, and a button opens the
+// complete event in the mini app.
 func (e Event) FormatMsg(localizer *i18n.Localizer, webUrl WebUrl) (string, *telebot.ReplyMarkup) {
+	allGames := len(e.BoardGames)
+	for _, maxListed := range []int{allParticipants, 10, 3, 0} {
+		if msg, markup, fits := e.formatMsg(localizer, webUrl, maxListed, allGames); fits {
+			e.logFormatted(maxListed, allGames)
+			return msg, markup
+		}
+	}
+
+	// Even without participants the games do not fit: find the largest
+	// number of games that does.
+	low, high := 0, allGames-1
+	for low < high {
+		mid := (low + high + 1) / 2
+		if _, _, fits := e.formatMsg(localizer, webUrl, 0, mid); fits {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	msg, markup, _ := e.formatMsg(localizer, webUrl, 0, low)
+	e.logFormatted(0, low)
+	return msg, markup
+}
+
+func (e Event) logFormatted(maxListed int, maxGames int) {
+	if maxGames < len(e.BoardGames) {
+		log.Default().Printf("Formatted message in chat_id %d for event %s with %d of %d games", e.ChatID, e.ID, maxGames, len(e.BoardGames))
+		return
+	}
+	if maxListed != allParticipants {
+		log.Default().Printf("Formatted message in chat_id %d for event %s listing at most %d participants per game", e.ChatID, e.ID, maxListed)
+		return
+	}
+	log.Default().Printf("Formatted message in chat_id %d for event %s", e.ChatID, e.ID)
+}
+
+// formatMsg renders the first maxGames games, each listing at most maxListed
+// participants, and reports whether the result fits Telegram limits.
+func (e Event) formatMsg(localizer *i18n.Localizer, webUrl WebUrl, maxListed int, maxGames int) (string, *telebot.ReplyMarkup, bool) {
 	btns := []telebot.InlineButton{}
+	collapsed := maxGames < len(e.BoardGames)
 
-	msg := "📆 <b>" + e.Name + "</b>\n\n"
+	msg := "📆 <b>" + e.Title() + "</b>\n\n"
 	if e.UserName != "" {
 		msg += "👑 <b>" + e.UserName + "</b>\n"
 	}
+	if len(e.CoHosts) > 0 {
+		names := []string{}
+		for _, coHost := range e.CoHosts {
+			names = append(names, coHost.UserName)
+		}
+		msg += "🤝 <b>" + strings.Join(names, ", ") + "</b>\n"
+	}
 	if e.StartsAt != nil {
 		gTitle := url.QueryEscape(e.Name)
 		gStart := e.StartsAt.Format("20060102T150400")
-		gEndTime := e.StartsAt.Add(2 * time.Hour)
+		gEndTime := e.StartsAt.Add(EventDuration)
 		gEnd := gEndTime.Format("20060102T150400")
 		gtz := e.StartsAt.Location().String()
 		gDetails := url.QueryEscape(localizer.MustLocalizeMessage(&i18n.Message{ID: "CalendarEventDetails"}))
@@ -238,22 +598,52 @@ func (e Event) FormatMsg(localizer *i18n.Localizer, webUrl WebUrl) (string, *tel
 	if e.Location != nil && *e.Location != "" {
 		msg += "📍 <b>" + *e.Location + "</b>\n"
 	}
-	if e.Location != nil || e.StartsAt != nil {
+	if e.RSVPDeadline != nil {
+		msg += "⏳ <b>" + e.FormatRSVPDeadline(localizer, time.Now()) + "</b>\n"
+	}
+	if e.Location != nil || e.StartsAt != nil || e.RSVPDeadline != nil {
 		msg += "\n"
 	}
-	for _, bg := range e.BoardGames {
-		bgMsg, btn, err := e.FormatBG(localizer, webUrl, bg)
+	games := e.GamesBySlot()
+	for i, bg := range games {
+		if i >= maxGames {
+			break
+		}
+		if maxListed != allParticipants && len(bg.Participants) > maxListed {
+			collapsed = true
+		}
+
+		bgMsg, btn, err := e.formatBG(localizer, webUrl, bg, maxListed)
 		if err != nil {
 			log.Default().Printf("Failed to format board game: %v", err)
 			continue
 		}
 
-		msg += bgMsg
+		msg += slotHeader(games, i) + bgMsg
 
 		btns = append(btns, btn)
 
 	}
 
+	if hidden := len(e.BoardGames) - maxGames; hidden > 0 {
+		msg += localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: "MoreGames",
+			},
+			TemplateData: map[string]string{
+				"Count": strconv.Itoa(hidden),
+			},
+		}) + "\n\n"
+	}
+
+	rsvps := formatRSVP(localizer, "MaybeTitle", e.Tentative, maxListed) + formatRSVP(localizer, "DeclinedTitle", e.Declined, maxListed)
+	if rsvps != "" {
+		msg += rsvps + "\n"
+	}
+	if maxListed != allParticipants && (len(e.Tentative) > maxListed || len(e.Declined) > maxListed) {
+		collapsed = true
+	}
+
 	msg += localizer.MustLocalize(&i18n.LocalizeConfig{
 		DefaultMessage: &i18n.Message{
 			ID: "UpdatedAt",
@@ -269,25 +659,136 @@ func (e Event) FormatMsg(localizer *i18n.Localizer, webUrl WebUrl) (string, *tel
 		Data:   e.ID,
 	}
 
-	btns = append(btns, btn)
+	btns = append(btns, btn, telebot.InlineButton{
+		Text:   localizer.MustLocalizeMessage(&i18n.Message{ID: "MaybeButton"}),
+		Unique: string(Maybe),
+		Data:   e.ID,
+	}, telebot.InlineButton{
+		Text:   localizer.MustLocalizeMessage(&i18n.Message{ID: "BringGuest"}),
+		Unique: string(AddGuest),
+		Data:   e.ID,
+	})
+
+	appUrl := fmt.Sprintf("%s?startapp=%s", webUrl.BotMiniAppURL, e.ID)
+	if collapsed {
+		btns = append(btns, telebot.InlineButton{
+			Text: localizer.MustLocalizeMessage(&i18n.Message{ID: "SeeAllInApp"}),
+			URL:  appUrl,
+		})
+	}
 
-	log.Default().Printf("Adding AddGame button for chat: %d", e.ChatID)
 	// Add "AddGame" button for this chat
 	btn2 := telebot.InlineButton{
 		Text: localizer.MustLocalizeMessage(&i18n.Message{ID: "AddGame"}),
-		URL:  fmt.Sprintf("%s?startapp=%s", webUrl.BotMiniAppURL, e.ID),
+		URL:  appUrl,
 	}
 	btns = append(btns, btn2)
 
+	// a finished event cannot be joined anymore
+	if e.Finished {
+		btns = nil
+	}
+
 	markup := &telebot.ReplyMarkup{}
 	markup.InlineKeyboard = [][]telebot.InlineButton{}
 	for _, btn := range btns {
 		markup.InlineKeyboard = append(markup.InlineKeyboard, []telebot.InlineButton{btn})
 	}
 
-	log.Default().Printf("Formatted message in chat_id %d for event %s", e.ChatID, e.ID)
+	fits := len(utf16.Encode([]rune(msg))) <= MaxMessageLength && len(btns) <= MaxKeyboardButtons
+	return msg, markup, fits
+}
 
-	return msg, markup
+// RSVPClosed reports whether the RSVP deadline of the event passed at now.
+func (e Event) RSVPClosed(now time.Time) bool {
+	return e.RSVPDeadline != nil && !now.Before(*e.RSVPDeadline)
+}
+
+// EndedBefore reports whether the event was over at now, EventDuration after
+// it started. Events without a start time never end.
+func (e Event) EndedBefore(now time.Time) bool {
+	return e.StartsAt != nil && !e.StartsAt.Add(EventDuration).After(now)
+}
+
+// FormatRSVPDeadline describes the RSVP deadline, telling whether the lineup
+// is already final at now.
+func (e Event) FormatRSVPDeadline(localizer *i18n.Localizer, now time.Time) string {
+	id := "RSVPDeadlineLine"
+	if e.RSVPClosed(now) {
+		id = "RSVPClosedLine"
+	}
+
+	return localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: id,
+		},
+		TemplateData: map[string]string{
+			"Time": e.RSVPDeadline.Format("2006-01-02 15:04"),
+		},
+	})
+}
+
+// FormatLineup renders the final lineup of the event, posted once the RSVP
+// deadline passes. Participant lists are collapsed when they do not fit a
+// single message.
+func (e Event) FormatLineup(localizer *i18n.Localizer, url WebUrl) string {
+	var msg string
+	for _, maxListed := range []int{allParticipants, 10, 3, 0} {
+		msg = localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: "FinalLineup",
+			},
+			TemplateData: map[string]string{
+				"Event": e.Name,
+			},
+		}) + "\n\n"
+
+		games := e.GamesBySlot()
+		for i, bg := range games {
+			bgMsg, _, err := e.formatBG(localizer, url, bg, maxListed)
+			if err != nil {
+				log.Default().Printf("Failed to format board game: %v", err)
+				continue
+			}
+			msg += slotHeader(games, i) + bgMsg
+		}
+
+		if len(utf16.Encode([]rune(msg))) <= MaxMessageLength {
+			break
+		}
+	}
+
+	return strings.TrimSuffix(msg, "\n")
+}
+
+// formatRSVP renders on a single line the users who gave an answer that takes
+// no seat, listing at most maxListed of them.
+func formatRSVP(localizer *i18n.Localizer, titleID string, participants []Participant, maxListed int) string {
+	if len(participants) == 0 {
+		return ""
+	}
+
+	names := []string{}
+	for i, p := range participants {
+		if maxListed != allParticipants && i >= maxListed {
+			names = append(names, localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID: "MorePlayers",
+				},
+				TemplateData: map[string]string{
+					"Count": strconv.Itoa(len(participants) - maxListed),
+				},
+			}))
+			break
+		}
+		if p.IsTelegramUsername {
+			names = append(names, "@"+p.UserName)
+			continue
+		}
+		names = append(names, p.UserName)
+	}
+
+	return fmt.Sprintf("<b>%s</b> (%d): %s\n", localizer.MustLocalizeMessage(&i18n.Message{ID: titleID}), len(participants), strings.Join(names, ", "))
 }
 
 func ExtractBoardGameID(inputURL string) (int64, bool) {
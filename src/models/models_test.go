@@ -5,11 +5,13 @@ import (
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf16"
 
 	langpack "boardgame-night-bot/src/language"
 	"github.com/BurntSushi/toml"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"golang.org/x/text/language"
+	"gopkg.in/telebot.v3"
 )
 
 func setupLocalizer() *i18n.Localizer {
@@ -325,13 +327,13 @@ func TestFormatMsgFullEvent(t *testing.T) {
 		t.Errorf("Expected PLAYER_COUNTER to be replaced by localised label, got:\n%s", msg)
 	}
 
-	// Buttons: one join per game + "not coming" + "add game"
+	// Buttons: one join per game + "not coming" + "maybe" + "bring a guest" + "add game"
 	totalButtons := 0
 	for _, row := range markup.InlineKeyboard {
 		totalButtons += len(row)
 	}
-	if totalButtons != 4 { // join Gloomhaven + join PLAYER_COUNTER + not coming + add game
-		t.Errorf("Expected 4 inline buttons, got %d", totalButtons)
+	if totalButtons != 6 { // join Gloomhaven + join PLAYER_COUNTER + not coming + maybe + bring a guest + add game
+		t.Errorf("Expected 6 inline buttons, got %d", totalButtons)
 	}
 }
 
@@ -342,3 +344,311 @@ func mustParseTime(s string) time.Time {
 	}
 	return t
 }
+
+func waitlistEvent(maxPlayers int64, userIDs ...int64) *Event {
+	participants := []Participant{}
+	for _, id := range userIDs {
+		participants = append(participants, Participant{UserID: id, UserName: fmt.Sprintf("user%d", id)})
+	}
+
+	return &Event{
+		ID: "event-id",
+		BoardGames: []BoardGame{{
+			UUID:         "game-id",
+			MaxPlayers:   maxPlayers,
+			Participants: participants,
+		}},
+	}
+}
+
+func TestWaitlistChangesJoinAndPromote(t *testing.T) {
+	now := time.Now()
+
+	changes := WaitlistChanges(waitlistEvent(2, 1, 2), waitlistEvent(2, 1, 2, 3), now)
+	if len(changes) != 1 || changes[0].UserID != 3 || changes[0].Status != HookWaitlistStatusJoined || changes[0].Position != 1 {
+		t.Fatalf("Expected user 3 to join the queue at position 1, got %+v", changes)
+	}
+
+	changes = WaitlistChanges(waitlistEvent(2, 1, 2, 3), waitlistEvent(2, 2, 3), now)
+	if len(changes) != 1 || changes[0].UserID != 3 || changes[0].Status != HookWaitlistStatusPromoted {
+		t.Fatalf("Expected user 3 to be promoted, got %+v", changes)
+	}
+}
+
+func TestWaitlistChangesLeaveAndDemote(t *testing.T) {
+	now := time.Now()
+
+	changes := WaitlistChanges(waitlistEvent(2, 1, 2, 3), waitlistEvent(2, 1, 2), now)
+	if len(changes) != 1 || changes[0].UserID != 3 || changes[0].Status != HookWaitlistStatusLeft || changes[0].UserName != "user3" {
+		t.Fatalf("Expected user 3 to leave the queue, got %+v", changes)
+	}
+
+	changes = WaitlistChanges(waitlistEvent(3, 1, 2, 3), waitlistEvent(2, 1, 2, 3), now)
+	if len(changes) != 1 || changes[0].UserID != 3 || changes[0].Status != HookWaitlistStatusDemoted {
+		t.Fatalf("Expected user 3 to be demoted, got %+v", changes)
+	}
+}
+
+func TestWaitlistChangesIgnoresSeatedPlayers(t *testing.T) {
+	changes := WaitlistChanges(waitlistEvent(UnlimitedPlayers, 1), waitlistEvent(UnlimitedPlayers, 1, 2), time.Now())
+	if len(changes) != 0 {
+		t.Fatalf("Expected no waitlist changes, got %+v", changes)
+	}
+}
+
+func largeEvent(games, participantsPerGame int) Event {
+	event := Event{ID: "test-event", ChatID: 12345, Name: "Big night", UserName: "organizer"}
+	for g := 0; g < games; g++ {
+		bg := BoardGame{ID: int64(g + 1), Name: fmt.Sprintf("Game number %d", g+1), MaxPlayers: 4}
+		for p := 0; p < participantsPerGame; p++ {
+			bg.Participants = append(bg.Participants, Participant{
+				ID:                 int64(g*participantsPerGame + p),
+				UserID:             int64(g*participantsPerGame + p),
+				UserName:           fmt.Sprintf("player_with_a_long_name_%d_%d", g, p),
+				IsTelegramUsername: true,
+			})
+		}
+		event.BoardGames = append(event.BoardGames, bg)
+	}
+	return event
+}
+
+func countButtons(markup *telebot.ReplyMarkup) (int, bool) {
+	total := 0
+	seeAll := false
+	for _, row := range markup.InlineKeyboard {
+		for _, btn := range row {
+			total++
+			if strings.Contains(btn.Text, "See all in the app") {
+				seeAll = true
+			}
+		}
+	}
+	return total, seeAll
+}
+
+func TestFormatMsgSmallEventIsNotCollapsed(t *testing.T) {
+	localizer := setupLocalizer()
+	event := largeEvent(2, 6)
+
+	msg, markup := event.FormatMsg(localizer, WebUrl{BotMiniAppURL: "https://t.me/bot/app"})
+
+	if strings.Contains(msg, "more") {
+		t.Errorf("Expected every participant to be listed, got:\n%s", msg)
+	}
+	if _, seeAll := countButtons(markup); seeAll {
+		t.Error("Expected no 'see all' button for a small event")
+	}
+}
+
+func TestFormatMsgFinishedEventHasNoButtons(t *testing.T) {
+	localizer := setupLocalizer()
+	event := largeEvent(2, 6)
+	event.Finished = true
+
+	msg, markup := event.FormatMsg(localizer, WebUrl{BotMiniAppURL: "https://t.me/bot/app"})
+
+	if !strings.Contains(msg, "Game number 2") {
+		t.Errorf("Expected the games to be listed, got:\n%s", msg)
+	}
+	if markup == nil || len(markup.InlineKeyboard) != 0 {
+		t.Errorf("Expected no buttons for a finished event, got %v", markup)
+	}
+}
+
+func TestFormatMsgCollapsesParticipants(t *testing.T) {
+	localizer := setupLocalizer()
+	event := largeEvent(10, 40)
+
+	msg, markup := event.FormatMsg(localizer, WebUrl{BotMiniAppURL: "https://t.me/bot/app"})
+
+	if length := len(utf16.Encode([]rune(msg))); length > MaxMessageLength {
+		t.Fatalf("Expected message within %d characters, got %d", MaxMessageLength, length)
+	}
+	if !strings.Contains(msg, "more</i>") {
+		t.Errorf("Expected collapsed participant lists, got:\n%s", msg)
+	}
+	if !strings.Contains(msg, "Game number 10") {
+		t.Errorf("Expected every game to be kept, got:\n%s", msg)
+	}
+	if _, seeAll := countButtons(markup); !seeAll {
+		t.Error("Expected a 'see all' button")
+	}
+}
+
+func TestFormatMsgLeavesOutGamesBeyondLimits(t *testing.T) {
+	localizer := setupLocalizer()
+	event := largeEvent(150, 1)
+
+	msg, markup := event.FormatMsg(localizer, WebUrl{BotMiniAppURL: "https://t.me/bot/app"})
+
+	if length := len(utf16.Encode([]rune(msg))); length > MaxMessageLength {
+		t.Fatalf("Expected message within %d characters, got %d", MaxMessageLength, length)
+	}
+	buttons, seeAll := countButtons(markup)
+	if buttons > MaxKeyboardButtons {
+		t.Errorf("Expected at most %d buttons, got %d", MaxKeyboardButtons, buttons)
+	}
+	if !seeAll {
+		t.Error("Expected a 'see all' button")
+	}
+	if !strings.Contains(msg, "more games") {
+		t.Errorf("Expected hidden games to be mentioned, got:\n%s", msg)
+	}
+}
+
+func TestFormatBGGuestsTakeSeats(t *testing.T) {
+	localizer := setupLocalizer()
+	url := WebUrl{BaseUrl: "http://example.com", BotMiniAppURL: "https://t.me/boardgame_night_bot"}
+
+	bg := BoardGame{
+		ID:         1,
+		Name:       "Test Game",
+		MaxPlayers: 3,
+		Participants: []Participant{
+			{ID: 1, UserID: 1, UserName: "host", IsTelegramUsername: true, Guests: []string{"Anna", ""}},
+			{ID: 2, UserID: 2, UserName: "late", IsTelegramUsername: true},
+		},
+	}
+
+	if count := bg.SeatCount(); count != 4 {
+		t.Fatalf("Expected 4 seats, got %d", count)
+	}
+	if position, ok := bg.WaitlistPosition(2); !ok || position != 1 {
+		t.Errorf("Expected late to be first in queue, got %d (%v)", position, ok)
+	}
+
+	event := Event{ID: "test-event"}
+	msg, _, err := event.FormatBG(localizer, url, bg)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, expected := range []string{"4/3", "Anna (guest of host)", "- guest of host", "@late (queued"} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected message to contain %q, got:\n%s", expected, msg)
+		}
+	}
+	if strings.Contains(msg, "@Anna") {
+		t.Errorf("Expected guests to not be tagged, got:\n%s", msg)
+	}
+}
+
+func TestFormatMsgListsTentativeAndDeclined(t *testing.T) {
+	localizer := setupLocalizer()
+	url := WebUrl{BaseUrl: "http://example.com", BotMiniAppURL: "https://t.me/boardgame_night_bot"}
+
+	event := Event{
+		ID:   "test-event",
+		Name: "Game night",
+		BoardGames: []BoardGame{{
+			ID:           1,
+			Name:         "Catan",
+			MaxPlayers:   4,
+			Participants: []Participant{{ID: 1, UserID: 1, UserName: "going", IsTelegramUsername: true}},
+		}},
+		Tentative: []Participant{{ID: 2, UserID: 2, UserName: "unsure", IsTelegramUsername: true}, {ID: 3, UserID: 3, UserName: "Bob Smith"}},
+		Declined:  []Participant{{ID: 4, UserID: 4, UserName: "busy", IsTelegramUsername: true}},
+	}
+
+	msg, markup := event.FormatMsg(localizer, url)
+
+	for _, expected := range []string{"(1/4 players)", "🤔 Maybe</b> (2): @unsure, Bob Smith", "🙅 Can't make it</b> (1): @busy"} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected message to contain %q, got:\n%s", expected, msg)
+		}
+	}
+
+	found := false
+	for _, row := range markup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.Unique == string(Maybe) && btn.Data == event.ID {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a maybe button, got %+v", markup.InlineKeyboard)
+	}
+}
+
+func TestFormatMsgShowsRSVPDeadline(t *testing.T) {
+	localizer := setupLocalizer()
+	url := WebUrl{BaseUrl: "http://example.com", BotMiniAppURL: "https://t.me/boardgame_night_bot"}
+
+	deadline := time.Date(2030, 1, 2, 18, 0, 0, 0, time.UTC)
+	event := Event{ID: "test-event", Name: "Game night", RSVPDeadline: &deadline}
+	if msg, _ := event.FormatMsg(localizer, url); !strings.Contains(msg, "⏳ <b>RSVP by 2030-01-02 18:00</b>") {
+		t.Errorf("Expected the deadline in the message, got:\n%s", msg)
+	}
+
+	passed := time.Now().Add(-time.Hour)
+	event.RSVPDeadline = &passed
+	if !event.RSVPClosed(time.Now()) {
+		t.Error("Expected RSVPs to be closed after the deadline")
+	}
+	if msg, _ := event.FormatMsg(localizer, url); !strings.Contains(msg, "Lineup final since") {
+		t.Errorf("Expected the lineup to be final, got:\n%s", msg)
+	}
+}
+
+func TestFormatMsgShowsLockAndCoHosts(t *testing.T) {
+	localizer := setupLocalizer()
+	url := WebUrl{BaseUrl: "http://example.com", BotMiniAppURL: "https://t.me/boardgame_night_bot"}
+
+	event := Event{ID: "test-event", Name: "Game night", UserID: 1, UserName: "host", Locked: true,
+		CoHosts: []CoHost{{UserID: 2, UserName: "anna"}, {UserID: 3, UserName: "mario"}}}
+	msg, _ := event.FormatMsg(localizer, url)
+	if !strings.Contains(msg, "📆 <b>🔒 Game night</b>") || !strings.Contains(msg, "🤝 <b>anna, mario</b>") {
+		t.Errorf("Expected the lock and the co-hosts in the message, got:\n%s", msg)
+	}
+
+	if !event.IsCoHost(1) || !event.IsCoHost(3) || event.IsCoHost(4) {
+		t.Error("Expected the owner and the co-hosts to be hosts")
+	}
+}
+
+func TestFormatMsgGroupsGamesBySlot(t *testing.T) {
+	localizer := setupLocalizer()
+	url := WebUrl{BaseUrl: "http://example.com", BotMiniAppURL: "https://t.me/boardgame_night_bot"}
+
+	event := Event{ID: "test-event", Name: "Long night", BoardGames: []BoardGame{
+		{ID: 1, Name: "Azul", MaxPlayers: 4, Slot: "22:00"},
+		{ID: 2, Name: "Catan", MaxPlayers: 4},
+		{ID: 3, Name: "Brass", MaxPlayers: 4, Slot: "20:00"},
+		{ID: 4, Name: "Dune", MaxPlayers: 4, Slot: "22:00"},
+	}}
+	msg, markup := event.FormatMsg(localizer, url)
+
+	order := []string{"[Catan]", "🕒 <b>20:00</b>", "[Brass]", "🕒 <b>22:00</b>", "[Azul]", "[Dune]"}
+	last := -1
+	for _, s := range order {
+		i := strings.Index(msg, s)
+		if i <= last {
+			t.Fatalf("Expected %q after the previous lines, got:\n%s", s, msg)
+		}
+		last = i
+	}
+	if strings.Count(msg, "🕒") != 2 {
+		t.Errorf("Expected one title per slot, got:\n%s", msg)
+	}
+	if markup.InlineKeyboard[1][0].Text != "Join Brass" {
+		t.Errorf("Expected the join buttons in the order of the slots, got %+v", markup.InlineKeyboard)
+	}
+}
+
+func TestEventEndedBefore(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-EventDuration)
+	starting := now.Add(-time.Hour)
+
+	if (Event{}).EndedBefore(now) {
+		t.Error("Expected an event without a start time never to end")
+	}
+	if (Event{StartsAt: &starting}).EndedBefore(now) {
+		t.Error("Expected an event started an hour ago not to be over")
+	}
+	if !(Event{StartsAt: &started}).EndedBefore(now) {
+		t.Error("Expected an event started EventDuration ago to be over")
+	}
+}
@@ -0,0 +1,229 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func callbackUpdate(userID int64, data string) string {
+	return fmt.Sprintf(`{"update_id":3,"callback_query":{"id":"cb-%d",
+		"from":{"id":%d,"first_name":"user%d","language_code":"en"},
+		"message":{"message_id":1,"date":0,"chat":{"id":-100,"type":"group"}},
+		"chat_instance":"1","data":%q}}`, userID, userID, userID, "\f"+data)
+}
+
+// lastCallbackAnswer returns the answerCallbackQuery sent to Telegram, failing
+// when none was sent.
+func (h *webhookHarness) lastCallbackAnswer(t *testing.T) apiCall {
+	t.Helper()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.calls) - 1; i >= 0; i-- {
+		if h.calls[i].Method == "answerCallbackQuery" {
+			return h.calls[i]
+		}
+	}
+	t.Fatalf("no answerCallbackQuery in %v", h.calls)
+	return apiCall{}
+}
+
+func (h *webhookHarness) createEventWithGame(t *testing.T, maxPlayers int) (string, int64) {
+	t.Helper()
+	messageID := int64(1)
+	eventID, err := h.tg.DB.InsertEvent(nil, -100, 1, "host", "Game night", &messageID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameID, _, err := h.tg.DB.InsertBoardGame(eventID, nil, "Catan", "", maxPlayers, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eventID, gameID
+}
+
+func TestCallbackJoinAnswersWithToast(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 1)
+	data := fmt.Sprintf("%s|%s|%d", models.AddPlayer, eventID, gameID)
+
+	if w := h.post(testWebhookSecret, callbackUpdate(42, data)); w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	answer := h.lastCallbackAnswer(t)
+	if answer.Params["text"] != "You joined Catan ✅" || answer.Params["show_alert"] == true {
+		t.Errorf("unexpected answer %v", answer.Params)
+	}
+
+	h.post(testWebhookSecret, callbackUpdate(43, data))
+	answer = h.lastCallbackAnswer(t)
+	if answer.Params["text"] != "Catan is full: you are #1 on the waitlist ⏳" {
+		t.Errorf("unexpected answer %v", answer.Params)
+	}
+
+	for _, call := range h.calls {
+		if call.Method == "sendMessage" {
+			t.Errorf("expected no message in the chat, got %v", call.Params)
+		}
+	}
+}
+
+func TestCallbackJoinLockedEvent(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 4)
+	if err := h.tg.DB.UpdateEventLocked(eventID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// a lock only keeps the games from being changed, everyone can still join
+	h.post(testWebhookSecret, callbackUpdate(42, fmt.Sprintf("%s|%s|%d", models.AddPlayer, eventID, gameID)))
+	if answer := h.lastCallbackAnswer(t); answer.Params["text"] != "You joined Catan ✅" {
+		t.Errorf("unexpected answer %v", answer.Params)
+	}
+}
+
+func TestCallbackLeaveAnswersWithToast(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 4)
+	if _, err := h.tg.DB.InsertParticipant(nil, eventID, gameID, 42, "user42", false); err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf("%s|%s", models.Cancel, eventID)
+
+	h.post(testWebhookSecret, callbackUpdate(42, data))
+	if answer := h.lastCallbackAnswer(t); answer.Params["text"] != "Got it, you can't make it 🙅" {
+		t.Errorf("unexpected answer %v", answer.Params)
+	}
+
+	event, err := h.tg.DB.SelectEventByEventID(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(event.BoardGames[0].Participants) != 0 || len(event.Declined) != 1 || event.Declined[0].UserID != 42 {
+		t.Errorf("expected the seat to be freed and the answer recorded, got %+v", event)
+	}
+}
+
+func TestCallbackMaybeFreesTheSeat(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 1)
+	for _, userID := range []int64{42, 43} {
+		if _, err := h.tg.DB.InsertParticipant(nil, eventID, gameID, userID, fmt.Sprintf("user%d", userID), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h.post(testWebhookSecret, callbackUpdate(42, fmt.Sprintf("%s|%s", models.Maybe, eventID)))
+	if answer := h.lastCallbackAnswer(t); answer.Params["show_alert"] == true {
+		t.Errorf("unexpected alert %v", answer.Params)
+	}
+
+	event, err := h.tg.DB.SelectEventByEventID(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(event.Tentative) != 1 || event.Tentative[0].UserID != 42 || event.Tentative[0].Status != models.RSVPMaybe {
+		t.Fatalf("expected user 42 to be tentative, got %+v", event.Tentative)
+	}
+	if position, ok := event.BoardGames[0].WaitlistPosition(43); !ok || position != 0 {
+		t.Errorf("expected user 43 to get the free seat, got %d (%v)", position, ok)
+	}
+
+	// joining a game again replaces the tentative answer
+	h.post(testWebhookSecret, callbackUpdate(42, fmt.Sprintf("%s|%s|%d", models.AddPlayer, eventID, gameID)))
+	if event, err = h.tg.DB.SelectEventByEventID(eventID); err != nil {
+		t.Fatal(err)
+	}
+	if len(event.Tentative) != 0 || event.BoardGames[0].SeatCount() != 2 {
+		t.Errorf("expected user 42 back in the game, got %+v", event)
+	}
+}
+
+func TestCallbackJoinOneGamePerSlot(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, _ := h.createEventWithGame(t, 4)
+	games := map[string]int64{}
+	for _, game := range []struct{ name, slot string }{{"Brass", "20:00"}, {"Azul", "20:00"}, {"Dune", "22:00"}} {
+		id, _, err := h.tg.DB.InsertBoardGame(eventID, nil, game.name, game.slot, 4, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		games[game.name] = id
+	}
+	join := func(name string) {
+		h.post(testWebhookSecret, callbackUpdate(42, fmt.Sprintf("%s|%s|%d", models.AddPlayer, eventID, games[name])))
+	}
+	seats := func() []string {
+		event, err := h.tg.DB.SelectEventByEventID(eventID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, bg := range event.BoardGames {
+			for _, p := range bg.Participants {
+				names = append(names, fmt.Sprintf("%s%v", bg.Name, p.Guests))
+			}
+		}
+		return names
+	}
+
+	join("Brass")
+	join("Dune")
+	if got := fmt.Sprint(seats()); got != "[Brass[] Dune[]]" {
+		t.Fatalf("expected a seat in each slot, got %s", got)
+	}
+
+	// a game of the same slot replaces the seat, guests included
+	if err := h.tg.DB.UpdateParticipantGuests(eventID, 42, games["Brass"], []string{"Anna"}); err != nil {
+		t.Fatal(err)
+	}
+	join("Azul")
+	if got := fmt.Sprint(seats()); got != "[Azul[Anna] Dune[]]" {
+		t.Fatalf("expected the 20:00 seat to move to Azul, got %s", got)
+	}
+
+	h.post(testWebhookSecret, callbackUpdate(42, fmt.Sprintf("%s|%s", models.Maybe, eventID)))
+	if got := seats(); len(got) != 0 {
+		t.Errorf("expected every seat to be freed, got %v", got)
+	}
+}
+
+func TestCallbackInvalidDataAnswersWithAlert(t *testing.T) {
+	h := newWebhookHarness(t)
+
+	h.post(testWebhookSecret, callbackUpdate(42, string(models.AddPlayer)+"|not-an-id|x"))
+	answer := h.lastCallbackAnswer(t)
+	if answer.Params["show_alert"] != true {
+		t.Errorf("expected an alert, got %v", answer.Params)
+	}
+	for _, call := range h.calls {
+		if call.Method == "sendMessage" {
+			t.Errorf("expected no message in the chat, got %v", call.Params)
+		}
+	}
+}
+
+func TestCallbackJoinAfterDeadlineAnswersWithAlert(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 4)
+	deadline := time.Now().Add(-time.Hour)
+	if err := h.tg.DB.UpdateEventRSVPDeadline(eventID, &deadline); err != nil {
+		t.Fatal(err)
+	}
+
+	h.post(testWebhookSecret, callbackUpdate(42, fmt.Sprintf("%s|%s|%d", models.AddPlayer, eventID, gameID)))
+	answer := h.lastCallbackAnswer(t)
+	if answer.Params["text"] != "The RSVP deadline has passed, the lineup is final 🔒" || answer.Params["show_alert"] != true {
+		t.Errorf("unexpected answer %v", answer.Params)
+	}
+
+	event, err := h.tg.DB.SelectEventByEventID(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.RSVPDeadline == nil || len(event.BoardGames[0].Participants) != 0 {
+		t.Errorf("expected the lineup to stay empty, got %+v", event)
+	}
+}
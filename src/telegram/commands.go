@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"gopkg.in/telebot.v3"
+)
+
+type botCommand struct {
+	Name          string
+	DescriptionID string
+	// AdminOnly commands are shown only to chat administrators and in
+	// private chats, where the user owns the chat.
+	AdminOnly bool
+	Handler   telebot.HandlerFunc
+}
+
+// commands lists the slash commands of the bot. It is the single source for
+// both the handlers and the menu registered with Telegram.
+func (t Telegram) commands() []botCommand {
+	return []botCommand{
+		{Name: "start", DescriptionID: "CommandStart", Handler: t.Start},
+		{Name: "help", DescriptionID: "CommandHelp", Handler: t.Start},
+		{Name: "create", DescriptionID: "CommandCreate", Handler: t.CreateGame},
+		{Name: "add_game", DescriptionID: "CommandAddGame", Handler: t.AddGame},
+		{Name: "my", DescriptionID: "CommandMy", Handler: t.My},
+		{Name: "language", DescriptionID: "CommandLanguage", Handler: t.SetLanguage},
+		{Name: "location", DescriptionID: "CommandLocation", Handler: t.SetDefaultLocation},
+		{Name: "timezone", DescriptionID: "CommandTimezone", Handler: t.SetDefaultTimezone},
+		{Name: "autopin", DescriptionID: "CommandAutoPin", Handler: t.SetAutoPin},
+		{Name: "topics", DescriptionID: "CommandTopics", Handler: t.SetEventTopics},
+		{Name: "lock", DescriptionID: "CommandLock", Handler: t.LockEvent},
+		{Name: "unlock", DescriptionID: "CommandUnlock", Handler: t.UnlockEvent},
+		{Name: "deadline", DescriptionID: "CommandDeadline", Handler: t.SetRSVPDeadline},
+		{Name: "cohost", DescriptionID: "CommandCoHost", Handler: t.CoHost},
+		{Name: "kick", DescriptionID: "CommandKick", Handler: t.Kick},
+		{Name: "move", DescriptionID: "CommandMove", Handler: t.Move},
+		{Name: "clone", DescriptionID: "CommandClone", Handler: t.Clone},
+		{Name: "register", DescriptionID: "CommandRegister", AdminOnly: true, Handler: t.RegisterWebhook},
+		{Name: "test", DescriptionID: "CommandTest", AdminOnly: true, Handler: t.TestWebhook},
+		{Name: "forget_me", DescriptionID: "CommandForgetMe", Handler: t.ForgetMe},
+		{Name: "purge", DescriptionID: "CommandPurge", AdminOnly: true, Handler: t.Purge},
+		{Name: "export", DescriptionID: "CommandExport", AdminOnly: true, Handler: t.Export},
+	}
+}
+
+// RegisterCommands publishes the command menu to Telegram for every language
+// of the LanguagePack, plus a default one in english for the other languages.
+func (t Telegram) RegisterCommands() error {
+	var errs []error
+
+	languages := append([]string{""}, t.LanguagePack.Languages...)
+	for _, lang := range languages {
+		localizer := i18n.NewLocalizer(t.LanguageBundle, lang, "en")
+
+		everyone := []telebot.Command{}
+		all := []telebot.Command{}
+		for _, cmd := range t.commands() {
+			command := telebot.Command{
+				Text:        cmd.Name,
+				Description: localizer.MustLocalizeMessage(&i18n.Message{ID: cmd.DescriptionID}),
+			}
+			if !cmd.AdminOnly {
+				everyone = append(everyone, command)
+			}
+			all = append(all, command)
+		}
+
+		scopes := []struct {
+			scope    telebot.CommandScopeType
+			commands []telebot.Command
+		}{
+			{telebot.CommandScopeDefault, everyone},
+			{telebot.CommandScopeAllPrivateChats, all},
+			{telebot.CommandScopeAllChatAdmin, all},
+		}
+		for _, s := range scopes {
+			if err := t.Bot.SetCommands(s.commands, telebot.CommandScope{Type: s.scope}, lang); err != nil {
+				errs = append(errs, fmt.Errorf("set %s commands for language %q: %w", s.scope, lang, err))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		log.Default().Printf("registered bot commands for languages: %v", t.LanguagePack.Languages)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+package telegram
+
+import (
+	"slices"
+	"testing"
+
+	"gopkg.in/telebot.v3"
+)
+
+func TestRegisterCommandsPerLanguageAndScope(t *testing.T) {
+	h := newWebhookHarness(t)
+
+	if err := h.tg.RegisterCommands(); err != nil {
+		t.Fatal(err)
+	}
+
+	type key struct{ scope, lang string }
+	commands := map[key]map[string]string{}
+	for _, call := range h.calls {
+		if call.Method != "setMyCommands" {
+			t.Fatalf("unexpected api call %s", call.Method)
+		}
+		scope := call.Params["scope"].(map[string]any)["type"].(string)
+		lang, _ := call.Params["language_code"].(string)
+
+		descriptions := map[string]string{}
+		for _, c := range call.Params["commands"].([]any) {
+			command := c.(map[string]any)
+			descriptions[command["command"].(string)] = command["description"].(string)
+		}
+		commands[key{scope, lang}] = descriptions
+	}
+
+	languages := append([]string{""}, h.tg.LanguagePack.Languages...)
+	if want := len(languages) * 3; len(h.calls) != want {
+		t.Fatalf("setMyCommands calls = %d, want %d", len(h.calls), want)
+	}
+
+	for _, lang := range languages {
+		everyone := commands[key{telebot.CommandScopeDefault, lang}]
+		admins := commands[key{telebot.CommandScopeAllChatAdmin, lang}]
+		private := commands[key{telebot.CommandScopeAllPrivateChats, lang}]
+
+		for _, cmd := range h.tg.commands() {
+			if _, ok := admins[cmd.Name]; !ok {
+				t.Errorf("lang %q: /%s missing for admins", lang, cmd.Name)
+			}
+			if _, ok := private[cmd.Name]; !ok {
+				t.Errorf("lang %q: /%s missing in private chats", lang, cmd.Name)
+			}
+			if _, ok := everyone[cmd.Name]; ok == cmd.AdminOnly {
+				t.Errorf("lang %q: /%s visible to everyone = %v, want %v", lang, cmd.Name, ok, !cmd.AdminOnly)
+			}
+		}
+	}
+
+	if !slices.Contains(h.tg.LanguagePack.Languages, "it") {
+		t.Fatal("italian localization not loaded")
+	}
+	if got := commands[key{telebot.CommandScopeDefault, "it"}]["create"]; got != "Crea un nuovo evento" {
+		t.Errorf("italian /create description = %q", got)
+	}
+	if got := commands[key{telebot.CommandScopeDefault, ""}]["create"]; got != "Create a new event" {
+		t.Errorf("default /create description = %q", got)
+	}
+}
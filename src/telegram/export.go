@@ -0,0 +1,50 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/web/api"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"gopkg.in/telebot.v3"
+)
+
+// Export sends a chat admin a zip with the events, games and participants of
+// the chat as JSON and CSV, in a private message.
+func (t Telegram) Export(c telebot.Context) error {
+	localizer := t.Localizer(c)
+	export, err := t.Service.ExportChat(c.Chat().ID, c.Sender().ID)
+	if err != nil {
+		if errors.Is(err, api.ErrNotChatAdmin) {
+			return c.Reply(localizer.MustLocalizeMessage(&i18n.Message{ID: "OnlyAdminsCanExport"}))
+		}
+
+		log.Default().Println("failed to export chat:", err)
+		return c.Reply(localizer.MustLocalizeMessage(&i18n.Message{ID: "FailedToExport"}))
+	}
+
+	var buf bytes.Buffer
+	if err = export.WriteZip(&buf); err != nil {
+		log.Default().Println("failed to write export:", err)
+		return c.Reply(localizer.MustLocalizeMessage(&i18n.Message{ID: "FailedToExport"}))
+	}
+
+	document := &telebot.Document{
+		File:     telebot.FromReader(&buf),
+		FileName: fmt.Sprintf("chat-%d-%s.zip", export.ChatID, export.ExportedAt.Format("2006-01-02")),
+		MIME:     "application/zip",
+		Caption:  localizer.MustLocalizeMessage(&i18n.Message{ID: "ExportCaption"}),
+	}
+	if _, err = t.Bot.Send(c.Sender(), document); err != nil {
+		log.Default().Println("failed to send export:", err)
+		return c.Reply(localizer.MustLocalizeMessage(&i18n.Message{ID: "ExportStartBot"}))
+	}
+
+	if c.Chat().ID == c.Sender().ID {
+		return nil
+	}
+
+	return c.Reply(localizer.MustLocalizeMessage(&i18n.Message{ID: "ExportSent"}))
+}
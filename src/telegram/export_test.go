@@ -0,0 +1,167 @@
+package telegram
+
+import (
+	"archive/zip"
+	"boardgame-night-bot/src/models"
+	"boardgame-night-bot/src/web/api"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func readZipFile(t *testing.T, archive *zip.Reader, name string) []byte {
+	t.Helper()
+	f, err := archive.Open(name)
+	if err != nil {
+		t.Fatalf("missing %s: %v", name, err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestExport(t *testing.T) {
+	h := newWebhookHarness(t)
+	h.createEventWithGame(t, 4)
+	db := h.tg.DB
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 1, "/export"))
+	if reply := h.lastReply(t); reply != "Only chat administrators can export the data of the chat." {
+		t.Errorf("unexpected reply %q", reply)
+	}
+
+	eventID, err := db.InsertEventWithOptionalGame(nil, 42, 42, "ada", "Solo night", nil, nil, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameID, _, err := db.InsertBoardGame(eventID, nil, "Azul", "", 4, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.InsertParticipant(nil, eventID, gameID, 7, "anna", true); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.UpdateParticipantGuests(eventID, 7, gameID, []string{"Luca", "Sara"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.SetParticipantRSVP(nil, eventID, 8, "bob", true, models.RSVPMaybe); err != nil {
+		t.Fatal(err)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(42, "private", 42, "/export"))
+	h.mu.Lock()
+	call := h.calls[len(h.calls)-1]
+	h.mu.Unlock()
+	if call.Method != "sendDocument" || call.Params["chat_id"] != "42" {
+		t.Fatalf("expected the export to be sent to the user, got %s %v", call.Method, call.Params)
+	}
+
+	content := call.Files["document"]
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var export models.ChatExport
+	if err = json.Unmarshal(readZipFile(t, archive, "export.json"), &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.ChatID != 42 || len(export.Events) != 1 || export.Events[0].ID != eventID {
+		t.Fatalf("expected only the event of the chat, got %+v", export)
+	}
+	if len(export.Games) != 1 || export.Games[0].Name != "Azul" {
+		t.Errorf("unexpected games %+v", export.Games)
+	}
+	if len(export.Participants) != 2 {
+		t.Fatalf("expected a player and a maybe, got %+v", export.Participants)
+	}
+	if p := export.Participants[0]; p.GameID != export.Games[0].ID || p.Status != models.RSVPGoing || len(p.Guests) != 2 {
+		t.Errorf("unexpected player %+v", p)
+	}
+	if p := export.Participants[1]; p.GameID != "" || p.Status != models.RSVPMaybe {
+		t.Errorf("unexpected answer %+v", p)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(readZipFile(t, archive, "participants.csv"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1][4] != "anna" || records[1][7] != "Luca;Sara" {
+		t.Errorf("unexpected participants.csv %v", records)
+	}
+	for _, name := range []string{"events.csv", "games.csv"} {
+		readZipFile(t, archive, name)
+	}
+}
+
+func TestImportChatKeepsUUIDs(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 4)
+	db := h.tg.DB
+
+	if _, err := db.InsertParticipant(nil, eventID, gameID, 7, "anna", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateParticipantGuests(eventID, 7, gameID, []string{"Luca"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SetParticipantRSVP(nil, eventID, 8, "bob", false, models.RSVPDeclined); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddEventCoHost(eventID, 7, "anna"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := db.UpdateEventRSVPDeadline(eventID, &deadline); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetEventFinished(eventID); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := db.SelectEventsByChatID(-100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := events[0]
+	export := models.NewChatExport(-100, events, time.Now())
+
+	if err = h.tg.Service.ImportChat(-300, &export); !errors.Is(err, api.ErrAlreadyImported) {
+		t.Fatalf("expected the import to be rejected while the event exists, got %v", err)
+	}
+
+	if _, err = db.PurgeChat(-100); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.tg.Service.ImportChat(-300, &export); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := db.SelectEventByEventID(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.ChatID != -300 || imported.Name != source.Name || !imported.Finished || imported.MessageID != nil {
+		t.Errorf("unexpected imported event %+v", imported)
+	}
+	if imported.RSVPDeadline == nil || !imported.RSVPDeadline.Equal(deadline) || len(imported.CoHosts) != 1 {
+		t.Errorf("expected the deadline and the co-hosts to be kept, got %+v", imported)
+	}
+	if len(imported.BoardGames) != 1 || imported.BoardGames[0].UUID != source.BoardGames[0].UUID {
+		t.Fatalf("expected the game UUID to be kept, got %+v", imported.BoardGames)
+	}
+	players := imported.BoardGames[0].Participants
+	if len(players) != 1 || players[0].UUID != source.BoardGames[0].Participants[0].UUID || len(players[0].Guests) != 1 {
+		t.Errorf("expected the player to be kept, got %+v", players)
+	}
+	if len(imported.Declined) != 1 || imported.Declined[0].UUID != source.Declined[0].UUID {
+		t.Errorf("expected the answer to be kept, got %+v", imported.Declined)
+	}
+}
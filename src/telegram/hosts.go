@@ -0,0 +1,346 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/database"
+	"boardgame-night-bot/src/models"
+	"boardgame-night-bot/src/utils"
+	"boardgame-night-bot/src/web/api"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"gopkg.in/telebot.v3"
+)
+
+// eventUser is a user picked by a host command.
+type eventUser struct {
+	ID   int64
+	Name string
+}
+
+// CoHost adds or removes a co-host of the latest event of the chat. The user
+// is the sender of the replied message or a participant mentioned by
+// username: /cohost @name, /cohost remove @name.
+func (t Telegram) CoHost(c telebot.Context) error {
+	var err error
+	userID := c.Sender().ID
+
+	args := c.Args()
+	add := true
+	if len(args) > 0 && args[0] == "remove" {
+		add = false
+		args = args[1:]
+	}
+
+	event, user, ok := t.pickEventUser(c, args, "/cohost", "@username | remove @username")
+	if !ok {
+		return nil
+	}
+
+	if event, err = t.Service.SetCoHost(event.ID, userID, user.ID, user.Name, add); err != nil {
+		switch {
+		case errors.Is(err, api.ErrNotEventOwner):
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "OnlyOwnerCanSetCoHost"}))
+		case errors.Is(err, api.ErrNotCoHost):
+			return t.replyUserNotFound(c, user.Name)
+		case errors.Is(err, api.ErrEventFinished):
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventFinished"}))
+		}
+
+		log.Default().Println("failed to set co-host:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "FailedToSetCoHost"}))
+	}
+
+	messageID := "CoHostRemoved"
+	if add {
+		messageID = "CoHostAdded"
+	}
+
+	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: messageID,
+		},
+		TemplateData: map[string]string{
+			"User":  user.Name,
+			"Event": event.Name,
+		},
+	}))
+}
+
+// Kick lets a host remove a participant from the latest event of the chat:
+// /kick @name, or /kick in reply to one of their messages.
+func (t Telegram) Kick(c telebot.Context) error {
+	var err error
+	userID := c.Sender().ID
+	userName, _ := DefineUsername(c.Sender())
+
+	event, user, ok := t.pickEventUser(c, c.Args(), "/kick", "@username")
+	if !ok {
+		return nil
+	}
+
+	if event, err = t.Service.KickParticipant(event.ID, userID, userName, user.ID); err != nil {
+		switch {
+		case errors.Is(err, api.ErrNotHost):
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "OnlyHostsCanManage"}))
+		case errors.Is(err, database.ErrNoRows):
+			return t.replyUserNotFound(c, user.Name)
+		case errors.Is(err, api.ErrEventFinished):
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventFinished"}))
+		}
+
+		log.Default().Println("failed to kick participant:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "FailedToManageParticipant"}))
+	}
+
+	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: "ParticipantKicked",
+		},
+		TemplateData: map[string]string{
+			"User":  user.Name,
+			"Event": event.Name,
+		},
+	}))
+}
+
+// Move shows a host the games a participant of the latest event of the chat
+// can be moved to: /move @name, or /move in reply to one of their messages.
+func (t Telegram) Move(c telebot.Context) error {
+	event, user, ok := t.pickEventUser(c, c.Args(), "/move", "@username")
+	if !ok {
+		return nil
+	}
+
+	if !t.Service.IsHost(event, c.Sender().ID) {
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "OnlyHostsCanManage"}))
+	}
+
+	var from *models.BoardGame
+	seated := map[int64]bool{}
+	for i := range event.BoardGames {
+		for _, p := range event.BoardGames[i].Participants {
+			if p.UserID == user.ID {
+				from = &event.BoardGames[i]
+				seated[from.ID] = true
+			}
+		}
+	}
+	if from == nil {
+		return t.replyUserNotFound(c, user.Name)
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	rows := []telebot.Row{}
+	for _, bg := range event.BoardGames {
+		if seated[bg.ID] || bg.Name == models.PLAYER_COUNTER {
+			continue
+		}
+		rows = append(rows, markup.Row(markup.Data(bg.Name, string(models.MoveTo), event.ID, strconv.FormatInt(user.ID, 10), strconv.FormatInt(bg.ID, 10))))
+	}
+	if len(rows) == 0 {
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "NoGameToMoveTo"}))
+	}
+	markup.Inline(rows...)
+
+	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: "ChooseGameToMoveTo",
+		},
+		TemplateData: map[string]string{
+			"User": user.Name,
+			"Game": from.Name,
+		},
+	}), markup)
+}
+
+// CallbackMoveTo moves a participant to the game picked in the selector sent
+// by /move. Only a host can pick it.
+func (t Telegram) CallbackMoveTo(c telebot.Context) error {
+	data := c.Callback().Data
+	parts := strings.Split(data, "|")
+	if len(parts) != 4 || !models.IsValidUUID(parts[1]) {
+		log.Default().Println("Invalid data:", data)
+		return t.alertCallback(c, "InvalidData")
+	}
+
+	participantID, err1 := strconv.ParseInt(parts[2], 10, 64)
+	gameID, err2 := strconv.ParseInt(parts[3], 10, 64)
+	if err1 != nil || err2 != nil {
+		log.Default().Println("Invalid parsed id:", data)
+		return t.alertCallback(c, "InvalidData")
+	}
+
+	userName, _ := DefineUsername(c.Sender())
+	event, game, err := t.Service.MoveParticipant(parts[1], c.Sender().ID, userName, participantID, gameID)
+	switch {
+	case errors.Is(err, api.ErrNotHost):
+		return t.alertCallback(c, "OnlyHostsCanManage")
+	case errors.Is(err, database.ErrNoRows):
+		return t.alertCallback(c, "ParticipantGone")
+	case errors.Is(err, api.ErrEventFinished):
+		return t.alertCallback(c, "EventFinished")
+	case err != nil:
+		log.Default().Println("failed to move participant:", err)
+		return t.alertCallback(c, "FailedToManageParticipant")
+	}
+
+	name := fmt.Sprint(participantID)
+	for _, p := range game.Participants {
+		if p.UserID == participantID {
+			name = p.UserName
+		}
+	}
+
+	moved := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{ID: "ParticipantMoved"},
+		TemplateData: map[string]string{
+			"User":  name,
+			"Game":  game.Name,
+			"Event": event.Name,
+		},
+	})
+	if _, err = t.Bot.Edit(c.Callback().Message, moved, telebot.ModeHTML); err != nil {
+		log.Default().Println("failed to edit the game selector:", err)
+	}
+
+	return c.Respond()
+}
+
+// Clone copies an event into a new one starting on another date: /clone
+// YYYY-MM-DD HH:MM in reply to the event message, or to one of its games, and
+// for the latest event of the chat without a reply. Add "participants" to
+// copy the players too.
+func (t Telegram) Clone(c telebot.Context) error {
+	chatID := c.Chat().ID
+	text := strings.Join(c.Args(), " ")
+	startsAt := parseDateTime(text, t.DB.GetDefaultTimezoneLocation(chatID))
+	if startsAt == nil {
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: "Usage",
+			},
+			TemplateData: map[string]string{
+				"Command": "/clone",
+				"Example": "YYYY-MM-DD HH:MM | YYYY-MM-DD HH:MM participants",
+			},
+		}))
+	}
+	withParticipants := strings.Contains(strings.ToLower(dateTimeRegex.ReplaceAllString(text, "")), "participants")
+
+	var err error
+	var event *models.Event
+	if reply := c.Message().ReplyTo; reply != nil {
+		var eventID string
+		if eventID, err = t.DB.SelectEventIDByMessageID(chatID, int64(reply.ID)); err == nil {
+			event, err = t.DB.SelectEventByEventID(eventID)
+		}
+	} else {
+		event, err = t.DB.SelectEvent(chatID)
+	}
+	if err != nil || event.ID == "" {
+		log.Default().Println("failed to load event:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventNotFound"}))
+	}
+
+	var threadID *int64
+	if c.Message().ThreadID != 0 {
+		threadID = utils.IntToPointer(c.Message().ThreadID)
+	}
+
+	userName, _ := DefineUsername(c.Sender())
+	if _, err = t.Service.CloneEvent(event.ID, threadID, c.Sender().ID, userName, startsAt, withParticipants); err != nil {
+		if errors.Is(err, api.ErrNotHost) {
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "OnlyHostsCanClone"}))
+		}
+
+		log.Default().Println("failed to clone event:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "FailedToCloneEvent"}))
+	}
+
+	return nil
+}
+
+// pickEventUser loads the latest event of the chat and the user a host
+// command is about: the sender of the replied message, or a user mentioned by
+// @username. It answers in the chat and reports false when either is missing.
+func (t Telegram) pickEventUser(c telebot.Context, args []string, command, example string) (*models.Event, eventUser, bool) {
+	reply := c.Message().ReplyTo
+	byReply := len(args) == 0 && reply != nil && reply.Sender != nil
+	if len(args) != 1 && !byReply {
+		_ = c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: "Usage",
+			},
+			TemplateData: map[string]string{
+				"Command": command,
+				"Example": example,
+			},
+		}))
+		return nil, eventUser{}, false
+	}
+
+	event, err := t.DB.SelectEvent(c.Chat().ID)
+	if err != nil || event.ID == "" {
+		log.Default().Println("failed to load event:", err)
+		_ = c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventNotFound"}))
+		return nil, eventUser{}, false
+	}
+
+	if byReply {
+		name, _ := DefineUsername(reply.Sender)
+		return event, eventUser{ID: reply.Sender.ID, Name: name}, true
+	}
+
+	user, found := findEventUser(event, args[0])
+	if !found {
+		_ = t.replyUserNotFound(c, args[0])
+		return nil, eventUser{}, false
+	}
+
+	return event, user, true
+}
+
+func (t Telegram) replyUserNotFound(c telebot.Context, user string) error {
+	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: "UserNotInEvent",
+		},
+		TemplateData: map[string]string{
+			"User": user,
+		},
+	}))
+}
+
+// findEventUser looks up a user mentioned by @username among the participants
+// and the co-hosts of the event. Users without a Telegram username cannot be
+// mentioned and must be picked by replying to one of their messages.
+func findEventUser(event *models.Event, mention string) (eventUser, bool) {
+	name := strings.TrimPrefix(mention, "@")
+	if name == mention || name == "" {
+		return eventUser{}, false
+	}
+
+	participants := append([]models.Participant{}, event.Tentative...)
+	participants = append(participants, event.Declined...)
+	for _, bg := range event.BoardGames {
+		participants = append(participants, bg.Participants...)
+	}
+
+	for _, p := range participants {
+		if p.IsTelegramUsername && strings.EqualFold(p.UserName, name) {
+			return eventUser{ID: p.UserID, Name: p.UserName}, true
+		}
+	}
+
+	for _, coHost := range event.CoHosts {
+		if strings.EqualFold(coHost.UserName, name) {
+			return eventUser{ID: coHost.UserID, Name: coHost.UserName}, true
+		}
+	}
+
+	return eventUser{}, false
+}
@@ -0,0 +1,160 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/models"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCoHostByMention(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 4)
+	if _, err := h.tg.DB.InsertParticipant(nil, eventID, gameID, 7, "anna", true); err != nil {
+		t.Fatal(err)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 7, "/cohost @anna"))
+	if text := h.calls[len(h.calls)-1].Params["text"]; text != "Only the creator of the event can choose its co-hosts." {
+		t.Errorf("unexpected reply %q", text)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 1, "/cohost @Anna"))
+	event, err := h.tg.DB.SelectEventByEventID(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(event.CoHosts) != 1 || event.CoHosts[0].UserID != 7 || event.CoHosts[0].UserName != "anna" {
+		t.Fatalf("expected anna to co-host, got %+v", event.CoHosts)
+	}
+	if text := h.calls[len(h.calls)-1].Params["text"]; text != "anna is now co-hosting <b>Game night</b> 🤝." {
+		t.Errorf("unexpected reply %q", text)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 1, "/cohost @nobody"))
+	if text := h.calls[len(h.calls)-1].Params["text"]; text != "@nobody is not taking part in the event. Reply to one of their messages instead." {
+		t.Errorf("unexpected reply %q", text)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 1, "/cohost remove @anna"))
+	if event, err = h.tg.DB.SelectEventByEventID(eventID); err != nil {
+		t.Fatal(err)
+	}
+	if len(event.CoHosts) != 0 {
+		t.Fatalf("expected no co-hosts, got %+v", event.CoHosts)
+	}
+}
+
+func TestKickAndMoveByHost(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, catanID := h.createEventWithGame(t, 4)
+	azulID, _, err := h.tg.DB.InsertBoardGame(eventID, nil, "Azul", "", 4, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []struct {
+		id   int64
+		name string
+	}{{7, "anna"}, {8, "mario"}} {
+		if _, err := h.tg.DB.InsertParticipant(nil, eventID, catanID, p.id, p.name, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 8, "/kick @anna"))
+	if text := h.calls[len(h.calls)-1].Params["text"]; text != "Only the hosts of the event can remove or move participants." {
+		t.Errorf("unexpected reply %q", text)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 1, "/move @anna"))
+	selector := h.calls[len(h.calls)-1]
+	data := fmt.Sprintf("%s|%s|7|%d", models.MoveTo, eventID, azulID)
+	if markup, _ := selector.Params["reply_markup"].(string); !strings.Contains(markup, data) || strings.Contains(markup, "Catan") {
+		t.Fatalf("expected a button to move anna to Azul, got %v", selector.Params)
+	}
+
+	h.post(testWebhookSecret, callbackUpdate(1, data))
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 1, "/kick @mario"))
+
+	event, err := h.tg.DB.SelectEventByEventID(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bg := range event.BoardGames {
+		names := []string{}
+		for _, p := range bg.Participants {
+			names = append(names, p.UserName)
+		}
+		want := map[string]string{"Catan": "", "Azul": "anna"}[bg.Name]
+		if strings.Join(names, ",") != want {
+			t.Errorf("%s participants = %v, want %q", bg.Name, names, want)
+		}
+	}
+
+	direct := []string{}
+	for _, call := range h.calls {
+		if call.Method == "sendMessage" && fmt.Sprint(call.Params["chat_id"]) != "-100" {
+			direct = append(direct, fmt.Sprint(call.Params["chat_id"]))
+		}
+	}
+	if strings.Join(direct, ",") != "7,8" {
+		t.Errorf("expected anna and mario to be told, got direct messages to %v", direct)
+	}
+}
+
+func TestCloneByReply(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, catanID := h.createEventWithGame(t, 4)
+	bggURL := "https://boardgamegeek.com/boardgame/13/catan"
+	if _, _, err := h.tg.DB.InsertBoardGame(eventID, nil, "Azul", "22:00", 2, nil, nil, &bggURL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.tg.DB.InsertParticipant(nil, eventID, catanID, 7, "anna", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.tg.DB.UpdateParticipantGuests(eventID, 7, catanID, []string{"Luca"}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 8, "/clone 2030-01-02 20:00"))
+	if text := h.calls[len(h.calls)-1].Params["text"]; text != "Only the hosts of the event can duplicate it." {
+		t.Errorf("unexpected reply %q", text)
+	}
+
+	h.post(testWebhookSecret, `{"update_id":6,"message":{"message_id":11,"date":0,
+		"from":{"id":1,"first_name":"host","language_code":"en"},
+		"chat":{"id":-100,"type":"group"},"text":"/clone 2030-01-02 20:00 participants",
+		"entities":[{"type":"bot_command","offset":0,"length":6}],
+		"reply_to_message":{"message_id":1,"date":0,"chat":{"id":-100,"type":"group"},"text":"Game night"}}}`)
+
+	events, err := h.tg.DB.SelectEventsByUserID(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the event to be copied, got %d events", len(events))
+	}
+	clone := events[0]
+	if clone.ID == eventID {
+		clone = events[1]
+	}
+
+	if clone.Name != "Game night" || clone.StartsAt == nil || clone.StartsAt.Format("2006-01-02 15:04") != "2030-01-02 20:00" || clone.MessageID == nil {
+		t.Fatalf("unexpected copy %+v", clone)
+	}
+	games := []string{}
+	for _, bg := range clone.BoardGames {
+		players := []string{}
+		for _, p := range bg.Participants {
+			players = append(players, fmt.Sprintf("%s%v", p.UserName, p.Guests))
+		}
+		url := ""
+		if bg.BggUrl != nil {
+			url = *bg.BggUrl
+		}
+		games = append(games, fmt.Sprintf("%s/%d/%s/%s%v", bg.Name, bg.MaxPlayers, bg.Slot, url, players))
+	}
+	if want := "[Catan/4//[anna[Luca]] Azul/2/22:00/" + bggURL + "[]]"; fmt.Sprint(games) != want {
+		t.Errorf("games = %v, want %s", games, want)
+	}
+}
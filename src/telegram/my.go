@@ -0,0 +1,202 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/models"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	maxMyEvents        = 10
+	maxButtonEventName = 24
+)
+
+// My shows, in a private chat, the upcoming events joined by the user in every
+// chat, with buttons to leave them, and the notification preferences.
+func (t Telegram) My(c telebot.Context) error {
+	if c.Chat().Type != telebot.ChatPrivate {
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "MyOnlyInPrivate"}}))
+	}
+
+	body, markup, err := t.myPanel(t.Localizer(c), c.Sender().ID)
+	if err != nil {
+		log.Default().Println("failed to load user events:", err)
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToLoadMyEvents"}}))
+	}
+
+	return c.Send(body, markup, telebot.NoPreview)
+}
+
+func (t Telegram) myPanel(localizer *i18n.Localizer, userID int64) (string, *telebot.ReplyMarkup, error) {
+	events, err := t.DB.SelectEventsByParticipant(userID, 50)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	upcoming := []models.Event{}
+	for _, event := range events {
+		if !event.EndedBefore(now) {
+			upcoming = append(upcoming, event)
+		}
+	}
+	// dated events first, soonest on top; undated ones keep the newest first
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		a, b := upcoming[i].StartsAt, upcoming[j].StartsAt
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Before(*b)
+	})
+	if len(upcoming) > maxMyEvents {
+		upcoming = upcoming[:maxMyEvents]
+	}
+
+	msg := localizer.MustLocalizeMessage(&i18n.Message{ID: "MyEventsTitle"}) + "\n\n"
+	if len(upcoming) == 0 {
+		msg += localizer.MustLocalizeMessage(&i18n.Message{ID: "MyNoEvents"}) + "\n\n"
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	for _, event := range upcoming {
+		msg += "📆 <b>" + event.Title() + "</b>\n"
+		if event.StartsAt != nil {
+			msg += "⏰ " + event.StartsAt.Format("2006-01-02 15:04") + "\n"
+		}
+		if event.Location != nil && *event.Location != "" {
+			msg += "📍 " + *event.Location + "\n"
+		}
+		for _, bg := range event.BoardGames {
+			position, ok := bg.WaitlistPosition(userID)
+			if !ok {
+				continue
+			}
+
+			name := bg.Name
+			if name == models.PLAYER_COUNTER {
+				name = localizer.MustLocalizeMessage(&i18n.Message{ID: "JoinEvent"})
+			}
+			if position > 0 {
+				name += " ⏳ #" + strconv.Itoa(position)
+			}
+			msg += "🎲 " + name + "\n"
+		}
+		for _, p := range event.Tentative {
+			if p.UserID == userID {
+				msg += localizer.MustLocalizeMessage(&i18n.Message{ID: "MaybeTitle"}) + "\n"
+			}
+		}
+		msg += "\n"
+
+		eventName := []rune(event.Name)
+		if len(eventName) > maxButtonEventName {
+			eventName = append(eventName[:maxButtonEventName-1], '…')
+		}
+		row := []telebot.InlineButton{
+			{
+				Text: localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{ID: "MyLeave"},
+					TemplateData:   map[string]string{"Name": string(eventName)},
+				}),
+				Unique: string(models.MyLeave),
+				Data:   event.ID,
+			},
+			{
+				Text: localizer.MustLocalizeMessage(&i18n.Message{ID: "MyOpen"}),
+				URL:  fmt.Sprintf("%s?startapp=%s", t.Url.BotMiniAppURL, event.ID),
+			},
+		}
+		if event.StartsAt != nil {
+			row = append(row, telebot.InlineButton{
+				Text: localizer.MustLocalizeMessage(&i18n.Message{ID: "MyCalendar"}),
+				URL:  fmt.Sprintf("%s/events/%s?format=ics", strings.TrimSuffix(t.Url.BaseUrl, "/"), event.ID),
+			})
+		}
+		markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+	}
+
+	msg += localizer.MustLocalizeMessage(&i18n.Message{ID: "MyNotifications"})
+
+	preferences := t.DB.GetNotificationPreferences(userID)
+	for _, p := range []struct {
+		kind      string
+		messageID string
+		enabled   bool
+	}{
+		{"waitlist", "MyNotifyWaitlist", preferences.Waitlist},
+		{"cancellations", "MyNotifyCancellations", preferences.Cancellations},
+		{"reminders", "MyNotifyReminders", preferences.Reminders},
+	} {
+		state := "❌"
+		if p.enabled {
+			state = "✅"
+		}
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []telebot.InlineButton{{
+			Text: localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{ID: p.messageID},
+				TemplateData:   map[string]string{"State": state},
+			}),
+			Unique: string(models.MyPreference),
+			Data:   p.kind,
+		}})
+	}
+
+	return msg, markup, nil
+}
+
+// refreshMyPanel renders again the /my panel the user clicked on.
+func (t Telegram) refreshMyPanel(c telebot.Context) {
+	body, markup, err := t.myPanel(t.Localizer(c), c.Sender().ID)
+	if err != nil {
+		log.Default().Println("failed to load user events:", err)
+		return
+	}
+
+	if err = c.Edit(body, markup, telebot.NoPreview); err != nil && !strings.Contains(err.Error(), models.MessageUnchangedErrorMessage) {
+		log.Default().Println("failed to edit my panel:", err)
+	}
+}
+
+func (t Telegram) CallbackMyLeave(c telebot.Context) error {
+	err := t.CallbackNotComing(c)
+	t.refreshMyPanel(c)
+	return err
+}
+
+func (t Telegram) CallbackMyPreference(c telebot.Context) error {
+	parts := strings.Split(c.Callback().Data, "|")
+	if len(parts) != 2 {
+		log.Default().Println("Invalid data:", c.Callback().Data)
+		return t.alertCallback(c, "InvalidData")
+	}
+
+	userID := c.Sender().ID
+	preferences := t.DB.GetNotificationPreferences(userID)
+	switch parts[1] {
+	case "waitlist":
+		preferences.Waitlist = !preferences.Waitlist
+	case "cancellations":
+		preferences.Cancellations = !preferences.Cancellations
+	case "reminders":
+		preferences.Reminders = !preferences.Reminders
+	default:
+		log.Default().Println("Invalid notification preference:", parts[1])
+		return t.alertCallback(c, "InvalidData")
+	}
+
+	log.Default().Printf("User %d changed notification preferences to %+v", userID, preferences)
+	if err := t.DB.SetNotificationPreferences(userID, preferences); err != nil {
+		log.Default().Println("failed to set notification preferences:", err)
+		return t.alertCallback(c, "FailedToSetPreferences")
+	}
+
+	t.refreshMyPanel(c)
+	return t.toastCallback(c, "PreferencesUpdated", nil)
+}
@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/models"
+	"strings"
+	"testing"
+)
+
+func TestMyListsJoinedEvents(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 4)
+	if _, err := h.tg.DB.InsertParticipant(nil, eventID, gameID, 42, "Ada", false); err != nil {
+		t.Fatal(err)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(42, "private", 42, "/my"))
+
+	if len(h.calls) != 1 || h.calls[0].Method != "sendMessage" {
+		t.Fatalf("api calls = %v, want [sendMessage]", h.methods())
+	}
+	text, _ := h.calls[0].Params["text"].(string)
+	if !strings.Contains(text, "Game night") || !strings.Contains(text, "Catan") {
+		t.Errorf("expected the joined event in %q", text)
+	}
+	markup, _ := h.calls[0].Params["reply_markup"].(string)
+	for _, want := range []string{string(models.MyLeave) + "|" + eventID, string(models.MyPreference) + "|waitlist", "startapp=" + eventID} {
+		if !strings.Contains(markup, want) {
+			t.Errorf("expected %q in the keyboard %s", want, markup)
+		}
+	}
+}
+
+func TestMyOnlyInPrivateChat(t *testing.T) {
+	h := newWebhookHarness(t)
+
+	h.post(testWebhookSecret, commandUpdate(42, "group", 42, "/my"))
+
+	if len(h.calls) != 1 {
+		t.Fatalf("api calls = %v, want one", h.methods())
+	}
+	if text := h.calls[0].Params["text"]; text != "Send /my to me in a private chat to see your upcoming events." {
+		t.Errorf("unexpected reply %q", text)
+	}
+}
+
+func TestCallbackMyPreferenceToggles(t *testing.T) {
+	h := newWebhookHarness(t)
+
+	h.post(testWebhookSecret, callbackUpdate(42, string(models.MyPreference)+"|waitlist"))
+
+	if answer := h.lastCallbackAnswer(t); answer.Params["text"] != "Notification preferences updated 🔔" {
+		t.Errorf("unexpected answer %v", answer.Params)
+	}
+	preferences := h.tg.DB.GetNotificationPreferences(42)
+	if preferences.Waitlist || !preferences.Cancellations {
+		t.Errorf("expected only waitlist notifications to be disabled, got %+v", preferences)
+	}
+}
@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/web/api"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"gopkg.in/telebot.v3"
+)
+
+// confirmArg is the argument that confirms a command erasing data.
+const confirmArg = "confirm"
+
+// ForgetMe erases the data of the sender in every chat: /forget_me explains
+// what is erased, /forget_me confirm erases it.
+func (t Telegram) ForgetMe(c telebot.Context) error {
+	if args := c.Args(); len(args) != 1 || args[0] != confirmArg {
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "ForgetMeConfirm"}))
+	}
+
+	if err := t.Service.ForgetUser(c.Sender().ID); err != nil {
+		log.Default().Println("failed to forget user:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "FailedToForgetMe"}))
+	}
+
+	return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "ForgetMeDone"}))
+}
+
+// Purge lets a chat admin erase every event, webhook and setting of the chat:
+// /purge explains what is erased, /purge confirm erases it.
+func (t Telegram) Purge(c telebot.Context) error {
+	if args := c.Args(); len(args) != 1 || args[0] != confirmArg {
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "PurgeConfirm"}))
+	}
+
+	// the localizer needs the language of the chat, which is erased too
+	localizer := t.Localizer(c)
+	deleted, err := t.Service.PurgeChat(c.Chat().ID, c.Sender().ID)
+	if err != nil {
+		if errors.Is(err, api.ErrNotChatAdmin) {
+			return c.Reply(localizer.MustLocalizeMessage(&i18n.Message{ID: "OnlyAdminsCanPurge"}))
+		}
+
+		log.Default().Println("failed to purge chat:", err)
+		return c.Reply(localizer.MustLocalizeMessage(&i18n.Message{ID: "FailedToPurge"}))
+	}
+
+	return c.Reply(localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: "ChatPurged",
+		},
+		TemplateData: map[string]string{
+			"Count": strconv.FormatInt(deleted, 10),
+		},
+	}))
+}
@@ -0,0 +1,170 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/models"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (h *webhookHarness) lastReply(t *testing.T) string {
+	t.Helper()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	text, _ := h.calls[len(h.calls)-1].Params["text"].(string)
+	return text
+}
+
+func TestForgetMe(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, gameID := h.createEventWithGame(t, 4)
+	db := h.tg.DB
+	if _, err := db.InsertParticipant(nil, eventID, gameID, 7, "anna", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateParticipantGuests(eventID, 7, gameID, []string{"Luca"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddEventCoHost(eventID, 7, "anna"); err != nil {
+		t.Fatal(err)
+	}
+	ownEventID, err := db.InsertEventWithOptionalGame(nil, -200, 7, "anna", "Anna's night", nil, nil, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.SetNotificationPreferences(7, models.NotificationPreferences{}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 7, "/forget_me"))
+	if reply := h.lastReply(t); !strings.Contains(reply, "/forget_me confirm") {
+		t.Errorf("expected to be asked for a confirmation, got %q", reply)
+	}
+	if event, _ := db.SelectEventByEventID(eventID); len(event.BoardGames[0].Participants) != 1 {
+		t.Fatal("expected nothing to be erased without confirmation")
+	}
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 7, "/forget_me confirm"))
+	if reply := h.lastReply(t); reply != "Your data has been erased 🧹" {
+		t.Errorf("unexpected reply %q", reply)
+	}
+
+	event, err := db.SelectEventByEventID(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(event.BoardGames[0].Participants) != 0 || len(event.CoHosts) != 0 {
+		t.Errorf("expected the user to be removed, got %+v and co-hosts %v", event.BoardGames[0].Participants, event.CoHosts)
+	}
+	own, err := db.SelectEventByEventID(ownEventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if own.ID == "" || own.UserID != 0 || own.UserName != models.AnonymousUserName {
+		t.Errorf("expected the event of the user to be kept without their name, got %+v", own)
+	}
+	if preferences := db.GetNotificationPreferences(7); preferences != models.DefaultNotificationPreferences() {
+		t.Errorf("expected the preferences to be reset, got %+v", preferences)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	h := newWebhookHarness(t)
+	eventID, _ := h.createEventWithGame(t, 4)
+	db := h.tg.DB
+
+	h.post(testWebhookSecret, commandUpdate(-100, "group", 1, "/purge confirm"))
+	if reply := h.lastReply(t); reply != "Only chat administrators can erase the data of the chat." {
+		t.Errorf("unexpected reply %q", reply)
+	}
+	if event, _ := db.SelectEventByEventID(eventID); event.ID == "" {
+		t.Fatal("expected the event to be kept")
+	}
+
+	privateEventID, err := db.InsertEventWithOptionalGame(nil, 42, 42, "ada", "Solo night", nil, nil, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	language := "it"
+	if err = db.InsertChat(42, &language, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(42, "private", 42, "/purge"))
+	if reply := h.lastReply(t); !strings.Contains(reply, "/purge confirm") {
+		t.Errorf("expected to be asked for a confirmation, got %q", reply)
+	}
+
+	h.post(testWebhookSecret, commandUpdate(42, "private", 42, "/purge confirm"))
+	if reply := h.lastReply(t); reply != "I dati di questa chat sono stati cancellati 🧹 1 eventi eliminati." {
+		t.Errorf("unexpected reply %q", reply)
+	}
+	if event, _ := db.SelectEventByEventID(privateEventID); event.ID != "" {
+		t.Error("expected the event of the chat to be deleted")
+	}
+	if db.GetPreferredLanguage(42) != "en" {
+		t.Error("expected the settings of the chat to be deleted")
+	}
+	if event, _ := db.SelectEventByEventID(eventID); event.ID == "" {
+		t.Error("expected the events of the other chats to be kept")
+	}
+}
+
+func TestRetentionAnonymizesAndDeletesOldEvents(t *testing.T) {
+	h := newWebhookHarness(t)
+	db := h.tg.DB
+	now := time.Now()
+
+	newEvent := func(name string, startsAt time.Time) (string, int64) {
+		eventID, err := db.InsertEventWithOptionalGame(nil, -100, 1, "host", name, nil, &startsAt, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gameID, _, err := db.InsertBoardGame(eventID, nil, "Catan", "", 4, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, userID := range []int64{7, 8} {
+			if _, err = db.InsertParticipant(nil, eventID, gameID, userID, fmt.Sprint("user", userID), true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = db.UpdateParticipantGuests(eventID, 7, gameID, []string{"Luca"}); err != nil {
+			t.Fatal(err)
+		}
+		return eventID, gameID
+	}
+	recentID, _ := newEvent("Recent", now.AddDate(0, -1, 0))
+	oldID, _ := newEvent("Old", now.AddDate(0, -7, 0))
+	ancientID, _ := newEvent("Ancient", now.AddDate(0, -25, 0))
+
+	h.tg.Service.Retention = models.RetentionPolicy{AnonymizeAfterMonths: 6, DeleteAfterMonths: 24}
+	h.tg.Service.ApplyRetention(now)
+
+	recent, _ := db.SelectEventByEventID(recentID)
+	if recent.UserName != "host" || recent.BoardGames[0].Participants[0].UserName != "user7" {
+		t.Errorf("expected the recent event to be kept as is, got %+v", recent)
+	}
+
+	old, _ := db.SelectEventByEventID(oldID)
+	if old.ID == "" || old.UserName != models.AnonymousUserName {
+		t.Fatalf("expected the old event to be anonymized, got %+v", old)
+	}
+	participants := old.BoardGames[0].Participants
+	if len(participants) != 2 {
+		t.Fatalf("expected the seats to be kept, got %+v", participants)
+	}
+	for _, p := range participants {
+		if p.UserName != models.AnonymousUserName || p.UserID >= 0 || p.IsTelegramUsername {
+			t.Errorf("expected an anonymous participant, got %+v", p)
+		}
+	}
+	if guests := participants[0].Guests; len(guests) != 1 || guests[0] != "" {
+		t.Errorf("expected the guest names to be erased, got %v", guests)
+	}
+
+	if ancient, _ := db.SelectEventByEventID(ancientID); ancient.ID != "" {
+		t.Errorf("expected the ancient event to be deleted, got %+v", ancient)
+	}
+}
@@ -10,9 +10,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"log"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -22,6 +24,9 @@ import (
 	"gopkg.in/telebot.v3"
 )
 
+// maxInlineResults is the number of results returned to an inline query.
+const maxInlineResults = 10
+
 var dateTimeRegex = regexp.MustCompile(`\d{2}-\d{2}-\d{4} \d{2}:\d{2}|\d{4}-\d{2}-\d{2} \d{2}:\d{2}`)
 var locationRegex = regexp.MustCompile(`📍([^\n]+?)(?:\n|$)`)
 
@@ -40,21 +45,7 @@ func parseCreateCommand(args []string, fullText string, tz *time.Location) (name
 	name = strings.ReplaceAll(name, "👥", "")
 	name = strings.TrimSpace(name)
 
-	if dateTimeStr := dateTimeRegex.FindString(fullText); dateTimeStr != "" {
-		var parsed time.Time
-		var parseErr error
-		for _, layout := range []string{"02-01-2006 15:04", "2006-01-02 15:04"} {
-			parsed, parseErr = time.ParseInLocation(layout, dateTimeStr, tz)
-			if parseErr == nil {
-				break
-			}
-		}
-		if parseErr == nil {
-			startsAt = &parsed
-		} else {
-			log.Default().Println("failed to parse date time:", parseErr)
-		}
-	}
+	startsAt = parseDateTime(fullText, tz)
 
 	if m := locationRegex.FindStringSubmatch(fullText); len(m) > 1 {
 		loc := strings.TrimSpace(m[1])
@@ -65,6 +56,34 @@ func parseCreateCommand(args []string, fullText string, tz *time.Location) (name
 	return
 }
 
+// parseDateTime reads the first date time written in text, formatted as
+// YYYY-MM-DD HH:MM or DD-MM-YYYY HH:MM, in the tz timezone.
+func parseDateTime(text string, tz *time.Location) *time.Time {
+	dateTimeStr := dateTimeRegex.FindString(text)
+	if dateTimeStr == "" {
+		return nil
+	}
+
+	var parsed time.Time
+	var parseErr error
+	for _, layout := range []string{"02-01-2006 15:04", "2006-01-02 15:04"} {
+		parsed, parseErr = time.ParseInLocation(layout, dateTimeStr, tz)
+		if parseErr == nil {
+			return &parsed
+		}
+	}
+
+	log.Default().Println("failed to parse date time:", parseErr)
+	return nil
+}
+
+// parseAddGameCommand splits the /add_game arguments into the game name and
+// its optional time slot, written after 🕒: /add_game Catan 🕒 21:00.
+func parseAddGameCommand(args []string) (name, slot string) {
+	name, slot, _ = strings.Cut(strings.Join(args, " "), "🕒")
+	return strings.TrimSpace(name), strings.TrimSpace(slot)
+}
+
 type Telegram struct {
 	Bot            *telebot.Bot
 	DB             *database.Database
@@ -76,15 +95,10 @@ type Telegram struct {
 }
 
 func (t Telegram) SetupHandlers() {
-	t.Bot.Handle("/start", t.Start)
-	t.Bot.Handle("/help", t.Start)
-	t.Bot.Handle("/create", t.CreateGame)
-	t.Bot.Handle("/add_game", t.AddGame)
-	t.Bot.Handle("/language", t.SetLanguage)
-	t.Bot.Handle("/location", t.SetDefaultLocation)
-	t.Bot.Handle("/timezone", t.SetDefaultTimezone)
-	t.Bot.Handle("/register", t.RegisterWebhook)
-	t.Bot.Handle("/test", t.TestWebhook)
+	for _, cmd := range t.commands() {
+		t.Bot.Handle("/"+cmd.Name, cmd.Handler)
+	}
+	t.Bot.Handle(telebot.OnQuery, t.InlineQuery)
 
 	t.Bot.Handle(telebot.OnText, func(c telebot.Context) error {
 		if c.Message().ReplyTo == nil {
@@ -105,12 +119,22 @@ func (t Telegram) SetupHandlers() {
 		case string(models.AddPlayer):
 			return t.CallbackAddPlayer(c)
 		case string(models.Cancel):
-			return t.CallbackRemovePlayer(c)
+			return t.CallbackNotComing(c)
+		case string(models.Maybe):
+			return t.CallbackMaybe(c)
 		case string(models.Unregister):
 			return t.CallbackUnregisterWebhook(c)
+		case string(models.AddGuest):
+			return t.CallbackAddGuest(c)
+		case string(models.MyLeave):
+			return t.CallbackMyLeave(c)
+		case string(models.MyPreference):
+			return t.CallbackMyPreference(c)
+		case string(models.MoveTo):
+			return t.CallbackMoveTo(c)
 		}
 
-		return c.Reply("invalid action")
+		return t.alertCallback(c, "InvalidData")
 	})
 }
 
@@ -128,6 +152,13 @@ func DefineUsername(user *telebot.User) (string, bool) {
 }
 
 func (t Telegram) Localizer(c telebot.Context) *i18n.Localizer {
+	if c.Chat() == nil {
+		// inline queries and buttons of inline messages carry no chat
+		if c.Sender() != nil {
+			return i18n.NewLocalizer(t.LanguageBundle, c.Sender().LanguageCode, "en")
+		}
+		return i18n.NewLocalizer(t.LanguageBundle, "en")
+	}
 	return i18n.NewLocalizer(t.LanguageBundle, t.DB.GetPreferredLanguage(c.Chat().ID), "en")
 }
 
@@ -218,11 +249,13 @@ func (t Telegram) CreateGame(c telebot.Context) error {
 	log.Default().Println("Full text for parsing:", fullText)
 	tzLocation := t.DB.GetDefaultTimezoneLocation(chatID)
 	eventName, location, startsAt, allowGeneralJoin := parseCreateCommand(args, fullText, tzLocation)
+	locked := strings.Contains(eventName, "🔒")
+	eventName = strings.TrimSpace(strings.ReplaceAll(eventName, "🔒", ""))
 
 	log.Default().Printf("Creating event: %s by user: %s (%d) in chat: %d", eventName, userName, userID, chatID)
 
 	var event *models.Event
-	if event, err = t.Service.CreateEvent(chatID, threadID, nil, userID, userName, eventName, location, startsAt, allowGeneralJoin); err != nil {
+	if event, err = t.Service.CreateEvent(chatID, threadID, nil, userID, userName, eventName, location, startsAt, locked, allowGeneralJoin); err != nil {
 		log.Default().Println("failed to create event:", err)
 		failedT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToCreateEvent"}})
 		return c.Reply(failedT)
@@ -241,6 +274,7 @@ func (t Telegram) CreateGame(c telebot.Context) error {
 			MessageID: event.MessageID,
 			Location:  event.Location,
 			StartsAt:  event.StartsAt,
+			Locked:    event.Locked,
 			CreatedAt: time.Now(),
 		},
 	})
@@ -278,8 +312,8 @@ func (t Telegram) AddGame(c telebot.Context) error {
 	var err error
 	log.Default().Println("user requested to add a game")
 
-	args := c.Args()
-	if len(args) < 1 {
+	gameName, slot := parseAddGameCommand(c.Args())
+	if gameName == "" {
 		gameNameT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "GameName"}})
 		usageT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
 			DefaultMessage: &i18n.Message{
@@ -296,7 +330,6 @@ func (t Telegram) AddGame(c telebot.Context) error {
 	chatID := c.Chat().ID
 	userID := c.Sender().ID
 	userName, _ := DefineUsername(c.Sender())
-	gameName := strings.Join(args[0:], " ")
 	log.Default().Printf("Adding game: %s in chat id %d", gameName, chatID)
 
 	var event *models.Event
@@ -307,14 +340,20 @@ func (t Telegram) AddGame(c telebot.Context) error {
 		return c.Reply(failedT)
 	}
 
-	if event.Locked && event.UserID != userID {
+	if event.Locked && !t.Service.IsHost(event, userID) {
 		log.Default().Println("event is locked")
 		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "EventLocked"}}))
 	}
 
 	var game *models.BoardGame
-	if event, game, err = t.Service.CreateGame(event.ID, nil, userID, gameName, nil, nil); err != nil {
+	if event, game, err = t.Service.CreateGame(event.ID, nil, userID, gameName, nil, nil, slot); err != nil {
 		log.Default().Println("failed to add game:", err)
+		if errors.Is(err, api.ErrRSVPClosed) {
+			return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "RSVPClosed"}}))
+		}
+		if errors.Is(err, api.ErrEventFinished) {
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventFinished"}))
+		}
 		failedT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToAddGame"}})
 		return c.Reply(failedT)
 	}
@@ -335,11 +374,17 @@ func (t Telegram) AddGame(c telebot.Context) error {
 		},
 	})
 
-	responseMsg, err := t.Bot.Reply(
-		c.Message(),
-		message,
-		telebot.NoPreview,
-	)
+	var responseMsg *telebot.Message
+	if event.TopicID != nil && int64(c.Message().ThreadID) != *event.TopicID {
+		// keep the game messages together with the event in its topic
+		responseMsg, err = t.Bot.Send(c.Chat(), message, &telebot.SendOptions{ThreadID: int(*event.TopicID)}, telebot.NoPreview)
+	} else {
+		responseMsg, err = t.Bot.Reply(
+			c.Message(),
+			message,
+			telebot.NoPreview,
+		)
+	}
 	if err != nil {
 		log.Default().Println("failed to dispatch add game message:", err)
 		failedT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToAddGame"}})
@@ -361,6 +406,7 @@ func (t Telegram) AddGame(c telebot.Context) error {
 			UserName:   userName,
 			Name:       gameName,
 			MaxPlayers: int(game.MaxPlayers),
+			Slot:       game.Slot,
 			MessageID:  utils.IntToPointer(responseMsg.ID),
 			BGG: models.HookBGGInfo{
 				IsSet:    game.BggID != nil,
@@ -439,29 +485,16 @@ func (t Telegram) UpdateGameNumberOfPlayer(c telebot.Context) error {
 		if errors.Is(err, errors.New("invalid bgg url")) {
 			return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidBggURL"}}))
 		}
+		if errors.Is(err, api.ErrEventFinished) {
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventFinished"}))
+		}
 		log.Default().Println("failed to update game:", err)
 		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToUpdateGame"}}))
 	}
 
 	t.Hook.SendAllWebhookAsync(context.Background(), event.ChatID, models.HookWebhookEnvelope{
 		Type: models.HookWebhookTypeUpdateGame,
-		Data: models.HookUpdateGamePayload{
-			ID:         game.UUID,
-			EventID:    event.ID,
-			UserID:     userID,
-			UserName:   userName,
-			Name:       game.Name,
-			MaxPlayers: int(maxPlayers),
-			MessageID:  utils.IntToPointer(messageID),
-			BGG: models.HookBGGInfo{
-				IsSet:    game.BggID != nil,
-				ID:       game.BggID,
-				Name:     game.BggName,
-				URL:      game.BggUrl,
-				ImageURL: game.BggImageUrl,
-			},
-			UpdatedAt: time.Now(),
-		},
+		Data: models.NewHookUpdateGamePayload(event.ID, *game, userID, userName),
 	})
 
 	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "GameUpdated"}}))
@@ -505,29 +538,16 @@ func (t Telegram) UpdateGameBGGInfo(c telebot.Context) error {
 		if errors.Is(err, errors.New("invalid bgg url")) {
 			return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidBggURL"}}))
 		}
+		if errors.Is(err, api.ErrEventFinished) {
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventFinished"}))
+		}
 		log.Default().Println("failed to update game:", err)
 		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToUpdateGame"}}))
 	}
 
 	t.Hook.SendAllWebhookAsync(context.Background(), event.ChatID, models.HookWebhookEnvelope{
 		Type: models.HookWebhookTypeUpdateGame,
-		Data: models.HookUpdateGamePayload{
-			ID:         game.UUID,
-			EventID:    event.ID,
-			UserID:     userID,
-			UserName:   userName,
-			Name:       game.Name,
-			MaxPlayers: int(game.MaxPlayers),
-			MessageID:  nil,
-			BGG: models.HookBGGInfo{
-				IsSet:    game.BggID != nil,
-				ID:       game.BggID,
-				Name:     game.BggName,
-				URL:      game.BggUrl,
-				ImageURL: game.BggImageUrl,
-			},
-			UpdatedAt: time.Now(),
-		},
+		Data: models.NewHookUpdateGamePayload(event.ID, *game, userID, userName),
 	})
 
 	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "GameUpdated"}}))
@@ -570,6 +590,8 @@ func (t Telegram) SetLanguage(c telebot.Context) error {
 		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToSetLanguage"}}))
 	}
 
+	t.notifyChatSettings(c, models.HookChatSettingsPayload{Language: &language})
+
 	messageT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
 		DefaultMessage: &i18n.Message{
 			ID: "LanguageSet",
@@ -606,6 +628,8 @@ func (t Telegram) SetDefaultLocation(c telebot.Context) error {
 		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToSetLocation"}}))
 	}
 
+	t.notifyChatSettings(c, models.HookChatSettingsPayload{Location: &location})
+
 	messageT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
 		DefaultMessage: &i18n.Message{
 			ID: "LocationSet",
@@ -646,6 +670,8 @@ func (t Telegram) SetDefaultTimezone(c telebot.Context) error {
 		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToSetTimezone"}}))
 	}
 
+	t.notifyChatSettings(c, models.HookChatSettingsPayload{Timezone: &timezone})
+
 	messageT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
 		DefaultMessage: &i18n.Message{
 			ID: "TimezoneSet",
@@ -658,6 +684,198 @@ func (t Telegram) SetDefaultTimezone(c telebot.Context) error {
 	return c.Reply(messageT)
 }
 
+// SetAutoPin turns the automatic pinning of new events on or off for the
+// chat. Only the chat admins can change it.
+func (t Telegram) SetAutoPin(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		usageT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: "Usage",
+			},
+			TemplateData: map[string]string{
+				"Command": "/autopin",
+				"Example": "on|off",
+			},
+		})
+		return c.Reply(usageT)
+	}
+
+	chatID := c.Chat().ID
+	enabled := args[0] == "on"
+	log.Default().Printf("Setting auto pin to %t in chat %d", enabled, chatID)
+
+	if err := t.Service.SetAutoPin(chatID, c.Sender().ID, enabled); err != nil {
+		if errors.Is(err, api.ErrNotChatAdmin) {
+			return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "OnlyAdminsCanSetAutoPin"}}))
+		}
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToSetAutoPin"}}))
+	}
+
+	t.notifyChatSettings(c, models.HookChatSettingsPayload{AutoPin: &enabled})
+
+	if enabled {
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "AutoPinEnabled"}}))
+	}
+	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "AutoPinDisabled"}}))
+}
+
+func (t Telegram) SetEventTopics(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		usageT := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: "Usage",
+			},
+			TemplateData: map[string]string{
+				"Command": "/topics",
+				"Example": "on|off",
+			},
+		})
+		return c.Reply(usageT)
+	}
+
+	chatID := c.Chat().ID
+	enabled := args[0] == "on"
+	log.Default().Printf("Setting event topics to %t in chat %d", enabled, chatID)
+
+	if err := t.DB.SetEventTopics(chatID, enabled); err != nil {
+		log.Default().Println("failed to set event topics:", err)
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToSetEventTopics"}}))
+	}
+
+	t.notifyChatSettings(c, models.HookChatSettingsPayload{EventTopics: &enabled})
+
+	if enabled {
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "EventTopicsEnabled"}}))
+	}
+	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "EventTopicsDisabled"}}))
+}
+
+// notifyChatSettings dispatches the changed chat settings to the webhooks.
+func (t Telegram) notifyChatSettings(c telebot.Context, payload models.HookChatSettingsPayload) {
+	payload.ChatID = c.Chat().ID
+	payload.UserID = c.Sender().ID
+	payload.UserName, _ = DefineUsername(c.Sender())
+	payload.UpdatedAt = time.Now()
+
+	t.Hook.SendAllWebhookAsync(context.Background(), payload.ChatID, models.HookWebhookEnvelope{
+		Type: models.HookWebhookTypeUpdateChat,
+		Data: payload,
+	})
+}
+
+func (t Telegram) LockEvent(c telebot.Context) error {
+	return t.setEventLocked(c, true)
+}
+
+func (t Telegram) UnlockEvent(c telebot.Context) error {
+	return t.setEventLocked(c, false)
+}
+
+// setEventLocked locks or unlocks the latest event of the chat.
+func (t Telegram) setEventLocked(c telebot.Context, locked bool) error {
+	var err error
+	chatID := c.Chat().ID
+	userID := c.Sender().ID
+	userName, _ := DefineUsername(c.Sender())
+
+	var event *models.Event
+	if event, err = t.DB.SelectEvent(chatID); err != nil || event.ID == "" {
+		log.Default().Println("failed to load event:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventNotFound"}))
+	}
+
+	if event, err = t.Service.SetEventLocked(event.ID, userID, userName, locked); err != nil {
+		if errors.Is(err, api.ErrNotEventOwner) {
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "OnlyOwnerCanLockEvent"}))
+		}
+		if errors.Is(err, api.ErrEventFinished) {
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventFinished"}))
+		}
+
+		log.Default().Println("failed to change event lock:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "FailedToLockEvent"}))
+	}
+
+	messageID := "EventUnlockedSet"
+	if locked {
+		messageID = "EventLockedSet"
+	}
+
+	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: messageID,
+		},
+		TemplateData: map[string]string{
+			"Event": event.Name,
+		},
+	}))
+}
+
+// SetRSVPDeadline sets or removes the RSVP deadline of the latest event of the
+// chat, read in the default timezone of the chat.
+func (t Telegram) SetRSVPDeadline(c telebot.Context) error {
+	var err error
+	chatID := c.Chat().ID
+	userID := c.Sender().ID
+
+	var deadline *time.Time
+	args := strings.Join(c.Args(), " ")
+	if args != "off" {
+		if deadline = parseDateTime(args, t.DB.GetDefaultTimezoneLocation(chatID)); deadline == nil {
+			return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID: "Usage",
+				},
+				TemplateData: map[string]string{
+					"Command": "/deadline",
+					"Example": "2023-12-30 18:00 | off",
+				},
+			}))
+		}
+	}
+
+	var event *models.Event
+	if event, err = t.DB.SelectEvent(chatID); err != nil || event.ID == "" {
+		log.Default().Println("failed to load event:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventNotFound"}))
+	}
+
+	if event, err = t.Service.SetRSVPDeadline(event.ID, userID, deadline); err != nil {
+		if errors.Is(err, api.ErrNotEventOwner) {
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "OnlyOwnerCanSetDeadline"}))
+		}
+		if errors.Is(err, api.ErrEventFinished) {
+			return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "EventFinished"}))
+		}
+
+		log.Default().Println("failed to set rsvp deadline:", err)
+		return c.Reply(t.Localizer(c).MustLocalizeMessage(&i18n.Message{ID: "FailedToSetDeadline"}))
+	}
+
+	if deadline == nil {
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID: "DeadlineRemoved",
+			},
+			TemplateData: map[string]string{
+				"Event": event.Name,
+			},
+		}))
+	}
+
+	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: "DeadlineSet",
+		},
+		TemplateData: map[string]string{
+			"Event": event.Name,
+			"Time":  deadline.Format("2006-01-02 15:04"),
+		},
+	}))
+}
+
 func (t Telegram) RegisterWebhook(c telebot.Context) error {
 	args := c.Args()
 	if len(args) < 1 {
@@ -667,7 +885,7 @@ func (t Telegram) RegisterWebhook(c telebot.Context) error {
 			},
 			TemplateData: map[string]string{
 				"Command": "/register",
-				"Example": "https://example.com/webhook",
+				"Example": "https://example.com/webhook [bgnb|cloudevents|cloudevents-binary]",
 			},
 		})
 		return c.Reply(usageT)
@@ -719,6 +937,17 @@ func (t Telegram) RegisterWebhook(c telebot.Context) error {
 		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidWebhookURL"}}))
 	}
 
+	formatName := ""
+	if len(args) > 1 {
+		formatName = args[1]
+	}
+
+	format, valid := models.ParseWebhookFormat(formatName)
+	if !valid {
+		log.Default().Println("invalid webhook format:", formatName)
+		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidWebhookFormat"}}))
+	}
+
 	testMessage := t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
 		DefaultMessage: &i18n.Message{
 			ID: "WebhookTestSendPrivateMessage",
@@ -747,7 +976,7 @@ func (t Telegram) RegisterWebhook(c telebot.Context) error {
 
 	var webhookID *int64
 	var webhookUUID *string
-	if webhookID, webhookUUID, err = t.DB.InsertWebhook(chatID, threadID, webhookUrl, secret); err != nil {
+	if webhookID, webhookUUID, err = t.DB.InsertWebhook(chatID, threadID, webhookUrl, secret, format); err != nil {
 		log.Default().Println("failed to register webhook:", err)
 		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToRegisterWebhook"}}))
 	}
@@ -807,6 +1036,153 @@ func (t Telegram) TestWebhook(c telebot.Context) error {
 	return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "WebhookTestDispatched"}}))
 }
 
+// InlineQuery answers "@bot" queries typed in any chat. An empty query lists
+// the upcoming events of the user so they can be cross-posted with working
+// join buttons, any other text is searched on BGG.
+func (t Telegram) InlineQuery(c telebot.Context) error {
+	query := strings.TrimSpace(c.Query().Text)
+	userID := c.Sender().ID
+	log.Default().Printf("User %d sent inline query: %q", userID, query)
+
+	var results telebot.Results
+	var err error
+	if query == "" {
+		results, err = t.inlineEventResults(userID)
+	} else {
+		results, err = t.inlineBGGResults(query)
+	}
+	if err != nil {
+		log.Default().Println("failed to answer inline query:", err)
+		results = telebot.Results{}
+	}
+
+	return c.Answer(&telebot.QueryResponse{
+		Results:    results,
+		CacheTime:  10,
+		IsPersonal: true,
+	})
+}
+
+func (t Telegram) inlineEventResults(userID int64) (telebot.Results, error) {
+	events, err := t.DB.SelectEventsByUserID(userID, 50)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	results := telebot.Results{}
+	for _, event := range events {
+		if len(results) >= maxInlineResults {
+			break
+		}
+		if event.StartsAt != nil && event.StartsAt.Before(now) {
+			continue
+		}
+
+		body, markup := event.FormatMsg(t.Service.Localizer(&event.ChatID), t.Url)
+
+		details := []string{}
+		if event.StartsAt != nil {
+			details = append(details, "⏰ "+event.StartsAt.Format("2006-01-02 15:04"))
+		}
+		if event.Location != nil && *event.Location != "" {
+			details = append(details, "📍 "+*event.Location)
+		}
+
+		result := &telebot.ArticleResult{
+			Title:       event.Name,
+			Description: strings.Join(details, " "),
+		}
+		result.SetResultID(event.ID)
+		result.SetContent(&telebot.InputTextMessageContent{
+			Text:           body,
+			ParseMode:      telebot.ModeHTML,
+			PreviewOptions: &telebot.PreviewOptions{Disabled: true},
+		})
+		result.SetReplyMarkup(markup)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (t Telegram) inlineBGGResults(query string) (telebot.Results, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	games, err := t.Service.BGG.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].ID < games[j].ID
+	})
+
+	results := telebot.Results{}
+	for _, game := range games {
+		if len(results) >= maxInlineResults {
+			break
+		}
+		if game.Name == "" || game.ID <= 0 {
+			continue
+		}
+
+		bggUrl := fmt.Sprintf("https://boardgamegeek.com/boardgame/%d", game.ID)
+		description := ""
+		if game.YearPublished > 0 {
+			description = strconv.Itoa(game.YearPublished)
+		}
+
+		result := &telebot.ArticleResult{
+			Title:       game.Name,
+			Description: description,
+			URL:         bggUrl,
+		}
+		result.SetResultID(strconv.FormatInt(game.ID, 10))
+		result.SetContent(&telebot.InputTextMessageContent{
+			Text:      fmt.Sprintf("🎲 <a href='%s'>%s</a>", bggUrl, html.EscapeString(game.Name)),
+			ParseMode: telebot.ModeHTML,
+		})
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// refreshInlineMessage re-renders an event posted through inline mode. Those
+// messages are not tracked by the event, so only the one clicked is updated.
+func (t Telegram) refreshInlineMessage(c telebot.Context, event *models.Event) {
+	if c.Callback() == nil || c.Callback().Message != nil || c.Callback().MessageID == "" || event == nil {
+		return
+	}
+
+	body, markup := event.FormatMsg(t.Service.Localizer(&event.ChatID), t.Url)
+	if err := c.Edit(body, markup, telebot.NoPreview); err != nil && !strings.Contains(err.Error(), models.MessageUnchangedErrorMessage) {
+		log.Default().Println("failed to edit inline message:", err)
+	}
+}
+
+// toastCallback answers the callback query with a short notification, which
+// also stops the loading spinner on the button.
+func (t Telegram) toastCallback(c telebot.Context, messageID string, data map[string]string) error {
+	return c.Respond(&telebot.CallbackResponse{
+		Text: t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: messageID},
+			TemplateData:   data,
+		}),
+	})
+}
+
+// alertCallback answers the callback query with a popup that only the user
+// who clicked can see, instead of writing the error in the chat.
+func (t Telegram) alertCallback(c telebot.Context, messageID string) error {
+	return c.Respond(&telebot.CallbackResponse{
+		Text:      t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: messageID}}),
+		ShowAlert: true,
+	})
+}
+
 func (t Telegram) CallbackAddPlayer(c telebot.Context) error {
 	var err error
 
@@ -814,29 +1190,37 @@ func (t Telegram) CallbackAddPlayer(c telebot.Context) error {
 	parts := strings.Split(data, "|")
 	if len(parts) != 3 {
 		log.Default().Println("Invalid data:", data)
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidData"}}))
+		return t.alertCallback(c, "InvalidData")
 	}
 
 	eventID := parts[1]
 	boardGameID, err2 := strconv.ParseInt(parts[2], 10, 64)
 	if !models.IsValidUUID(eventID) || err2 != nil {
 		log.Default().Println("Invalid parsed id:", data)
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidData"}}))
+		return t.alertCallback(c, "InvalidData")
 	}
 
-	chatID := c.Chat().ID
 	userID := c.Sender().ID
 	userName, isTelegramUsername := DefineUsername(c.Sender())
 	log.Default().Printf("User %s (%d) clicked to join a game.", userName, userID)
 
 	var participantID string
+	var event *models.Event
 	var game *models.BoardGame
-	if participantID, _, game, err = t.Service.AddPlayer(nil, eventID, boardGameID, userID, userName, isTelegramUsername); err != nil {
+	if participantID, event, game, err = t.Service.AddPlayer(nil, eventID, boardGameID, userID, userName, isTelegramUsername); err != nil {
 		log.Default().Println("failed to add user to participants table:", err)
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToAddPlayer"}}))
+		if errors.Is(err, api.ErrRSVPClosed) {
+			return t.alertCallback(c, "RSVPClosed")
+		}
+		if errors.Is(err, api.ErrEventFinished) {
+			return t.alertCallback(c, "EventFinished")
+		}
+		return t.alertCallback(c, "FailedToAddPlayer")
 	}
 
-	t.Hook.SendAllWebhookAsync(context.Background(), chatID, models.HookWebhookEnvelope{
+	t.refreshInlineMessage(c, event)
+
+	t.Hook.SendAllWebhookAsync(context.Background(), event.ChatID, models.HookWebhookEnvelope{
 		Type: models.HookWebhookTypeAddParticipant,
 		Data: models.HookAddParticipantPayload{
 			ID:       participantID,
@@ -848,54 +1232,100 @@ func (t Telegram) CallbackAddPlayer(c telebot.Context) error {
 		},
 	})
 
-	return nil
+	if position, _ := game.WaitlistPosition(userID); position > 0 {
+		return t.toastCallback(c, "JoinedWaitlist", map[string]string{
+			"Name":     game.Name,
+			"Position": strconv.Itoa(position),
+		})
+	}
+	if game.Name == models.PLAYER_COUNTER {
+		return t.toastCallback(c, "JoinedEvent", nil)
+	}
+	return t.toastCallback(c, "JoinedGame", map[string]string{"Name": game.Name})
 }
 
-func (t Telegram) CallbackRemovePlayer(c telebot.Context) error {
-	var err error
+// CallbackNotComing records that the user cannot make it to the event,
+// freeing their seat.
+func (t Telegram) CallbackNotComing(c telebot.Context) error {
+	return t.callbackRSVP(c, models.RSVPDeclined, "DeclinedEvent")
+}
 
+// CallbackMaybe records that the user may come to the event, freeing their
+// seat until they decide.
+func (t Telegram) CallbackMaybe(c telebot.Context) error {
+	return t.callbackRSVP(c, models.RSVPMaybe, "MaybeEvent")
+}
+
+func (t Telegram) callbackRSVP(c telebot.Context, status models.RSVPStatus, toastID string) error {
 	data := c.Callback().Data
 	parts := strings.Split(data, "|")
-	if len(parts) != 2 {
+	if len(parts) != 2 || !models.IsValidUUID(parts[1]) {
 		log.Default().Println("Invalid data:", data)
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidData"}}))
+		return t.alertCallback(c, "InvalidData")
 	}
 
 	eventID := parts[1]
-	if !models.IsValidUUID(eventID) {
-		log.Default().Println("Invalid parsed id:", data)
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidData"}}))
-	}
-
-	chatID := c.Chat().ID
 	userID := c.Sender().ID
-	userName, _ := DefineUsername(c.Sender())
-	log.Default().Printf("User %s (%d) clicked to exit a game.", userName, userID)
+	userName, isTelegramUsername := DefineUsername(c.Sender())
+	log.Default().Printf("User %s (%d) clicked to answer %s.", userName, userID, status)
 
-	var participantID string
-	var game *models.BoardGame
-	if participantID, _, game, err = t.Service.DeletePlayer(eventID, userID); err != nil {
-		if errors.Is(err, database.ErrNoRows) {
-			return nil
+	event, _, err := t.Service.SetRSVP(eventID, userID, userName, isTelegramUsername, status)
+	if err != nil {
+		log.Default().Println("failed to set rsvp:", err)
+		if errors.Is(err, api.ErrEventFinished) {
+			return t.alertCallback(c, "EventFinished")
 		}
+		return t.alertCallback(c, "FailedToSetRSVP")
+	}
+
+	t.refreshInlineMessage(c, event)
+
+	return t.toastCallback(c, toastID, nil)
+}
 
-		log.Default().Println("failed to delete player:", err)
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToRemovePlayer"}}))
+func (t Telegram) CallbackAddGuest(c telebot.Context) error {
+	data := c.Callback().Data
+	parts := strings.Split(data, "|")
+	if len(parts) != 2 || !models.IsValidUUID(parts[1]) {
+		log.Default().Println("Invalid data:", data)
+		return t.alertCallback(c, "InvalidData")
 	}
 
-	t.Hook.SendAllWebhookAsync(context.Background(), chatID, models.HookWebhookEnvelope{
-		Type: models.HookWebhookTypeRemoveParticipant,
-		Data: models.HookRemoveParticipantPayload{
-			ID:        participantID,
-			EventID:   eventID,
-			UserID:    userID,
-			GameID:    game.UUID,
-			UserName:  userName,
-			RemovedAt: time.Now(),
-		},
-	})
+	eventID := parts[1]
+	userID := c.Sender().ID
+	log.Default().Printf("User %d clicked to bring a guest.", userID)
+
+	event, game, err := t.Service.AddGuest(eventID, userID, "")
+	switch {
+	case errors.Is(err, database.ErrNoRows):
+		return t.alertCallback(c, "JoinBeforeGuests")
+	case errors.Is(err, api.ErrTooManyGuests):
+		return t.alertCallback(c, "TooManyGuests")
+	case errors.Is(err, api.ErrRSVPClosed):
+		return t.alertCallback(c, "RSVPClosed")
+	case errors.Is(err, api.ErrEventFinished):
+		return t.alertCallback(c, "EventFinished")
+	case err != nil:
+		log.Default().Println("failed to add guest:", err)
+		return t.alertCallback(c, "FailedToAddGuest")
+	}
 
-	return nil
+	t.refreshInlineMessage(c, event)
+
+	name := game.Name
+	if name == models.PLAYER_COUNTER {
+		name = event.Name
+	}
+	guests := 0
+	for _, p := range game.Participants {
+		if p.UserID == userID {
+			guests = len(p.Guests)
+		}
+	}
+	return t.toastCallback(c, "GuestAdded", map[string]string{
+		"Count": strconv.Itoa(guests),
+		"Name":  name,
+	})
 }
 
 func (t Telegram) CallbackUnregisterWebhook(c telebot.Context) error {
@@ -905,14 +1335,14 @@ func (t Telegram) CallbackUnregisterWebhook(c telebot.Context) error {
 	parts := strings.Split(data, "|")
 	if len(parts) != 2 {
 		log.Default().Println("Invalid data:", data)
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "InvalidData"}}))
+		return t.alertCallback(c, "InvalidData")
 	}
 
 	// parse to int64
 	webhookID, err2 := strconv.ParseInt(parts[1], 10, 64)
 	if err2 != nil {
 		log.Default().Println("Invalid webhook id:", parts[1])
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToUnregisterWebhook"}}))
+		return t.alertCallback(c, "FailedToUnregisterWebhook")
 	}
 
 	userID := c.Sender().ID
@@ -921,7 +1351,7 @@ func (t Telegram) CallbackUnregisterWebhook(c telebot.Context) error {
 
 	if err = t.DB.RemoveWebhook(webhookID); err != nil {
 		log.Default().Println("failed to remove webhook:", err)
-		return c.Reply(t.Localizer(c).MustLocalize(&i18n.LocalizeConfig{DefaultMessage: &i18n.Message{ID: "FailedToUnregisterWebhook"}}))
+		return t.alertCallback(c, "FailedToUnregisterWebhook")
 	}
 
 	messageID := c.Callback().Message.ID
@@ -933,5 +1363,5 @@ func (t Telegram) CallbackUnregisterWebhook(c telebot.Context) error {
 		log.Default().Println("failed to delete webhook message:", err)
 	}
 
-	return nil
+	return t.toastCallback(c, "WebhookUnregistered", nil)
 }
@@ -0,0 +1,223 @@
+package telegram
+
+import (
+	"boardgame-night-bot/src/database"
+	"boardgame-night-bot/src/hooks"
+	langpack "boardgame-night-bot/src/language"
+	"boardgame-night-bot/src/models"
+	"boardgame-night-bot/src/web/api"
+	"boardgame-night-bot/src/web/tgwebhook"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+	"gopkg.in/telebot.v3"
+)
+
+const testWebhookSecret = "test_secret"
+
+// apiCall is a request the bot made to the fake Bot API.
+type apiCall struct {
+	Method string
+	Params map[string]any
+	// Files holds the content of the files uploaded with the call.
+	Files map[string][]byte
+}
+
+// webhookHarness wires the real handlers to a gin router in webhook mode and
+// records every call made to a fake Bot API server.
+type webhookHarness struct {
+	tg     Telegram
+	router *gin.Engine
+	mu     sync.Mutex
+	calls  []apiCall
+}
+
+func newWebhookHarness(t *testing.T) *webhookHarness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	h := &webhookHarness{}
+	fakeAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/")
+		call := apiCall{Method: parts[len(parts)-1]}
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			call.Params, call.Files = map[string]any{}, map[string][]byte{}
+			for key, values := range r.MultipartForm.Value {
+				call.Params[key] = values[0]
+			}
+			for key, files := range r.MultipartForm.File {
+				f, _ := files[0].Open()
+				call.Files[key], _ = io.ReadAll(f)
+				f.Close()
+			}
+		} else {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &call.Params)
+		}
+
+		h.mu.Lock()
+		h.calls = append(h.calls, call)
+		h.mu.Unlock()
+
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"group"}}}`)
+	}))
+	t.Cleanup(fakeAPI.Close)
+
+	bot, err := telebot.NewBot(telebot.Settings{
+		URL:         fakeAPI.URL,
+		Token:       "test",
+		ParseMode:   telebot.ModeHTML,
+		Offline:     true,
+		Synchronous: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := database.NewDatabase(t.TempDir())
+	t.Cleanup(db.Close)
+	db.CreateTables()
+	db.MigrateToV1()
+	db.MigrateToV2()
+	db.MigrateToV3()
+	db.MigrateToV4()
+	db.MigrateToV5()
+	db.MigrateToV6()
+	db.MigrateToV7()
+	db.MigrateToV8()
+	db.MigrateToV9()
+	db.MigrateToV10()
+	db.MigrateToV11()
+	db.MigrateToV12()
+	db.MigrateToV13()
+	db.MigrateToV14()
+	db.MigrateToV15()
+	db.MigrateToV16()
+
+	lp, err := langpack.BuildLanguagePack("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := i18n.NewBundle(language.English)
+	bundle.RegisterUnmarshalFunc("toml", toml.Unmarshal)
+	for _, lang := range lp.Languages {
+		bundle.MustLoadMessageFile(fmt.Sprintf("../../localization/active.%s.toml", lang))
+	}
+
+	url := models.WebUrl{BaseUrl: "https://example.com", BotMiniAppURL: "https://t.me/bot/app"}
+	h.tg = Telegram{
+		Bot:            bot,
+		DB:             db,
+		LanguageBundle: bundle,
+		LanguagePack:   lp,
+		Url:            url,
+		Hook:           hooks.NewWebhookClient(db, url.BaseUrl, time.Second, 1, time.Minute, 1),
+		Service:        api.NewService(db, nil, bot, nil, bundle, url),
+	}
+	h.tg.SetupHandlers()
+
+	h.router = gin.New()
+	h.router.POST(tgwebhook.Path, tgwebhook.GinHandler(bot, testWebhookSecret))
+	return h
+}
+
+func (h *webhookHarness) post(secret string, update string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, tgwebhook.Path, bytes.NewBufferString(update))
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(tgwebhook.SecretTokenHeader, secret)
+	}
+	w := httptest.NewRecorder()
+	h.router.ServeHTTP(w, req)
+	return w
+}
+
+func (h *webhookHarness) methods() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	methods := []string{}
+	for _, call := range h.calls {
+		methods = append(methods, call.Method)
+	}
+	return methods
+}
+
+const startUpdate = `{"update_id":1,"message":{"message_id":10,"date":0,
+	"from":{"id":42,"first_name":"Ada","language_code":"en"},
+	"chat":{"id":-100,"type":"group"},"text":"/start",
+	"entities":[{"type":"bot_command","offset":0,"length":6}]}}`
+
+// commandUpdate is a message sending text, which starts with a command, from
+// the user to the chat.
+func commandUpdate(chatID int64, chatType string, userID int64, text string) string {
+	command := strings.Fields(text)[0]
+	return fmt.Sprintf(`{"update_id":2,"message":{"message_id":10,"date":0,
+		"from":{"id":%d,"first_name":"Ada","language_code":"en"},
+		"chat":{"id":%d,"type":%q},"text":%q,
+		"entities":[{"type":"bot_command","offset":0,"length":%d}]}}`, userID, chatID, chatType, text, len(command))
+}
+
+func TestWebhookDispatchesToHandlers(t *testing.T) {
+	h := newWebhookHarness(t)
+
+	w := h.post(testWebhookSecret, startUpdate)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	methods := h.methods()
+	if len(methods) != 1 || methods[0] != "sendMessage" {
+		t.Fatalf("api calls = %v, want [sendMessage]", methods)
+	}
+	if chatID := fmt.Sprint(h.calls[0].Params["chat_id"]); chatID != "-100" {
+		t.Errorf("chat_id = %s, want -100", chatID)
+	}
+}
+
+func TestWebhookAnswersInlineQuery(t *testing.T) {
+	h := newWebhookHarness(t)
+
+	w := h.post(testWebhookSecret, `{"update_id":2,"inline_query":{"id":"q1",
+		"from":{"id":42,"first_name":"Ada"},"query":"","offset":""}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	methods := h.methods()
+	if len(methods) != 1 || methods[0] != "answerInlineQuery" {
+		t.Fatalf("api calls = %v, want [answerInlineQuery]", methods)
+	}
+}
+
+func TestWebhookRejectsInvalidSecret(t *testing.T) {
+	h := newWebhookHarness(t)
+
+	for _, secret := range []string{"", "wrong"} {
+		if w := h.post(secret, startUpdate); w.Code != http.StatusUnauthorized {
+			t.Errorf("secret %q: status = %d, want %d", secret, w.Code, http.StatusUnauthorized)
+		}
+	}
+	if methods := h.methods(); len(methods) != 0 {
+		t.Errorf("api calls = %v, want none", methods)
+	}
+}
+
+func TestWebhookRejectsMalformedUpdate(t *testing.T) {
+	h := newWebhookHarness(t)
+
+	if w := h.post(testWebhookSecret, `{"update_id":`); w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
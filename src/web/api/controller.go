@@ -6,13 +6,16 @@ import (
 	"boardgame-night-bot/src/hooks"
 	"boardgame-night-bot/src/models"
 	"boardgame-night-bot/src/utils"
+	"boardgame-night-bot/src/web/idempotency"
 	"boardgame-night-bot/src/web/limiter"
+	"boardgame-night-bot/src/web/webapp"
 	"bytes"
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -31,6 +34,13 @@ import (
 	"gopkg.in/telebot.v3"
 )
 
+const (
+	// webhookMaxAge is how old a signed inbound webhook request may be.
+	webhookMaxAge = 2 * time.Minute
+	// webhookMaxClockSkew is how far in the future a request date may be.
+	webhookMaxClockSkew = 30 * time.Second
+)
+
 type Controller struct {
 	Router         *gin.RouterGroup
 	DB             *database.Database
@@ -40,6 +50,9 @@ type Controller struct {
 	Hook           *hooks.WebhookClient
 	Service        *Service
 	Limiter        *limiter.Limiter
+	Idempotency    *idempotency.Store
+	// BotToken verifies the init data the mini app receives from Telegram.
+	BotToken string
 }
 
 func NewController(router *gin.RouterGroup, db *database.Database, bgg bgg.BGGService, bot *telebot.Bot, LanguageBundle *i18n.Bundle, hook *hooks.WebhookClient, service *Service) *Controller {
@@ -52,6 +65,9 @@ func NewController(router *gin.RouterGroup, db *database.Database, bgg bgg.BGGSe
 		Hook:           hook,
 		Service:        service,
 		Limiter:        limiter.NewLimiter(5, 5),
+		// keys must outlive every request that can still pass the date check
+		Idempotency: idempotency.NewStore(webhookMaxAge + webhookMaxClockSkew),
+		BotToken:    bot.Token,
 	}
 }
 
@@ -75,11 +91,19 @@ func (c *Controller) InjectRoute() {
 	c.Router.DELETE("/events/:event_id/games/:game_id", c.DeleteGame)
 	c.Router.POST("/events/:event_id/add-game", c.AddGame)
 	c.Router.POST("/events/:event_id/join", c.AddPlayer)
+	c.Router.POST("/events/:event_id/guests", c.AddGuest)
+	c.Router.DELETE("/events/:event_id/guests", c.RemoveGuest)
+	c.Router.POST("/events/:event_id/rsvp", c.SetRSVP)
+	c.Router.GET("/events/:event_id/hosts/:user_id", c.IsHost)
+	c.Router.POST("/events/:event_id/kick", c.KickParticipant)
+	c.Router.POST("/events/:event_id/move", c.MoveParticipant)
+	c.Router.POST("/events/:event_id/clone", c.CloneEvent)
 	c.Router.GET("/bgg/search", c.BggSearch)
 	c.Router.POST(
 		"/webhooks/:webhook_id",
 		c.Limiter.GinHandler(),
 		c.VerifyWebhook(),
+		c.Idempotency.GinHandler(hooks.IdempotencyKeyHeader),
 		c.CheckEventID(),
 		c.ListenWebhook,
 	)
@@ -211,22 +235,34 @@ func (c *Controller) CreateEvent(ctx *gin.Context) {
 		newEvent.ThreadID = nil
 	}
 
-	if newEvent.IsLocked {
-		newEvent.Name = fmt.Sprintf("🔒 %s", newEvent.Name)
-	}
-
 	if newEvent.Location != nil && *newEvent.Location == "" {
 		newEvent.Location = nil
 	}
 
 	var event *models.Event
-	if event, err = c.Service.CreateEvent(newEvent.ChatID, newEvent.ThreadID, nil, newEvent.UserID, newEvent.UserName, newEvent.Name, newEvent.Location, newEvent.StartsAt, bool(newEvent.AllowGeneralJoin)); err != nil {
+	if event, err = c.Service.CreateEvent(newEvent.ChatID, newEvent.ThreadID, nil, newEvent.UserID, newEvent.UserName, newEvent.Name, newEvent.Location, newEvent.StartsAt, bool(newEvent.IsLocked), bool(newEvent.AllowGeneralJoin)); err != nil {
 		log.Default().Println("failed to create event:", err)
 		c.renderError(ctx, nil, nil, "Failed to create event")
 		return
 	}
 
 	ctx.Redirect(http.StatusFound, fmt.Sprintf("/events/%s", event.ID))
+
+	c.Hook.SendAllWebhookAsync(context.Background(), event.ChatID, models.HookWebhookEnvelope{
+		Type: models.HookWebhookTypeNewEvent,
+		Data: models.HookNewEventPayload{
+			ID:        event.ID,
+			ChatID:    event.ChatID,
+			UserID:    event.UserID,
+			UserName:  event.UserName,
+			Name:      event.Name,
+			MessageID: event.MessageID,
+			Location:  event.Location,
+			StartsAt:  event.StartsAt,
+			Locked:    event.Locked,
+			CreatedAt: time.Now(),
+		},
+	})
 }
 
 func (c *Controller) GetEvent(ctx *gin.Context) {
@@ -266,13 +302,22 @@ func (c *Controller) GetEvent(ctx *gin.Context) {
 		}
 	}
 
+	var deadline string
+	if event.RSVPDeadline != nil {
+		deadline = event.FormatRSVPDeadline(localizer, time.Now())
+	}
+
 	// serve an html file
 	ctx.HTML(http.StatusOK, "event", gin.H{
 		"Id":             event.ID,
-		"Title":          event.Name,
+		"Title":          event.Title(),
 		"Host":           event.UserName,
+		"CoHosts":        event.CoHosts,
 		"StartsAt":       event.FormatStartAt(),
 		"Location":       event.Location,
+		"RSVPDeadline":   deadline,
+		"Finished":       event.Finished,
+		"EventFinished":  localizer.MustLocalizeMessage(&i18n.Message{ID: "WebEventFinished"}),
 		"Games":          event.BoardGames,
 		"UpdatedAt":      timeT,
 		"NoParticipants": localizer.MustLocalizeMessage(&i18n.Message{ID: "WebNoParticipants"}),
@@ -283,7 +328,23 @@ func (c *Controller) GetEvent(ctx *gin.Context) {
 		"AddNewGame":     localizer.MustLocalizeMessage(&i18n.Message{ID: "WebAddNewGame"}),
 		"GameName":       localizer.MustLocalizeMessage(&i18n.Message{ID: "WebGameName"}),
 		"MaxPlayers":     localizer.MustLocalizeMessage(&i18n.Message{ID: "WebMaxPlayers"}),
+		"Slot":           localizer.MustLocalizeMessage(&i18n.Message{ID: "WebSlot"}),
 		"AddToCalendar":  localizer.MustLocalizeMessage(&i18n.Message{ID: "WebAddToCalendar"}),
+		"Guests":         localizer.MustLocalizeMessage(&i18n.Message{ID: "WebGuests"}),
+		"GuestName":      localizer.MustLocalizeMessage(&i18n.Message{ID: "WebGuestName"}),
+		"Tentative":      event.Tentative,
+		"Declined":       event.Declined,
+		"MaybeTitle":     localizer.MustLocalizeMessage(&i18n.Message{ID: "MaybeTitle"}),
+		"DeclinedTitle":  localizer.MustLocalizeMessage(&i18n.Message{ID: "DeclinedTitle"}),
+		"MaybeButton":    localizer.MustLocalizeMessage(&i18n.Message{ID: "MaybeButton"}),
+		"NotComing":      localizer.MustLocalizeMessage(&i18n.Message{ID: "NotComing"}),
+		"MoveTo":         localizer.MustLocalizeMessage(&i18n.Message{ID: "WebMoveTo"}),
+		"Kick":           localizer.MustLocalizeMessage(&i18n.Message{ID: "WebKick"}),
+		"KickConfirm":    localizer.MustLocalizeMessage(&i18n.Message{ID: "WebKickConfirm"}),
+		"DuplicateEvent": localizer.MustLocalizeMessage(&i18n.Message{ID: "WebDuplicateEvent"}),
+		"EventDate":      localizer.MustLocalizeMessage(&i18n.Message{ID: "WebEventDate"}),
+		"CopyPlayers":    localizer.MustLocalizeMessage(&i18n.Message{ID: "WebCopyParticipants"}),
+		"Duplicate":      localizer.MustLocalizeMessage(&i18n.Message{ID: "WebDuplicate"}),
 		"QueuedLang":     c.DB.GetPreferredLanguage(event.ChatID),
 	})
 }
@@ -420,13 +481,14 @@ func (c *Controller) GetGame(ctx *gin.Context) {
 
 	ctx.HTML(http.StatusOK, "game_info", gin.H{
 		"Id":                      event.ID,
-		"Title":                   event.Name,
+		"Title":                   event.Title(),
 		"StartsAt":                event.FormatStartAt(),
 		"Location":                event.Location,
 		"Game":                    game,
 		"NoParticipants":          localizer.MustLocalizeMessage(&i18n.Message{ID: "WebNoParticipants"}),
 		"Players":                 localizer.MustLocalizeMessage(&i18n.Message{ID: "WebPlayers"}),
 		"MaxPlayers":              localizer.MustLocalizeMessage(&i18n.Message{ID: "WebMaxPlayers"}),
+		"Slot":                    localizer.MustLocalizeMessage(&i18n.Message{ID: "WebSlot"}),
 		"UpdateGame":              localizer.MustLocalizeMessage(&i18n.Message{ID: "WebUpdateGame"}),
 		"Update":                  localizer.MustLocalizeMessage(&i18n.Message{ID: "Update"}),
 		"UnlinkFormBoardGameGeek": localizer.MustLocalizeMessage(&i18n.Message{ID: "WebUnlinkFormBoardGameGeek"}),
@@ -467,6 +529,10 @@ func (c *Controller) UpdateGame(ctx *gin.Context) {
 		if event != nil {
 			chatID = &event.ChatID
 		}
+		if errors.Is(err, ErrEventFinished) {
+			c.renderError(ctx, &eventID, chatID, "The event is over")
+			return
+		}
 		c.renderError(ctx, &eventID, chatID, "Failed to update game")
 		return
 	}
@@ -478,13 +544,14 @@ func (c *Controller) UpdateGame(ctx *gin.Context) {
 
 	ctx.HTML(http.StatusOK, "game_info", gin.H{
 		"Id":                      event.ID,
-		"Title":                   event.Name,
+		"Title":                   event.Title(),
 		"StartsAt":                event.FormatStartAt(),
 		"Location":                event.Location,
 		"Game":                    game,
 		"NoParticipants":          localizer.MustLocalizeMessage(&i18n.Message{ID: "WebNoParticipants"}),
 		"Players":                 localizer.MustLocalizeMessage(&i18n.Message{ID: "WebPlayers"}),
 		"MaxPlayers":              localizer.MustLocalizeMessage(&i18n.Message{ID: "WebMaxPlayers"}),
+		"Slot":                    localizer.MustLocalizeMessage(&i18n.Message{ID: "WebSlot"}),
 		"UpdateGame":              localizer.MustLocalizeMessage(&i18n.Message{ID: "WebUpdateGame"}),
 		"Update":                  localizer.MustLocalizeMessage(&i18n.Message{ID: "Update"}),
 		"UnlinkFormBoardGameGeek": localizer.MustLocalizeMessage(&i18n.Message{ID: "WebUnlinkFormBoardGameGeek"}),
@@ -496,23 +563,7 @@ func (c *Controller) UpdateGame(ctx *gin.Context) {
 
 	c.Hook.SendAllWebhookAsync(context.Background(), event.ChatID, models.HookWebhookEnvelope{
 		Type: models.HookWebhookTypeUpdateGame,
-		Data: models.HookUpdateGamePayload{
-			ID:         game.UUID,
-			EventID:    event.ID,
-			UserID:     bg.UserID,
-			UserName:   bg.UserName,
-			Name:       game.Name,
-			MaxPlayers: int(game.MaxPlayers),
-			MessageID:  nil,
-			BGG: models.HookBGGInfo{
-				IsSet:    game.BggID != nil,
-				ID:       game.BggID,
-				Name:     game.BggName,
-				URL:      game.BggUrl,
-				ImageURL: game.BggImageUrl,
-			},
-			UpdatedAt: time.Now(),
-		},
+		Data: models.NewHookUpdateGamePayload(event.ID, *game, bg.UserID, bg.UserName),
 	})
 }
 
@@ -554,6 +605,10 @@ func (c *Controller) DeleteGame(ctx *gin.Context) {
 		if event != nil {
 			chatID = &event.ChatID
 		}
+		if errors.Is(err, ErrEventFinished) {
+			c.renderError(ctx, &eventID, chatID, "The event is over")
+			return
+		}
 		c.renderError(ctx, &eventID, chatID, "Failed to delete game")
 		return
 	}
@@ -592,12 +647,16 @@ func (c *Controller) AddGame(ctx *gin.Context) {
 	var event *models.Event
 	var game *models.BoardGame
 
-	if event, game, err = c.Service.CreateGame(eventID, nil, bg.UserID, bg.Name, bg.MaxPlayers, bg.BggUrl); err != nil {
+	if event, game, err = c.Service.CreateGame(eventID, nil, bg.UserID, bg.Name, bg.MaxPlayers, bg.BggUrl, bg.Slot); err != nil {
 		log.Default().Println("failed to add game:", err)
 		var chatID *int64
 		if event != nil {
 			chatID = &event.ChatID
 		}
+		if errors.Is(err, ErrEventFinished) {
+			c.renderError(ctx, &eventID, chatID, "The event is over")
+			return
+		}
 		c.renderError(ctx, &eventID, chatID, "Failed to add game")
 		return
 	}
@@ -606,13 +665,14 @@ func (c *Controller) AddGame(ctx *gin.Context) {
 
 	ctx.HTML(http.StatusOK, "game_info", gin.H{
 		"Id":                      event.ID,
-		"Title":                   event.Name,
+		"Title":                   event.Title(),
 		"StartsAt":                event.FormatStartAt(),
 		"Location":                event.Location,
 		"Game":                    game,
 		"NoParticipants":          localizer.MustLocalizeMessage(&i18n.Message{ID: "WebNoParticipants"}),
 		"Players":                 localizer.MustLocalizeMessage(&i18n.Message{ID: "WebPlayers"}),
 		"MaxPlayers":              localizer.MustLocalizeMessage(&i18n.Message{ID: "WebMaxPlayers"}),
+		"Slot":                    localizer.MustLocalizeMessage(&i18n.Message{ID: "WebSlot"}),
 		"UpdateGame":              localizer.MustLocalizeMessage(&i18n.Message{ID: "WebUpdateGame"}),
 		"Update":                  localizer.MustLocalizeMessage(&i18n.Message{ID: "Update"}),
 		"UnlinkFormBoardGameGeek": localizer.MustLocalizeMessage(&i18n.Message{ID: "WebUnlinkFormBoardGameGeek"}),
@@ -631,6 +691,7 @@ func (c *Controller) AddGame(ctx *gin.Context) {
 			UserName:   event.UserName,
 			Name:       bg.Name,
 			MaxPlayers: int(game.MaxPlayers),
+			Slot:       game.Slot,
 			MessageID:  nil,
 			BGG: models.HookBGGInfo{
 				IsSet:    game.BggID != nil,
@@ -665,6 +726,10 @@ func (c *Controller) AddPlayer(ctx *gin.Context) {
 	var game *models.BoardGame
 	if participantID, event, game, err = c.Service.AddPlayer(nil, eventID, addPlayer.GameID, addPlayer.UserID, addPlayer.UserName, addPlayer.IsTelegramUsername); err != nil {
 		log.Default().Println("failed to add player:", err)
+		if errors.Is(err, ErrRSVPClosed) || errors.Is(err, ErrEventFinished) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
 		return
 	}
@@ -684,23 +749,270 @@ func (c *Controller) AddPlayer(ctx *gin.Context) {
 	})
 }
 
+func (c *Controller) AddGuest(ctx *gin.Context) {
+	eventID := ctx.Param("event_id")
+	if !models.IsValidUUID(eventID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var addGuest models.AddGuestRequest
+	if err := ctx.ShouldBindJSON(&addGuest); err != nil {
+		log.Default().Println("failed to bind form:", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+		return
+	}
+
+	if _, _, err := c.Service.AddGuest(eventID, addGuest.UserID, strings.TrimSpace(addGuest.Name)); err != nil {
+		log.Default().Println("failed to add guest:", err)
+		c.guestError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Guest added."})
+}
+
+func (c *Controller) RemoveGuest(ctx *gin.Context) {
+	eventID := ctx.Param("event_id")
+	if !models.IsValidUUID(eventID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(ctx.Query("user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if _, _, err = c.Service.RemoveGuest(eventID, userID); err != nil {
+		log.Default().Println("failed to remove guest:", err)
+		c.guestError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Guest removed."})
+}
+
+func (c *Controller) SetRSVP(ctx *gin.Context) {
+	eventID := ctx.Param("event_id")
+	if !models.IsValidUUID(eventID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var rsvp models.RSVPRequest
+	if err := ctx.ShouldBindJSON(&rsvp); err != nil {
+		log.Default().Println("failed to bind form:", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+		return
+	}
+
+	if _, _, err := c.Service.SetRSVP(eventID, rsvp.UserID, rsvp.UserName, rsvp.IsTelegramUsername, rsvp.Status); err != nil {
+		log.Default().Println("failed to set rsvp:", err)
+		if errors.Is(err, ErrEventFinished) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Answer saved."})
+}
+
+// IsHost tells the mini app whether to show the host actions to the user.
+// webAppUser returns the user who opened the mini app, as signed by Telegram
+// in the init data sent in the InitDataHeader header or the InitDataField
+// form field.
+func (c *Controller) webAppUser(ctx *gin.Context) (*webapp.User, error) {
+	initData := ctx.GetHeader(webapp.InitDataHeader)
+	if initData == "" {
+		initData = ctx.PostForm(webapp.InitDataField)
+	}
+
+	user, err := webapp.Validate(initData, c.BotToken, time.Now())
+	if err != nil {
+		log.Default().Println("failed to verify mini app init data:", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// IsHost reports whether the user who opened the mini app can manage the
+// event. Users can only ask about themselves.
+func (c *Controller) IsHost(ctx *gin.Context) {
+	eventID := ctx.Param("event_id")
+	if !models.IsValidUUID(eventID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := c.webAppUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid init data"})
+		return
+	}
+	if user.ID != userID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	event, err := c.DB.SelectEventByEventID(eventID)
+	if err != nil || event.ID == "" {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"host": c.Service.IsHost(event, userID)})
+}
+
+func (c *Controller) KickParticipant(ctx *gin.Context) {
+	eventID := ctx.Param("event_id")
+	if !models.IsValidUUID(eventID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	user, err := c.webAppUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid init data"})
+		return
+	}
+
+	var kick models.HostActionRequest
+	if err = ctx.ShouldBindJSON(&kick); err != nil {
+		log.Default().Println("failed to bind form:", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+		return
+	}
+
+	if _, err = c.Service.KickParticipant(eventID, user.ID, user.DisplayName(), kick.ParticipantID); err != nil {
+		log.Default().Println("failed to kick participant:", err)
+		c.hostError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Participant removed."})
+}
+
+func (c *Controller) MoveParticipant(ctx *gin.Context) {
+	eventID := ctx.Param("event_id")
+	if !models.IsValidUUID(eventID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	user, err := c.webAppUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid init data"})
+		return
+	}
+
+	var move models.HostActionRequest
+	if err = ctx.ShouldBindJSON(&move); err != nil || move.GameID == 0 {
+		log.Default().Println("failed to bind form:", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+		return
+	}
+
+	if _, _, err = c.Service.MoveParticipant(eventID, user.ID, user.DisplayName(), move.ParticipantID, move.GameID); err != nil {
+		log.Default().Println("failed to move participant:", err)
+		c.hostError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Participant moved."})
+}
+
+// CloneEvent duplicates an event from the mini app and opens the copy.
+func (c *Controller) CloneEvent(ctx *gin.Context) {
+	eventID := ctx.Param("event_id")
+	if !models.IsValidUUID(eventID) {
+		c.renderError(ctx, nil, nil, "Invalid event ID")
+		return
+	}
+
+	user, err := c.webAppUser(ctx)
+	if err != nil {
+		c.renderError(ctx, &eventID, nil, "Open the event from Telegram to duplicate it")
+		return
+	}
+
+	var clone models.CloneEventRequest
+	if err = ctx.ShouldBind(&clone); err != nil {
+		log.Default().Println("failed to bind form:", err)
+		c.renderError(ctx, &eventID, nil, "Invalid submitted form data")
+		return
+	}
+
+	event, err := c.Service.CloneEvent(eventID, nil, user.ID, user.DisplayName(), clone.StartsAt, bool(clone.Participants))
+	if err != nil {
+		log.Default().Println("failed to clone event:", err)
+		if errors.Is(err, ErrNotHost) {
+			c.renderError(ctx, &eventID, nil, "Only the hosts can duplicate the event")
+			return
+		}
+		c.renderError(ctx, &eventID, nil, "Failed to duplicate event")
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, fmt.Sprintf("/events/%s", event.ID))
+}
+
+func (c *Controller) hostError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotHost):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+	case errors.Is(err, ErrEventFinished):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+	}
+}
+
+func (c *Controller) guestError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, database.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Join a game before bringing guests"})
+	case errors.Is(err, ErrTooManyGuests), errors.Is(err, ErrNoGuests), errors.Is(err, ErrRSVPClosed), errors.Is(err, ErrEventFinished):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+	}
+}
+
 func P(x string) *string {
 	return &x
 }
 
+// abortWebhook stops the webhook handler chain with a structured error response.
+func abortWebhook(ctx *gin.Context, status int, code models.HookErrorCode, message string) {
+	ctx.AbortWithStatusJSON(status, models.NewHookErrorResponse(code, message))
+}
+
 func (c *Controller) VerifyWebhook() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		webhookID := ctx.Param("webhook_id")
 
 		webhook, err := c.DB.GetWebhookByWebhookID(webhookID)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid chat webhook ID"})
+			abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeInvalidWebhook, "invalid chat webhook ID")
 			return
 		}
 
 		body, err := ctx.GetRawData()
 		if err != nil {
-			ctx.AbortWithStatusJSON(400, gin.H{"error": "invalid body"})
+			abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid body")
 			return
 		}
 
@@ -708,7 +1020,7 @@ func (c *Controller) VerifyWebhook() gin.HandlerFunc {
 
 		date := ctx.GetHeader("x-ms-date")
 		if date == "" {
-			ctx.AbortWithStatusJSON(400, gin.H{"error": "missing date"})
+			abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeMissingHeader, "missing date")
 			return
 		}
 
@@ -720,34 +1032,45 @@ func (c *Controller) VerifyWebhook() gin.HandlerFunc {
 		log.Default().Printf("Computed content hash: %s", contentHashHex)
 
 		if ctx.GetHeader("x-ms-content-sha256") != contentHashHex {
-			ctx.AbortWithStatusJSON(400, gin.H{"error": "hash mismatch"})
+			abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeHashMismatch, "hash mismatch")
 			return
 		}
 
-		stringToSign := fmt.Sprintf("%s;%s", date, contentHashHex)
-
-		expectedSig := hooks.ComputeHMACBase64(stringToSign, []byte(webhook.Secret))
+		idempotencyKey := ctx.GetHeader(hooks.IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeMissingHeader, "missing idempotency key")
+			return
+		}
 
-		clientSig := ctx.GetHeader("X-BGNB-Signature")
+		// only the v2 signature covers the idempotency key: the original one
+		// would let a captured request be replayed under a fresh key
+		stringToSign := hooks.StringToSign(date, contentHashHex, idempotencyKey)
+		clientSig := ctx.GetHeader(hooks.SignatureV2Header)
 		if clientSig == "" {
-			ctx.AbortWithStatusJSON(401, gin.H{"error": "missing signature"})
+			abortWebhook(ctx, http.StatusUnauthorized, models.HookErrorCodeMissingHeader, "missing signature")
 			return
-
 		}
 
+		expectedSig := hooks.ComputeHMACBase64(stringToSign, []byte(webhook.Secret))
+
 		if !hmac.Equal([]byte(expectedSig), []byte(clientSig)) {
-			ctx.AbortWithStatusJSON(401, gin.H{"error": "invalid signature"})
+			abortWebhook(ctx, http.StatusUnauthorized, models.HookErrorCodeInvalidSignature, "invalid signature")
 			return
 		}
 
 		reqTime, parseErr := time.Parse(time.RFC1123, date)
 		if parseErr != nil {
-			ctx.AbortWithStatusJSON(400, gin.H{"error": "invalid date"})
+			abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeInvalidDate, "invalid date")
+			return
+		}
+
+		if time.Since(reqTime) > webhookMaxAge {
+			abortWebhook(ctx, http.StatusUnauthorized, models.HookErrorCodeRequestExpired, "request too old")
 			return
 		}
 
-		if time.Since(reqTime) > 2*time.Minute {
-			ctx.AbortWithStatusJSON(401, gin.H{"error": "request too old"})
+		if time.Until(reqTime) > webhookMaxClockSkew {
+			abortWebhook(ctx, http.StatusUnauthorized, models.HookErrorCodeInvalidDate, "request date is in the future")
 			return
 		}
 
@@ -764,27 +1087,14 @@ func (c *Controller) CheckEventID() gin.HandlerFunc {
 		var webhookEnvelope map[string]any
 		if err = ctx.ShouldBindBodyWith(&webhookEnvelope, binding.JSON); err != nil {
 			log.Default().Println("failed to bind webhook json:", err)
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+			abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid webhook data")
 			return
 		}
 
-		if webhookEnvelope["data"] != nil {
-			data := webhookEnvelope["data"].(map[string]any)
-			var eventID string
-			if iEventID, ok := data["event_id"]; ok {
-				eventID = iEventID.(string)
-
-				var event *models.Event
-				if event, err = c.DB.SelectEventByEventID(eventID); err != nil {
-					log.Default().Println("failed to load event:", err)
-					ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
-					return
-				}
-
-				chatID := ctx.GetInt64("chat_id")
-				if event.ChatID != chatID {
-					log.Default().Printf("Webhook event chat ID %d does not match expected chat ID %d", event.ChatID, chatID)
-					ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		if data, ok := webhookEnvelope["data"].(map[string]any); ok {
+			if eventID, ok := data["event_id"].(string); ok {
+				if failure := checkEventChat(c.DB, eventID, ctx.GetInt64("chat_id")); failure != nil {
+					abortWebhook(ctx, failure.status, failure.code, failure.message)
 					return
 				}
 			}
@@ -794,6 +1104,76 @@ func (c *Controller) CheckEventID() gin.HandlerFunc {
 	}
 }
 
+// webhookFailure describes why an inbound webhook operation was rejected.
+type webhookFailure struct {
+	status  int
+	code    models.HookErrorCode
+	message string
+}
+
+func (f *webhookFailure) Error() string {
+	return f.message
+}
+
+// serviceFailure maps the errors the service returns for requests it refuses
+// to their error code. Any other error fails the operation with message.
+func serviceFailure(err error, message string) *webhookFailure {
+	switch {
+	case errors.Is(err, ErrEventFinished):
+		return &webhookFailure{http.StatusConflict, models.HookErrorCodeEventFinished, err.Error()}
+	case errors.Is(err, ErrRSVPClosed):
+		return &webhookFailure{http.StatusConflict, models.HookErrorCodeRSVPClosed, err.Error()}
+	case errors.Is(err, ErrEventLocked):
+		return &webhookFailure{http.StatusConflict, models.HookErrorCodeEventLocked, err.Error()}
+	case errors.Is(err, ErrAlreadyImported):
+		return &webhookFailure{http.StatusConflict, models.HookErrorCodeAlreadyExists, err.Error()}
+	case errors.Is(err, ErrInvalidGame):
+		return &webhookFailure{http.StatusNotFound, models.HookErrorCodeGameNotFound, err.Error()}
+	case errors.Is(err, ErrNotHost), errors.Is(err, ErrNotEventOwner), errors.Is(err, ErrNotChatAdmin):
+		return &webhookFailure{http.StatusForbidden, models.HookErrorCodeForbidden, err.Error()}
+	}
+
+	return &webhookFailure{http.StatusInternalServerError, models.HookErrorCodeOperationFailed, message}
+}
+
+// eventGameID resolves gameUUID to the ID of a game of eventID.
+func eventGameID(db database.DatabaseService, eventID, gameUUID string) (int64, *webhookFailure) {
+	gameID, err := db.SelectGameIDByGameUUID(gameUUID)
+	if err != nil {
+		log.Default().Println("failed to get game ID from UUID in webhook:", err)
+		return 0, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidGame, "invalid game ID"}
+	}
+
+	event, err := db.SelectEventByEventID(eventID)
+	if err != nil {
+		log.Default().Println("failed to load event:", err)
+		return 0, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidEvent, "invalid event ID"}
+	}
+
+	if utils.PickGame(event, gameID) == nil {
+		log.Default().Printf("Webhook game %s does not belong to event %s", gameUUID, eventID)
+		return 0, &webhookFailure{http.StatusNotFound, models.HookErrorCodeGameNotFound, "game does not belong to the event"}
+	}
+
+	return gameID, nil
+}
+
+// checkEventChat ensures eventID exists and belongs to the webhook chat.
+func checkEventChat(db database.DatabaseService, eventID string, chatID int64) *webhookFailure {
+	event, err := db.SelectEventByEventID(eventID)
+	if err != nil {
+		log.Default().Println("failed to load event:", err)
+		return &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidEvent, "invalid event ID"}
+	}
+
+	if event.ChatID != chatID {
+		log.Default().Printf("Webhook event chat ID %d does not match expected chat ID %d", event.ChatID, chatID)
+		return &webhookFailure{http.StatusForbidden, models.HookErrorCodeForbidden, "event belongs to another chat"}
+	}
+
+	return nil
+}
+
 func (c *Controller) ListenWebhook(ctx *gin.Context) {
 	var err error
 	chatID := ctx.GetInt64("chat_id")
@@ -803,18 +1183,40 @@ func (c *Controller) ListenWebhook(ctx *gin.Context) {
 	var webhookEnvelope models.HookWebhookEnvelope
 	if err = ctx.ShouldBindBodyWith(&webhookEnvelope, binding.JSON); err != nil {
 		log.Default().Println("failed to bind webhook json:", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid webhook data")
 		return
 	}
 
 	log.Default().Printf("Received webhook for chat %d: %v", chatID, webhookEnvelope)
 
-	switch webhookEnvelope.Type {
+	if webhookEnvelope.Type == models.HookWebhookTypeBatch {
+		c.listenBatch(ctx, chatID, threadID, webhookID, webhookEnvelope)
+		return
+	}
+
+	result, failure := c.applyWebhook(c.Service, chatID, threadID, webhookID, webhookEnvelope)
+	if failure != nil {
+		abortWebhook(ctx, failure.status, failure.code, failure.message)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.HookWebhookResponse{
+		Type:    webhookEnvelope.Type,
+		Message: "Webhook received.",
+		Data:    result,
+	})
+}
+
+// applyWebhook executes a single inbound operation through s and returns the
+// resulting payload.
+func (c *Controller) applyWebhook(s *Service, chatID, threadID int64, webhookID string, envelope models.HookWebhookEnvelope) (any, *webhookFailure) {
+	var err error
+	var result any
+	switch envelope.Type {
 	case models.HookWebhookTypeNewEvent:
 		var payload *models.HookNewEventPayload
-		if payload, err = Cast[models.HookNewEventPayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookNewEventPayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid new_event data"}
 		}
 
 		if payload.ChatID == 0 {
@@ -824,128 +1226,209 @@ func (c *Controller) ListenWebhook(ctx *gin.Context) {
 
 		if payload.ChatID != chatID {
 			log.Default().Printf("Webhook chat ID %d does not match expected chat ID %d", payload.ChatID, chatID)
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-			return
+			return nil, &webhookFailure{http.StatusForbidden, models.HookErrorCodeForbidden, "chat_id does not match the webhook chat"}
+		}
+
+		var id *string
+		if payload.ID != "" {
+			id = &payload.ID
 		}
 
 		log.Default().Printf("Processing new event webhook: %+v", payload)
-		if _, err = c.Service.CreateEvent(payload.ChatID, &threadID, &payload.ID, payload.UserID, payload.UserName, payload.Name, payload.Location, payload.StartsAt, false); err != nil {
+		var event *models.Event
+		if event, err = s.CreateEvent(payload.ChatID, &threadID, id, payload.UserID, payload.UserName, payload.Name, payload.Location, payload.StartsAt, payload.Locked, false); err != nil {
 			log.Default().Println("failed to add event from webhook:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add event"})
-			return
+			return nil, serviceFailure(err, "failed to add event")
+		}
+
+		result = models.HookNewEventPayload{
+			ID:        event.ID,
+			ChatID:    event.ChatID,
+			UserID:    event.UserID,
+			UserName:  event.UserName,
+			Name:      event.Name,
+			MessageID: event.MessageID,
+			Location:  event.Location,
+			StartsAt:  event.StartsAt,
+			Locked:    event.Locked,
+			CreatedAt: time.Now(),
 		}
 	case models.HookWebhookTypeDeleteEvent:
 		var payload *models.HookDeleteEventPayload
-		if payload, err = Cast[models.HookDeleteEventPayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookDeleteEventPayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid delete_event data"}
 		}
 
 		log.Default().Printf("Processing delete event webhook: %+v", payload)
 
-		if err = c.Service.DeleteEvent(payload.EventID, payload.UserID, payload.UserName); err != nil {
+		if err = s.DeleteEvent(payload.EventID, payload.UserID, payload.UserName); err != nil {
 			log.Default().Println("failed to delete event from webhook:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
-			return
+			return nil, serviceFailure(err, "failed to delete event")
 		}
+
+		payload.DeletedAt = time.Now().Format("2006-01-02 15:04:05")
+		result = payload
 	case models.HookWebhookTypeNewGame:
 		var payload *models.HookNewGamePayload
-		if payload, err = Cast[models.HookNewGamePayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookNewGamePayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid new_game data"}
+		}
+
+		var id *string
+		if payload.ID != "" {
+			id = &payload.ID
 		}
 
 		log.Default().Printf("Processing new game webhook: %+v", payload)
-		if _, _, err = c.Service.CreateGame(payload.EventID, &payload.ID, payload.UserID, payload.Name, &payload.MaxPlayers, payload.BGG.URL); err != nil {
+		var event *models.Event
+		var game *models.BoardGame
+		if event, game, err = s.CreateGame(payload.EventID, id, payload.UserID, payload.Name, &payload.MaxPlayers, payload.BGG.URL, payload.Slot); err != nil {
 			log.Default().Println("failed to add game from webhook:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add game"})
-			return
+			return nil, serviceFailure(err, "failed to add game")
+		}
+
+		if game == nil {
+			return nil, &webhookFailure{http.StatusInternalServerError, models.HookErrorCodeOperationFailed, "game not found after creation"}
+		}
+
+		result = models.HookNewGamePayload{
+			ID:         game.UUID,
+			EventID:    event.ID,
+			UserID:     payload.UserID,
+			UserName:   payload.UserName,
+			Name:       game.Name,
+			MaxPlayers: int(game.MaxPlayers),
+			Slot:       game.Slot,
+			MessageID:  game.MessageID,
+			BGG: models.HookBGGInfo{
+				IsSet:    game.BggID != nil,
+				ID:       game.BggID,
+				Name:     game.BggName,
+				URL:      game.BggUrl,
+				ImageURL: game.BggImageUrl,
+			},
+			CreatedAt: time.Now(),
 		}
 	case models.HookWebhookTypeDeleteGame:
 		var payload *models.HookDeleteGamePayload
-		if payload, err = Cast[models.HookDeleteGamePayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookDeleteGamePayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid delete_game data"}
 		}
 
 		log.Default().Printf("Processing delete game webhook: %+v", payload)
 
-		if _, _, err = c.Service.DeleteGame(payload.EventID, payload.ID, payload.UserID, payload.UserName); err != nil {
+		if _, failure := eventGameID(s.DB, payload.EventID, payload.ID); failure != nil {
+			return nil, failure
+		}
+
+		var game *models.BoardGame
+		if _, game, err = s.DeleteGame(payload.EventID, payload.ID, payload.UserID, payload.UserName); err != nil {
 			log.Default().Println("failed to delete game from webhook:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete game"})
-			return
+			return nil, serviceFailure(err, "failed to delete game")
+		}
+
+		if game == nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidGame, "invalid game ID"}
 		}
+
+		payload.Name = game.Name
+		payload.DeletedAt = time.Now().Format("2006-01-02 15:04:05")
+		result = payload
 	case models.HookWebhookTypeUpdateGame:
 		var payload *models.HookUpdateGamePayload
-		if payload, err = Cast[models.HookUpdateGamePayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookUpdateGamePayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid update_game data"}
 		}
 
 		log.Default().Printf("Processing update game webhook: %+v", payload)
 
-		gameID, err := c.DB.SelectGameIDByGameUUID(payload.ID)
-		if err != nil {
-			log.Default().Println("failed to get game ID from UUID in webhook:", err)
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update game"})
-			return
+		gameID, failure := eventGameID(s.DB, payload.EventID, payload.ID)
+		if failure != nil {
+			return nil, failure
 		}
 
 		unlink := ""
 		if payload.BGG.URL == nil {
 			unlink = "on"
 		}
-		if _, _, err = c.Service.UpdateGame(payload.EventID, gameID, payload.UserID, models.UpdateGameRequest{
+
+		var event *models.Event
+		var game *models.BoardGame
+		if event, game, err = s.UpdateGame(payload.EventID, gameID, payload.UserID, models.UpdateGameRequest{
 			MaxPlayers: &payload.MaxPlayers,
 			BggUrl:     payload.BGG.URL,
+			Slot:       payload.Slot,
 			UserID:     payload.UserID,
 			UserName:   payload.UserName,
 			Unlink:     unlink,
 		}); err != nil {
 			log.Default().Println("failed to update game from webhook:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update game"})
-			return
+			return nil, serviceFailure(err, "failed to update game")
 		}
+
+		result = models.NewHookUpdateGamePayload(event.ID, *game, payload.UserID, payload.UserName)
 	case models.HookWebhookTypeAddParticipant:
 		var payload *models.HookAddParticipantPayload
-		if payload, err = Cast[models.HookAddParticipantPayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookAddParticipantPayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid add_participant data"}
 		}
 
 		log.Default().Printf("Processing add participant webhook: %+v", payload)
 
-		gameID, err := c.DB.SelectGameIDByGameUUID(payload.GameID)
-		if err != nil {
-			log.Default().Println("failed to get game ID from UUID in webhook:", err)
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to add participant"})
-			return
+		gameID, failure := eventGameID(s.DB, payload.EventID, payload.GameID)
+		if failure != nil {
+			return nil, failure
 		}
 
-		if _, _, _, err = c.Service.AddPlayer(&payload.ID, payload.EventID, gameID, payload.UserID, payload.UserName, false); err != nil {
+		var id *string
+		if payload.ID != "" {
+			id = &payload.ID
+		}
+
+		var participantID string
+		if participantID, _, _, err = s.AddPlayer(id, payload.EventID, gameID, payload.UserID, payload.UserName, false); err != nil {
 			log.Default().Println("failed to add participant from webhook:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add participant"})
-			return
+			return nil, serviceFailure(err, "failed to add participant")
 		}
+
+		payload.ID = participantID
+		payload.AddedAt = time.Now()
+		result = payload
 	case models.HookWebhookTypeRemoveParticipant:
 		var payload *models.HookRemoveParticipantPayload
-		if payload, err = Cast[models.HookRemoveParticipantPayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookRemoveParticipantPayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid remove_participant data"}
 		}
 
 		log.Default().Printf("Processing remove participant webhook: %+v", payload)
 
-		if _, _, _, err = c.Service.DeletePlayer(payload.EventID, payload.UserID); err != nil {
+		// without a game every seat of the user is freed
+		var gameID *int64
+		if payload.GameID != "" {
+			id, failure := eventGameID(s.DB, payload.EventID, payload.GameID)
+			if failure != nil {
+				return nil, failure
+			}
+			gameID = &id
+		}
+
+		var participantID string
+		var game *models.BoardGame
+		if participantID, _, game, err = s.DeletePlayer(payload.EventID, payload.UserID, gameID); err != nil {
 			log.Default().Println("failed to remove participant from webhook:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove participant"})
-			return
+			return nil, serviceFailure(err, "failed to remove participant")
 		}
+
+		payload.ID = participantID
+		if game != nil {
+			payload.GameID = game.UUID
+		}
+		payload.RemovedAt = time.Now()
+		result = payload
 	case models.HookWebhookTypeSendMessage:
 		var payload *models.HookSendMessagePayload
-		if payload, err = Cast[models.HookSendMessagePayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookSendMessagePayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid send_message data"}
 		}
 
 		log.Default().Printf("Processing send message webhook: %+v", payload)
@@ -955,31 +1438,189 @@ func (c *Controller) ListenWebhook(ctx *gin.Context) {
 			name = fmt.Sprintf(" @%s", *payload.UserName)
 		}
 		msg := fmt.Sprintf("🤖[%s%s] %s", webhookID[0:3], name, payload.Message)
-		_, err = c.Bot.Send(telebot.ChatID(chatID), msg, &telebot.SendOptions{
+		var sent *telebot.Message
+		sent, err = c.Bot.Send(telebot.ChatID(chatID), msg, &telebot.SendOptions{
 			ParseMode: telebot.ModeHTML,
 			ThreadID:  int(threadID),
 		})
 		if err != nil {
 			log.Default().Println("failed to send message from webhook:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
-			return
+			return nil, &webhookFailure{http.StatusInternalServerError, models.HookErrorCodeOperationFailed, "failed to send message"}
+		}
+
+		sentAt := sent.Time()
+		payload.SentAt = &sentAt
+		payload.MessageID = utils.IntToPointer(sent.ID)
+		result = payload
+	case models.HookWebhookTypeImportChat:
+		var payload *models.ChatExport
+		if payload, err = Cast[models.ChatExport](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid import_chat data"}
+		}
+
+		if payload.Version != models.ChatExportVersion {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, fmt.Sprintf("unsupported export version %d", payload.Version)}
+		}
+
+		log.Default().Printf("Processing import chat webhook: %d events", len(payload.Events))
+
+		if err = s.ImportChat(chatID, payload); err != nil {
+			log.Default().Println("failed to import chat from webhook:", err)
+			return nil, serviceFailure(err, "failed to import chat")
+		}
+
+		result = models.HookImportChatResult{
+			Events:       len(payload.Events),
+			Games:        len(payload.Games),
+			Participants: len(payload.Participants),
+			ImportedAt:   time.Now(),
 		}
 	case models.HookWebhookTypeTestWebhook:
 		log.Default().Printf("Received test webhook for chat %d", chatID)
 		var payload *models.HookTestPayload
-		if payload, err = Cast[models.HookTestPayload](webhookEnvelope.Data); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
-			return
+		if payload, err = Cast[models.HookTestPayload](envelope.Data); err != nil {
+			return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid test data"}
 		}
 
 		log.Default().Printf("Test webhook payload: %s", payload.Message)
+		result = payload
 	default:
-		log.Default().Printf("Unhandled webhook type: %s", webhookEnvelope.Type)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unhandled webhook type"})
+		log.Default().Printf("Unhandled webhook type: %s", envelope.Type)
+		return nil, &webhookFailure{http.StatusBadRequest, models.HookErrorCodeUnsupportedType, fmt.Sprintf("unsupported webhook type %q", envelope.Type)}
+	}
+
+	return result, nil
+}
+
+// batchableWebhookTypes lists the operations accepted inside a batch. Creating
+// or deleting events and sending messages post to Telegram immediately, which
+// cannot be rolled back, so they must be sent on their own.
+var batchableWebhookTypes = map[models.HookWebhookType]bool{
+	models.HookWebhookTypeNewGame:           true,
+	models.HookWebhookTypeUpdateGame:        true,
+	models.HookWebhookTypeDeleteGame:        true,
+	models.HookWebhookTypeAddParticipant:    true,
+	models.HookWebhookTypeRemoveParticipant: true,
+}
+
+// listenBatch applies the operations of a batch envelope in order inside a
+// single transaction. The event messages are refreshed once at the end, and
+// the response reports the outcome of every operation.
+func (c *Controller) listenBatch(ctx *gin.Context, chatID, threadID int64, webhookID string, envelope models.HookWebhookEnvelope) {
+	payload, err := Cast[models.HookBatchPayload](envelope.Data)
+	if err != nil {
+		abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "invalid batch data")
+		return
+	}
+
+	if len(payload.Operations) == 0 {
+		abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeInvalidPayload, "batch has no operations")
+		return
+	}
+
+	if len(payload.Operations) > models.HookBatchMaxOperations {
+		abortWebhook(ctx, http.StatusBadRequest, models.HookErrorCodeInvalidPayload, fmt.Sprintf("batch exceeds %d operations", models.HookBatchMaxOperations))
+		return
+	}
+
+	results := make([]models.HookBatchOperationResult, len(payload.Operations))
+	for i, op := range payload.Operations {
+		results[i] = models.HookBatchOperationResult{
+			Index:  i,
+			Type:   op.Type,
+			Status: models.HookBatchOperationStatusSkipped,
+		}
+	}
+
+	log.Default().Printf("Processing batch webhook with %d operations for chat %d", len(payload.Operations), chatID)
+
+	// BGG is queried before the transaction is opened
+	queries := []BGGQuery{}
+	for _, op := range payload.Operations {
+		switch op.Type {
+		case models.HookWebhookTypeNewGame:
+			if game, castErr := Cast[models.HookNewGamePayload](op.Data); castErr == nil {
+				queries = append(queries, BGGQuery{URL: game.BGG.URL, Name: game.Name})
+			}
+		case models.HookWebhookTypeUpdateGame:
+			if game, castErr := Cast[models.HookUpdateGamePayload](op.Data); castErr == nil && game.BGG.URL != nil {
+				queries = append(queries, BGGQuery{URL: game.BGG.URL, Name: game.Name})
+			}
+		}
+	}
+
+	var failed *webhookFailure
+	failedIndex := -1
+	err = c.Service.RunBatch(queries, func(tx *Service) error {
+		for i, op := range payload.Operations {
+			var data any
+			failure := checkBatchOperation(tx.DB, chatID, op)
+			if failure == nil {
+				data, failure = c.applyWebhook(tx, chatID, threadID, webhookID, op)
+			}
+
+			if failure != nil {
+				results[i].Status = models.HookBatchOperationStatusFailed
+				results[i].Error = &models.HookError{Code: failure.code, Message: failure.message}
+				failed = failure
+				failedIndex = i
+				return failure
+			}
+
+			results[i].Status = models.HookBatchOperationStatusApplied
+			results[i].Data = data
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Default().Println("batch webhook rolled back:", err)
+
+		message := "failed to commit batch"
+		if failed == nil {
+			failed = &webhookFailure{http.StatusInternalServerError, models.HookErrorCodeOperationFailed, message}
+		} else {
+			message = fmt.Sprintf("operation %d failed: %s", failedIndex, failed.message)
+		}
+
+		for i := range results {
+			if results[i].Status == models.HookBatchOperationStatusApplied {
+				results[i].Status = models.HookBatchOperationStatusRolledBack
+				results[i].Data = nil
+			}
+		}
+
+		ctx.AbortWithStatusJSON(failed.status, models.HookWebhookResponse{
+			Type:    models.HookWebhookTypeBatch,
+			Message: "Batch rolled back.",
+			Data:    models.HookBatchResultPayload{Results: results},
+			Error:   &models.HookError{Code: failed.code, Message: message},
+		})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook received."})
+	ctx.JSON(http.StatusOK, models.HookWebhookResponse{
+		Type:    models.HookWebhookTypeBatch,
+		Message: "Webhook received.",
+		Data:    models.HookBatchResultPayload{Results: results},
+	})
+}
+
+// checkBatchOperation rejects operations that cannot run inside a batch and
+// those targeting an event of another chat.
+func checkBatchOperation(db database.DatabaseService, chatID int64, op models.HookWebhookEnvelope) *webhookFailure {
+	if !batchableWebhookTypes[op.Type] {
+		return &webhookFailure{http.StatusBadRequest, models.HookErrorCodeUnsupportedType, fmt.Sprintf("webhook type %q is not allowed in a batch", op.Type)}
+	}
+
+	if data, ok := op.Data.(map[string]any); ok {
+		if eventID, ok := data["event_id"].(string); ok {
+			return checkEventChat(db, eventID, chatID)
+		}
+	}
+
+	return nil
 }
 
 func Cast[T any](data any) (*T, error) {
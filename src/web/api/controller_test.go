@@ -0,0 +1,272 @@
+package api
+
+import (
+	"boardgame-night-bot/src/database"
+	"boardgame-night-bot/src/hooks"
+	"boardgame-night-bot/src/mocks"
+	"boardgame-night-bot/src/models"
+	"boardgame-night-bot/src/web/webapp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testBotToken = "123456:test-token"
+
+// initData signs the mini app init data of userID as Telegram does.
+func initData(userID int64) string {
+	values := url.Values{}
+	values.Set("auth_date", strconv.FormatInt(time.Now().Unix(), 10))
+	values.Set("user", fmt.Sprintf(`{"id":%d,"first_name":"user%d"}`, userID, userID))
+	check := fmt.Sprintf("auth_date=%s\nuser=%s", values.Get("auth_date"), values.Get("user"))
+	values.Set("hash", webapp.Sign(check, testBotToken))
+	return values.Encode()
+}
+
+func serveMiniApp(c *Controller, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetHTMLTemplate(template.Must(template.New("error").Parse("{{ .Error }}")))
+	c.Router = router.Group("/")
+	c.InjectRoute()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func miniAppRequest(c *Controller, method, path, body, initData string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if initData != "" {
+		req.Header.Set(webapp.InitDataHeader, initData)
+	}
+	return serveMiniApp(c, req)
+}
+
+func TestDeleteGameWebhookUnknownGame(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	db.SelectGameIDByGameUUIDFunc = func(gameUUID string) (int64, error) {
+		return 0, database.ErrNoRows
+	}
+	db.DeleteBoardGameByIDFunc = func(ID string) error {
+		t.Error("expected no game to be deleted")
+		return nil
+	}
+
+	c := &Controller{Service: service}
+	_, failure := c.applyWebhook(service, -100, 0, "webhook", models.HookWebhookEnvelope{
+		Type: models.HookWebhookTypeDeleteGame,
+		Data: models.HookDeleteGamePayload{EventID: "event-1", ID: "missing", UserName: "host"},
+	})
+	if failure == nil || failure.status != http.StatusBadRequest || failure.code != models.HookErrorCodeInvalidGame {
+		t.Fatalf("expected an invalid_game failure, got %+v", failure)
+	}
+}
+
+func TestAddParticipantWebhookGameOfAnotherEvent(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	db.SelectGameIDByGameUUIDFunc = func(gameUUID string) (int64, error) {
+		return 7, nil
+	}
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{ID: eventID, ChatID: 12345, UserID: 1, BoardGames: []models.BoardGame{{ID: 1, UUID: "game-1"}}}, nil
+	}
+	db.InsertParticipantFunc = func(id *string, eventID string, boardgameID, userID int64, userName string, isTelegramUsername bool) (string, error) {
+		t.Error("expected no participant to be added")
+		return "", nil
+	}
+
+	c := &Controller{Service: service}
+	_, failure := c.applyWebhook(service, 12345, 0, "webhook", models.HookWebhookEnvelope{
+		Type: models.HookWebhookTypeAddParticipant,
+		Data: models.HookAddParticipantPayload{EventID: "event-1", GameID: "game-7", UserID: 2, UserName: "player"},
+	})
+	if failure == nil || failure.status != http.StatusNotFound || failure.code != models.HookErrorCodeGameNotFound {
+		t.Fatalf("expected a game_not_found failure, got %+v", failure)
+	}
+}
+
+func TestUpdateGameWebhookLockedEvent(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	db.SelectGameIDByGameUUIDFunc = func(gameUUID string) (int64, error) {
+		return 1, nil
+	}
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{ID: eventID, ChatID: 12345, UserID: 1, Locked: true, BoardGames: []models.BoardGame{{ID: 1, UUID: "game-1"}}}, nil
+	}
+
+	c := &Controller{Service: service}
+	_, failure := c.applyWebhook(service, 12345, 0, "webhook", models.HookWebhookEnvelope{
+		Type: models.HookWebhookTypeUpdateGame,
+		Data: models.HookUpdateGamePayload{EventID: "event-1", ID: "game-1", UserID: 2, UserName: "player", MaxPlayers: 4},
+	})
+	if failure == nil || failure.status != http.StatusConflict || failure.code != models.HookErrorCodeEventLocked {
+		t.Fatalf("expected an event_locked failure, got %+v", failure)
+	}
+}
+
+func TestKickParticipantUsesVerifiedHost(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	messageID := int64(11111)
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:        eventID,
+			ChatID:    12345,
+			UserID:    1,
+			MessageID: &messageID,
+			Tentative: []models.Participant{{UserID: 3, UserName: "player"}},
+		}, nil
+	}
+	service.Hook = &recordingNotifier{}
+	removed := false
+	db.RemoveRSVPFunc = func(eventID string, userID int64) error {
+		removed = true
+		return nil
+	}
+
+	c := &Controller{Service: service, BotToken: testBotToken}
+	eventID := "2f1a4b9e-8d3c-4e5f-9a6b-7c8d9e0f1a2b"
+	path := fmt.Sprintf("/events/%s/kick", eventID)
+	// the body used to name the host, it must not be trusted anymore
+	body := `{"user_id":1,"user_name":"owner","participant_id":3}`
+
+	if w := miniAppRequest(c, http.MethodPost, path, body, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a request without init data to be rejected, got %d", w.Code)
+	}
+	forged := strings.Replace(initData(2), "%22id%22%3A2", "%22id%22%3A1", 1)
+	if w := miniAppRequest(c, http.MethodPost, path, body, forged); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected forged init data to be rejected, got %d", w.Code)
+	}
+	if w := miniAppRequest(c, http.MethodPost, path, body, initData(2)); w.Code != http.StatusForbidden {
+		t.Errorf("expected a user who is not a host to be rejected, got %d", w.Code)
+	}
+	if removed {
+		t.Fatal("expected nobody to be kicked")
+	}
+
+	if w := miniAppRequest(c, http.MethodPost, path, body, initData(1)); w.Code != http.StatusOK || !removed {
+		t.Errorf("expected the owner to kick the participant, got %d", w.Code)
+	}
+}
+
+func TestIsHostOnlyAnswersForTheUser(t *testing.T) {
+	c := &Controller{Service: BeforeEach(), BotToken: testBotToken}
+	path := "/events/2f1a4b9e-8d3c-4e5f-9a6b-7c8d9e0f1a2b/hosts/1"
+
+	if w := miniAppRequest(c, http.MethodGet, path, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a request without init data to be rejected, got %d", w.Code)
+	}
+	if w := miniAppRequest(c, http.MethodGet, path, "", initData(2)); w.Code != http.StatusForbidden {
+		t.Errorf("expected a question about another user to be rejected, got %d", w.Code)
+	}
+}
+
+func TestCloneEventUsesVerifiedHost(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{ID: eventID, ChatID: 12345, UserID: 1, Name: "Game night"}, nil
+	}
+	db.WithTransactionFunc = func(fn func(db database.DatabaseService) error) error {
+		t.Error("expected the event not to be cloned")
+		return nil
+	}
+
+	c := &Controller{Service: service, BotToken: testBotToken, LanguageBundle: service.LanguageBundle}
+	clone := func(initData string) string {
+		form := url.Values{}
+		form.Set("starts_at", "2030-01-02T20:00")
+		// the form used to name the host, it must not be trusted anymore
+		form.Set("user_id", "1")
+		form.Set("user_name", "owner")
+		if initData != "" {
+			form.Set(webapp.InitDataField, initData)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/events/2f1a4b9e-8d3c-4e5f-9a6b-7c8d9e0f1a2b/clone", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serveMiniApp(c, req).Body.String()
+	}
+
+	if body := clone(""); body != "Open the event from Telegram to duplicate it" {
+		t.Errorf("expected a form without init data to be rejected, got %q", body)
+	}
+	if body := clone(initData(2)); body != "Only the hosts can duplicate the event" {
+		t.Errorf("expected a user who is not a host to be rejected, got %q", body)
+	}
+}
+
+func TestVerifyWebhookRequiresSignatureV2(t *testing.T) {
+	db := database.NewDatabase(t.TempDir())
+	t.Cleanup(db.Close)
+	db.CreateTables()
+	for _, migrate := range []func(){
+		db.MigrateToV1, db.MigrateToV2, db.MigrateToV3, db.MigrateToV4, db.MigrateToV5, db.MigrateToV6,
+		db.MigrateToV7, db.MigrateToV8, db.MigrateToV9, db.MigrateToV10, db.MigrateToV11, db.MigrateToV12,
+		db.MigrateToV13, db.MigrateToV14, db.MigrateToV15, db.MigrateToV16,
+	} {
+		migrate()
+	}
+	secret := "webhook-secret"
+	_, webhookID, err := db.InsertWebhook(-100, nil, "https://example.com", secret, models.WebhookFormatDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	c := &Controller{DB: db}
+	router.POST("/webhooks/:webhook_id", c.VerifyWebhook(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	send := func(key string, sign func(date, hash string) (string, string)) int {
+		body := `{"type":"new_game"}`
+		hash := sha256.Sum256([]byte(body))
+		hashHex := hex.EncodeToString(hash[:])
+		date := time.Now().UTC().Format(time.RFC1123)
+
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/"+*webhookID, strings.NewReader(body))
+		req.Header.Set("x-ms-date", date)
+		req.Header.Set("x-ms-content-sha256", hashHex)
+		req.Header.Set(hooks.IdempotencyKeyHeader, key)
+		header, signature := sign(date, hashHex)
+		req.Header.Set(header, signature)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// the original signature does not cover the key, a captured request
+	// could be replayed under a fresh one
+	legacy := func(date, hash string) (string, string) {
+		return hooks.SignatureHeader, hooks.ComputeHMACBase64(hooks.LegacyStringToSign(date, hash), []byte(secret))
+	}
+	if code := send("key-1", legacy); code != http.StatusUnauthorized {
+		t.Errorf("expected the original signature alone to be rejected, got %d", code)
+	}
+
+	v2 := func(date, hash string) (string, string) {
+		return hooks.SignatureV2Header, hooks.ComputeHMACBase64(hooks.StringToSign(date, hash, "key-2"), []byte(secret))
+	}
+	if code := send("key-2", v2); code != http.StatusOK {
+		t.Errorf("expected the v2 signature to be accepted, got %d", code)
+	}
+	if code := send("key-3", v2); code != http.StatusUnauthorized {
+		t.Errorf("expected the v2 signature of another key to be rejected, got %d", code)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"boardgame-night-bot/src/models"
+	"boardgame-night-bot/src/telegram_interface"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// RenderFunc builds the current content of a message. It is called right
+// before editing, so the latest state is always the one sent.
+type RenderFunc func() (string, *telebot.ReplyMarkup, error)
+
+type refreshKey struct {
+	chatID    int64
+	messageID int
+}
+
+type pendingRefresh struct {
+	render RenderFunc
+	dirty  bool
+}
+
+// MessageRefresher coalesces the edits of each Telegram message. Refreshes
+// requested within window are merged into a single edit, and a 429 response
+// postpones the edit by the retry_after given by Telegram. A refresh requested
+// while an edit is in flight is rendered again afterwards, so the last state
+// always reaches the chat.
+type MessageRefresher struct {
+	bot     telegram_interface.TelegramService
+	window  time.Duration
+	pending map[refreshKey]*pendingRefresh
+	mu      sync.Mutex
+}
+
+func NewMessageRefresher(bot telegram_interface.TelegramService, window time.Duration) *MessageRefresher {
+	return &MessageRefresher{
+		bot:     bot,
+		window:  window,
+		pending: make(map[refreshKey]*pendingRefresh),
+	}
+}
+
+// Schedule requests a refresh of the message, replacing any render function
+// still waiting for the same message.
+func (r *MessageRefresher) Schedule(chatID int64, messageID int, render RenderFunc) {
+	key := refreshKey{chatID: chatID, messageID: messageID}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.pending[key]; ok {
+		p.render = render
+		p.dirty = true
+		return
+	}
+
+	r.pending[key] = &pendingRefresh{render: render}
+	time.AfterFunc(r.window, func() { r.flush(key) })
+}
+
+func (r *MessageRefresher) flush(key refreshKey) {
+	r.mu.Lock()
+	p := r.pending[key]
+	render := p.render
+	p.dirty = false
+	r.mu.Unlock()
+
+	delay := r.window
+	retry := false
+
+	err := r.edit(key, render)
+	var flood telebot.FloodError
+	if errors.As(err, &flood) {
+		retry = true
+		if retryAfter := time.Duration(flood.RetryAfter) * time.Second; retryAfter > delay {
+			delay = retryAfter
+		}
+		log.Default().Printf("rate limited editing message %d in chat %d, retrying in %s", key.messageID, key.chatID, delay)
+	} else if err != nil {
+		log.Default().Println("failed to edit message:", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if retry || p.dirty {
+		time.AfterFunc(delay, func() { r.flush(key) })
+		return
+	}
+
+	delete(r.pending, key)
+}
+
+func (r *MessageRefresher) edit(key refreshKey, render RenderFunc) error {
+	body, markup, err := render()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.bot.Edit(&telebot.Message{
+		ID: key.messageID,
+		Chat: &telebot.Chat{
+			ID: key.chatID,
+		},
+	}, body, markup, telebot.NoPreview)
+	var flood telebot.FloodError
+	if err != nil && !errors.As(err, &flood) && strings.Contains(err.Error(), models.MessageUnchangedErrorMessage) {
+		// Content is already up-to-date; not a real error.
+		return nil
+	}
+
+	return err
+}
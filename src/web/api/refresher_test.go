@@ -0,0 +1,121 @@
+package api
+
+import (
+	"boardgame-night-bot/src/mocks"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+const testRefreshWindow = 20 * time.Millisecond
+
+type recordedEdits struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (r *recordedEdits) add(body string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+}
+
+func (r *recordedEdits) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.bodies...)
+}
+
+func renderText(body string) RenderFunc {
+	return func() (string, *telebot.ReplyMarkup, error) {
+		return body, nil, nil
+	}
+}
+
+func TestMessageRefresherCoalescesBurst(t *testing.T) {
+	telegram := mocks.NewMockTelegramService()
+	edits := &recordedEdits{}
+	telegram.EditFunc = func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		edits.add(what.(string))
+		return &telebot.Message{ID: 1}, nil
+	}
+
+	refresher := NewMessageRefresher(telegram, testRefreshWindow)
+	for _, body := range []string{"v1", "v2", "v3"} {
+		refresher.Schedule(12345, 1, renderText(body))
+	}
+
+	time.Sleep(5 * testRefreshWindow)
+
+	if got := edits.get(); len(got) != 1 || got[0] != "v3" {
+		t.Fatalf("Expected a single edit with the last state, got %v", got)
+	}
+}
+
+func TestMessageRefresherRetriesAfterFloodError(t *testing.T) {
+	telegram := mocks.NewMockTelegramService()
+	edits := &recordedEdits{}
+	attempts := 0
+	telegram.EditFunc = func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, telebot.FloodError{RetryAfter: 0}
+		}
+		edits.add(what.(string))
+		return &telebot.Message{ID: 1}, nil
+	}
+
+	refresher := NewMessageRefresher(telegram, testRefreshWindow)
+	refresher.Schedule(12345, 1, renderText("v1"))
+
+	time.Sleep(5 * testRefreshWindow)
+
+	if got := edits.get(); len(got) != 1 || got[0] != "v1" {
+		t.Fatalf("Expected the edit to be retried after the flood error, got %v", got)
+	}
+}
+
+func TestMessageRefresherRendersChangesDuringEdit(t *testing.T) {
+	telegram := mocks.NewMockTelegramService()
+	edits := &recordedEdits{}
+	refresher := NewMessageRefresher(telegram, testRefreshWindow)
+
+	telegram.EditFunc = func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		body := what.(string)
+		if body == "v1" {
+			// a new change arrives while the first edit is in flight
+			refresher.Schedule(12345, 1, renderText("v2"))
+		}
+		edits.add(body)
+		return &telebot.Message{ID: 1}, nil
+	}
+
+	refresher.Schedule(12345, 1, renderText("v1"))
+
+	time.Sleep(6 * testRefreshWindow)
+
+	if got := edits.get(); len(got) != 2 || got[1] != "v2" {
+		t.Fatalf("Expected the change made during the edit to be rendered, got %v", got)
+	}
+}
+
+func TestMessageRefresherKeepsMessagesApart(t *testing.T) {
+	telegram := mocks.NewMockTelegramService()
+	edits := &recordedEdits{}
+	telegram.EditFunc = func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		edits.add(what.(string))
+		return &telebot.Message{ID: 1}, nil
+	}
+
+	refresher := NewMessageRefresher(telegram, testRefreshWindow)
+	refresher.Schedule(12345, 1, renderText("first"))
+	refresher.Schedule(12345, 2, renderText("second"))
+
+	time.Sleep(5 * testRefreshWindow)
+
+	if got := edits.get(); len(got) != 2 {
+		t.Fatalf("Expected one edit per message, got %v", got)
+	}
+}
@@ -21,27 +21,250 @@ import (
 	"gopkg.in/telebot.v3"
 )
 
+// ErrNotEventOwner is returned when an action is reserved to the event owner.
+var ErrNotEventOwner = errors.New("only the event owner can perform this action")
+
+var (
+	// ErrTooManyGuests is returned when a participant already brings MaxGuests.
+	ErrTooManyGuests = errors.New("too many guests")
+	// ErrNoGuests is returned when removing a guest from a participant without any.
+	ErrNoGuests = errors.New("no guests to remove")
+	// ErrRSVPClosed is returned when joining an event whose RSVP deadline passed.
+	ErrRSVPClosed = errors.New("the rsvp deadline has passed")
+	// ErrNotCoHost is returned when removing a user who is not a co-host.
+	ErrNotCoHost = errors.New("the user is not a co-host")
+	// ErrNotHost is returned when an action is reserved to the hosts.
+	ErrNotHost = errors.New("only the hosts can perform this action")
+	// ErrEventLocked is returned when a user who is not a host changes the
+	// games of a locked event or deletes it.
+	ErrEventLocked = errors.New("the event is locked")
+	// ErrInvalidGame is returned when the game is not one of the event games.
+	ErrInvalidGame = errors.New("invalid game ID")
+	// ErrEventFinished is returned when changing an event that is over.
+	ErrEventFinished = errors.New("the event is over")
+	// ErrNotChatAdmin is returned when an action is reserved to the chat admins.
+	ErrNotChatAdmin = errors.New("only the chat admins can perform this action")
+	// ErrAlreadyImported is returned when an import holds an event or a game
+	// that already exists.
+	ErrAlreadyImported = errors.New("the event already exists")
+)
+
+// WebhookNotifier dispatches outbound webhooks to the chat subscribers.
+type WebhookNotifier interface {
+	SendAllWebhookAsync(ctx context.Context, chatID int64, payload models.HookWebhookEnvelope)
+}
+
 type Service struct {
 	DB             database.DatabaseService
 	BGG            bgg.BGGService
 	Bot            telegram_interface.TelegramService
+	Hook           WebhookNotifier
 	LanguageBundle *i18n.Bundle
 	Url            models.WebUrl
-	gameUpdateMu   sync.Map // map[int64]*sync.Mutex — serialises concurrent updates per game ID
+	// Refresher, when set, coalesces the edits of event messages; otherwise
+	// each change edits the message immediately.
+	Refresher *MessageRefresher
+	// Retention is how long the data of past events is kept by
+	// ApplyRetention.
+	Retention    models.RetentionPolicy
+	gameUpdateMu sync.Map // map[int64]*sync.Mutex — serialises concurrent updates per game ID
+	// root is the Service a batch was started from: it owns the per-game
+	// mutexes shared with the Service bound to the transaction.
+	root  *Service
+	batch *telegramBatch
+}
+
+// telegramBatch collects the Telegram side effects requested while a batch is
+// running, so that they are applied once and only after the batch commits.
+type telegramBatch struct {
+	events  []string
+	seen    map[string]bool
+	effects []func()
+	// bgg holds the BGG metadata of the games of the batch, by bggLookupKey.
+	bgg map[string]bggLookup
+}
+
+// BGGQuery is a game whose BGG metadata a batch needs: its BGG URL, or its
+// name when the URL is not set.
+type BGGQuery struct {
+	URL  *string
+	Name string
+}
+
+// bggLookup is the BGG metadata of a game, fetched before a batch opens its
+// transaction so that no network call holds the transaction open.
+type bggLookup struct {
+	id   *int64
+	info models.BggInfo
+	err  error
+}
+
+func bggLookupKey(bggUrl *string, name string) string {
+	if bggUrl != nil && *bggUrl != "" {
+		return "url|" + *bggUrl
+	}
+	return "name|" + name
+}
+
+// RunBatch applies fn to a Service bound to a single database transaction.
+// The BGG metadata of queries is fetched first, outside of the transaction.
+// Event message refreshes are coalesced and, together with any notification,
+// sent only once the transaction has been committed; nothing reaches Telegram
+// when fn fails.
+func (s *Service) RunBatch(queries []BGGQuery, fn func(tx *Service) error) error {
+	batch := &telegramBatch{seen: map[string]bool{}, bgg: map[string]bggLookup{}}
+	for _, q := range queries {
+		key := bggLookupKey(q.URL, q.Name)
+		if _, ok := batch.bgg[key]; ok {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		id, info, err := s.fetchBGGInfo(ctx, q.URL, q.Name)
+		cancel()
+		batch.bgg[key] = bggLookup{id: id, info: info, err: err}
+	}
+
+	root := s
+	if s.root != nil {
+		root = s.root
+	}
+
+	if err := s.DB.WithTransaction(func(db database.DatabaseService) error {
+		return fn(&Service{
+			DB:             db,
+			BGG:            s.BGG,
+			Bot:            s.Bot,
+			Hook:           s.Hook,
+			LanguageBundle: s.LanguageBundle,
+			Url:            s.Url,
+			Refresher:      s.Refresher,
+			Retention:      s.Retention,
+			root:           root,
+			batch:          batch,
+		})
+	}); err != nil {
+		return err
+	}
+
+	for _, effect := range batch.effects {
+		effect()
+	}
+
+	for _, eventID := range batch.events {
+		if _, err := s.updateTelegram(eventID); err != nil {
+			log.Default().Println("failed to update telegram", err)
+		}
+	}
+
+	return nil
+}
+
+// notify sends an outbound webhook, deferring it until commit inside a batch.
+func (s *Service) notify(chatID int64, hookType models.HookWebhookType, data any) {
+	if s.Hook == nil {
+		return
+	}
+
+	s.afterCommit(func() {
+		s.Hook.SendAllWebhookAsync(context.Background(), chatID, models.HookWebhookEnvelope{
+			Type: hookType,
+			Data: data,
+		})
+	})
+}
+
+// notifyEventUpdated sends the snapshot of an event whose fields changed.
+func (s *Service) notifyEventUpdated(event *models.Event) {
+	s.notify(event.ChatID, models.HookWebhookTypeUpdateEvent, models.HookUpdateEventPayload{
+		ID:           event.ID,
+		ChatID:       event.ChatID,
+		UserID:       event.UserID,
+		UserName:     event.UserName,
+		Name:         event.Name,
+		MessageID:    event.MessageID,
+		Location:     event.Location,
+		StartsAt:     event.StartsAt,
+		Locked:       event.Locked,
+		RSVPDeadline: event.RSVPDeadline,
+		CoHosts:      event.CoHosts,
+		UpdatedAt:    time.Now(),
+	})
+}
+
+// notifyWaitlist reports the participants who entered or left a queue between
+// the two snapshots of the event.
+func (s *Service) notifyWaitlist(before, after *models.Event) {
+	if before == nil || after == nil {
+		return
+	}
+
+	for _, change := range models.WaitlistChanges(before, after, time.Now()) {
+		s.notify(after.ChatID, models.HookWebhookTypeUpdateWaitlist, change)
+
+		if change.Status == models.HookWaitlistStatusPromoted && s.DB.GetNotificationPreferences(change.UserID).Waitlist {
+			s.sendDirect(change.UserID, s.promotionMessage(after, change.GameID))
+		}
+	}
+}
+
+// promotionMessage tells a participant that they left the waitlist of a game.
+func (s *Service) promotionMessage(event *models.Event, gameUUID string) string {
+	localizer := s.Localizer(&event.ChatID)
+	for _, bg := range event.BoardGames {
+		if bg.UUID == gameUUID && bg.Name != models.PLAYER_COUNTER {
+			return localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{ID: "WaitlistPromotedGame"},
+				TemplateData: map[string]string{
+					"Game":  bg.Name,
+					"Event": event.Name,
+				},
+			})
+		}
+	}
+
+	return localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{ID: "WaitlistPromotedEvent"},
+		TemplateData: map[string]string{
+			"Event": event.Name,
+		},
+	})
+}
+
+// sendDirect sends a private message to the user, deferring it until commit
+// inside a batch. Users who never started the bot cannot be reached, so a
+// failure is only logged.
+func (s *Service) sendDirect(userID int64, message string) {
+	s.afterCommit(func() {
+		if _, err := s.Bot.Send(&telebot.User{ID: userID}, message, telebot.ModeHTML, telebot.NoPreview); err != nil {
+			log.Default().Printf("failed to send direct message to user %d: %v", userID, err)
+		}
+	})
+}
+
+// afterCommit runs effect immediately, or once the running batch commits.
+func (s *Service) afterCommit(effect func()) {
+	if s.batch == nil {
+		effect()
+		return
+	}
+
+	s.batch.effects = append(s.batch.effects, effect)
 }
 
-func NewService(db database.DatabaseService, bgg bgg.BGGService, bot telegram_interface.TelegramService, languageBundle *i18n.Bundle, url models.WebUrl) *Service {
+func NewService(db database.DatabaseService, bgg bgg.BGGService, bot telegram_interface.TelegramService, hook WebhookNotifier, languageBundle *i18n.Bundle, url models.WebUrl) *Service {
 	return &Service{
-		DB:  db,
-		BGG: bgg,
-		Bot: bot,
+		DB:   db,
+		BGG:  bgg,
+		Bot:  bot,
+		Hook: hook,
 
 		LanguageBundle: languageBundle,
 		Url:            url,
 	}
 }
 
-func (s *Service) CreateEvent(chatID int64, threadID *int64, id *string, userID int64, userName, name string, location *string, startsAt *time.Time, allowGeneralJoin bool) (*models.Event, error) {
+func (s *Service) CreateEvent(chatID int64, threadID *int64, id *string, userID int64, userName, name string, location *string, startsAt *time.Time, locked, allowGeneralJoin bool) (*models.Event, error) {
 	var err error
 	fullText := name
 	log.Default().Println("Full text for parsing:", fullText)
@@ -49,11 +272,19 @@ func (s *Service) CreateEvent(chatID int64, threadID *int64, id *string, userID
 	var eventID string
 	log.Default().Printf("Creating event: %s by user: %s (%d) in chat: %d", name, userName, userID, chatID)
 
-	if eventID, err = s.DB.InsertEventWithOptionalGame(id, chatID, userID, userName, name, location, startsAt, allowGeneralJoin); err != nil {
+	if eventID, err = s.DB.InsertEventWithOptionalGame(id, chatID, userID, userName, name, location, startsAt, locked, allowGeneralJoin); err != nil {
 		log.Default().Println("failed to create event:", err)
 		return nil, fmt.Errorf("failed to create event: %w", err)
 	}
 	log.Default().Printf("Event created with id: %s", eventID)
+
+	return s.publishEvent(eventID, chatID, threadID)
+}
+
+// publishEvent posts the message of a new event in the chat, in its own topic
+// when the chat has event topics, and pins it when the chat asks for it.
+func (s *Service) publishEvent(eventID string, chatID int64, threadID *int64) (*models.Event, error) {
+	var err error
 	var event *models.Event
 
 	if event, err = s.DB.SelectEventByEventID(eventID); err != nil {
@@ -70,7 +301,16 @@ func (s *Service) CreateEvent(chatID int64, threadID *int64, id *string, userID
 	opts := &telebot.SendOptions{
 		ParseMode: telebot.ModeHTML,
 	}
-	if threadID != nil {
+
+	topicFailed := false
+	if s.DB.GetEventTopics(chatID) {
+		event.TopicID = s.createEventTopic(event)
+		topicFailed = event.TopicID == nil
+	}
+
+	if event.TopicID != nil {
+		opts.ThreadID = int(*event.TopicID)
+	} else if threadID != nil {
 		opts.ReplyTo = &telebot.Message{
 			ID: int(*threadID),
 		}
@@ -89,9 +329,334 @@ func (s *Service) CreateEvent(chatID int64, threadID *int64, id *string, userID
 
 	event.MessageID = utils.IntToPointer(responseMsg.ID)
 
+	if topicFailed {
+		notice := s.Localizer(&chatID).MustLocalizeMessage(&i18n.Message{ID: "TopicNotAllowed"})
+		if _, err = s.Bot.Send(to, notice, &telebot.SendOptions{ReplyTo: &telebot.Message{ID: responseMsg.ID}}); err != nil {
+			log.Default().Println("failed to send topic notice:", err)
+		}
+	}
+
+	if s.DB.GetAutoPin(chatID) {
+		event.Pinned = s.pinEvent(event, &telebot.Message{ID: responseMsg.ID, Chat: to})
+	}
+
+	return event, nil
+}
+
+// CloneEvent copies an event into a new one of the same chat starting at
+// startsAt: its name, location, lock and games with their BGG links and max
+// players, plus the participants and their guests when withParticipants is
+// set. Only the hosts of the event can clone it, and the user cloning it owns
+// the copy.
+func (s *Service) CloneEvent(eventID string, threadID *int64, userID int64, userName string, startsAt *time.Time, withParticipants bool) (*models.Event, error) {
+	var err error
+	var source *models.Event
+	if source, err = s.DB.SelectEventByEventID(eventID); err != nil || source.ID == "" {
+		log.Default().Println("failed to load event:", err)
+		return nil, fmt.Errorf("invalid event ID: %w", database.ErrNoRows)
+	}
+
+	if !s.IsHost(source, userID) {
+		log.Default().Printf("user %d is not a host of event %s", userID, eventID)
+		return nil, ErrNotHost
+	}
+
+	var cloneID string
+	if err = s.DB.WithTransaction(func(db database.DatabaseService) error {
+		var err error
+		if cloneID, err = db.InsertEventWithOptionalGame(nil, source.ChatID, userID, userName, source.Name, source.Location, startsAt, source.Locked, false); err != nil {
+			return err
+		}
+
+		for _, bg := range source.BoardGames {
+			var gameID int64
+			if gameID, _, err = db.InsertBoardGame(cloneID, nil, bg.Name, bg.Slot, int(bg.MaxPlayers), bg.BggID, bg.BggName, bg.BggUrl, bg.BggImageUrl); err != nil {
+				return err
+			}
+
+			if !withParticipants {
+				continue
+			}
+
+			for _, p := range bg.Participants {
+				if _, err = db.InsertParticipant(nil, cloneID, gameID, p.UserID, p.UserName, p.IsTelegramUsername); err != nil {
+					return err
+				}
+				if len(p.Guests) == 0 {
+					continue
+				}
+				if err = db.UpdateParticipantGuests(cloneID, p.UserID, gameID, p.Guests); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		log.Default().Println("failed to clone event:", err)
+		return nil, fmt.Errorf("failed to clone event: %w", err)
+	}
+	log.Default().Printf("User %s (%d) cloned event %s into %s", userName, userID, eventID, cloneID)
+
+	var event *models.Event
+	if event, err = s.publishEvent(cloneID, source.ChatID, threadID); err != nil {
+		return nil, err
+	}
+
+	s.notify(event.ChatID, models.HookWebhookTypeNewEvent, models.HookNewEventPayload{
+		ID:        event.ID,
+		ChatID:    event.ChatID,
+		UserID:    event.UserID,
+		UserName:  event.UserName,
+		Name:      event.Name,
+		MessageID: event.MessageID,
+		Location:  event.Location,
+		StartsAt:  event.StartsAt,
+		Locked:    event.Locked,
+		CreatedAt: time.Now(),
+	})
+	for _, bg := range event.BoardGames {
+		s.notify(event.ChatID, models.HookWebhookTypeNewGame, models.HookNewGamePayload{
+			ID:         bg.UUID,
+			EventID:    event.ID,
+			UserID:     userID,
+			UserName:   userName,
+			Name:       bg.Name,
+			MaxPlayers: int(bg.MaxPlayers),
+			Slot:       bg.Slot,
+			BGG: models.HookBGGInfo{
+				IsSet:    bg.BggID != nil,
+				ID:       bg.BggID,
+				Name:     bg.BggName,
+				URL:      bg.BggUrl,
+				ImageURL: bg.BggImageUrl,
+			},
+			CreatedAt: time.Now(),
+		})
+		for _, p := range bg.Participants {
+			s.notify(event.ChatID, models.HookWebhookTypeAddParticipant, models.HookAddParticipantPayload{
+				ID:       p.UUID,
+				EventID:  event.ID,
+				GameID:   bg.UUID,
+				UserID:   p.UserID,
+				UserName: p.UserName,
+				AddedAt:  time.Now(),
+			})
+		}
+	}
+
 	return event, nil
 }
 
+// pinEvent pins the event message and reports whether it succeeded. When the
+// bot lacks the permission, a notice is posted in reply to the event instead.
+func (s *Service) pinEvent(event *models.Event, msg *telebot.Message) bool {
+	if err := s.Bot.Pin(msg, telebot.Silent); err != nil {
+		log.Default().Printf("failed to pin event %s in chat %d: %v", event.ID, event.ChatID, err)
+
+		notice := s.Localizer(&event.ChatID).MustLocalizeMessage(&i18n.Message{ID: "PinNotAllowed"})
+		if _, err = s.Bot.Send(msg.Chat, notice, &telebot.SendOptions{ReplyTo: msg}); err != nil {
+			log.Default().Println("failed to send pin notice:", err)
+		}
+		return false
+	}
+
+	if err := s.DB.UpdateEventPinned(event.ID, true); err != nil {
+		log.Default().Println("failed to mark event as pinned:", err)
+	}
+	return true
+}
+
+// createEventTopic opens a forum topic named after the event and returns its
+// thread ID, or nil when the chat is not a forum or the bot cannot manage
+// topics, in which case the event is posted in the chat as usual.
+func (s *Service) createEventTopic(event *models.Event) *int64 {
+	name := []rune(event.Name)
+	if len(name) > models.MaxTopicNameLength {
+		name = name[:models.MaxTopicNameLength]
+	}
+
+	topic, err := s.Bot.CreateTopic(&telebot.Chat{ID: event.ChatID}, &telebot.Topic{Name: string(name)})
+	if err != nil {
+		log.Default().Printf("failed to create topic for event %s in chat %d: %v", event.ID, event.ChatID, err)
+		return nil
+	}
+
+	topicID := int64(topic.ThreadID)
+	if err = s.DB.UpdateEventTopic(event.ID, topicID); err != nil {
+		log.Default().Println("failed to store event topic:", err)
+	}
+	return &topicID
+}
+
+// closeEventTopic closes the forum topic created by createEventTopic.
+func (s *Service) closeEventTopic(event *models.Event) {
+	if event.TopicID == nil {
+		return
+	}
+
+	if err := s.Bot.CloseTopic(&telebot.Chat{ID: event.ChatID}, &telebot.Topic{ThreadID: int(*event.TopicID)}); err != nil {
+		log.Default().Printf("failed to close topic of event %s in chat %d: %v", event.ID, event.ChatID, err)
+	}
+
+	if err := s.DB.CloseEventTopic(event.ID); err != nil {
+		log.Default().Println("failed to mark event topic as closed:", err)
+	}
+}
+
+// CloseEndedEventTopics closes the topics of the events that ended before now.
+// Topics of events without a start time stay open until they are deleted.
+func (s *Service) CloseEndedEventTopics(now time.Time) {
+	events, err := s.DB.SelectOpenTopicEvents()
+	if err != nil {
+		log.Default().Println("failed to load events with open topics:", err)
+		return
+	}
+
+	for _, event := range events {
+		if !event.EndedBefore(now) {
+			continue
+		}
+
+		log.Default().Printf("Closing topic of ended event %s in chat %d", event.ID, event.ChatID)
+		s.closeEventTopic(&event)
+	}
+}
+
+// RemindMaybeUsers asks the users who answered maybe to an event starting
+// within models.MaybeReminderLead to decide. Every event is reminded once,
+// events that already started are only marked as reminded.
+func (s *Service) RemindMaybeUsers(now time.Time) {
+	events, err := s.DB.SelectMaybeEventsToRemind()
+	if err != nil {
+		log.Default().Println("failed to load events to remind:", err)
+		return
+	}
+
+	for _, event := range events {
+		if event.StartsAt == nil || event.StartsAt.After(now.Add(models.MaybeReminderLead)) {
+			continue
+		}
+
+		if event.StartsAt.After(now) {
+			log.Default().Printf("Reminding %d tentative users of event %s", len(event.Tentative), event.ID)
+			message := s.Localizer(&event.ChatID).MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID: "MaybeReminder",
+				},
+				TemplateData: map[string]string{
+					"Event": event.Name,
+					"Time":  event.StartsAt.Format("2006-01-02 15:04"),
+					"Link":  fmt.Sprintf("%s?startapp=%s", s.Url.BotMiniAppURL, event.ID),
+				},
+			})
+			for _, p := range event.Tentative {
+				if s.DB.GetNotificationPreferences(p.UserID).Reminders {
+					s.sendDirect(p.UserID, message)
+				}
+			}
+		}
+
+		if err = s.DB.SetMaybeReminded(event.ID); err != nil {
+			log.Default().Println("failed to mark event as reminded:", err)
+		}
+	}
+}
+
+// replyToEvent returns the options to post a message in reply to the event,
+// inside its topic when it has one.
+func replyToEvent(event *models.Event) *telebot.SendOptions {
+	options := &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+		ReplyTo: &telebot.Message{
+			ID: int(*event.MessageID),
+		},
+	}
+	if event.TopicID != nil {
+		options.ThreadID = int(*event.TopicID)
+	}
+	return options
+}
+
+// unpinEvent unpins the event message pinned by pinEvent.
+func (s *Service) unpinEvent(event *models.Event) {
+	if !event.Pinned || event.MessageID == nil {
+		return
+	}
+
+	if err := s.Bot.Unpin(&telebot.Chat{ID: event.ChatID}, int(*event.MessageID)); err != nil {
+		log.Default().Printf("failed to unpin event %s in chat %d: %v", event.ID, event.ChatID, err)
+	}
+
+	if err := s.DB.UpdateEventPinned(event.ID, false); err != nil {
+		log.Default().Println("failed to mark event as unpinned:", err)
+	}
+}
+
+// UnpinEndedEvents unpins the events that ended before now. Events without a
+// start time stay pinned until they are deleted.
+func (s *Service) UnpinEndedEvents(now time.Time) {
+	events, err := s.DB.SelectPinnedEvents()
+	if err != nil {
+		log.Default().Println("failed to load pinned events:", err)
+		return
+	}
+
+	for _, event := range events {
+		if !event.EndedBefore(now) {
+			continue
+		}
+
+		log.Default().Printf("Unpinning ended event %s in chat %d", event.ID, event.ChatID)
+		s.unpinEvent(&event)
+	}
+}
+
+// ArchiveEndedEvents marks the events that ended before now as finished and
+// removes the buttons from their message, which is otherwise left as is.
+// Events without a start time are never archived.
+func (s *Service) ArchiveEndedEvents(now time.Time) {
+	events, err := s.DB.SelectEventsToArchive()
+	if err != nil {
+		log.Default().Println("failed to load events to archive:", err)
+		return
+	}
+
+	for _, event := range events {
+		if !event.EndedBefore(now) {
+			continue
+		}
+
+		log.Default().Printf("Archiving ended event %s in chat %d", event.ID, event.ChatID)
+		if err = s.DB.SetEventFinished(event.ID); err != nil {
+			log.Default().Println("failed to mark event as finished:", err)
+			continue
+		}
+
+		if event.MessageID != nil {
+			s.removeButtons(event.ChatID, *event.MessageID)
+		}
+		// the games posted on their own keep join buttons too
+		for _, bg := range event.BoardGames {
+			if bg.MessageID != nil {
+				s.removeButtons(event.ChatID, *bg.MessageID)
+			}
+		}
+	}
+}
+
+// removeButtons removes the inline keyboard of a message sent by the bot.
+func (s *Service) removeButtons(chatID, messageID int64) {
+	if _, err := s.Bot.EditReplyMarkup(&telebot.Message{
+		ID: int(messageID),
+		Chat: &telebot.Chat{
+			ID: chatID,
+		},
+	}, nil); err != nil && !strings.Contains(err.Error(), models.MessageUnchangedErrorMessage) {
+		log.Default().Printf("failed to remove the buttons of message %d in chat %d: %v", messageID, chatID, err)
+	}
+}
+
 func (s *Service) DeleteEvent(eventID string, userID *int64, userName string) error {
 	var err error
 	var event *models.Event
@@ -101,9 +666,9 @@ func (s *Service) DeleteEvent(eventID string, userID *int64, userName string) er
 		return fmt.Errorf("invalid event ID: %w", err)
 	}
 
-	if event.Locked && (userID == nil || event.UserID != *userID) {
+	if event.Locked && (userID == nil || !s.IsHost(event, *userID)) {
 		log.Default().Println("event is locked")
-		return errors.New("unable to delete locked event")
+		return ErrEventLocked
 	}
 
 	if err = s.DB.DeleteEvent(eventID); err != nil {
@@ -111,6 +676,30 @@ func (s *Service) DeleteEvent(eventID string, userID *int64, userName string) er
 		return fmt.Errorf("failed to delete event: %w", err)
 	}
 
+	if event.Pinned && event.MessageID != nil {
+		if err = s.Bot.Unpin(&telebot.Chat{ID: event.ChatID}, int(*event.MessageID)); err != nil {
+			log.Default().Println("failed to unpin message:", err)
+		}
+	}
+
+	cancelled := s.Localizer(&event.ChatID).MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID: "EventCancelledDM",
+		},
+		TemplateData: map[string]string{
+			"Username": userName,
+			"Event":    event.Name,
+		},
+	})
+	for _, participantID := range event.ParticipantIDs() {
+		if userID != nil && participantID == *userID {
+			continue
+		}
+		if s.DB.GetNotificationPreferences(participantID).Cancellations {
+			s.sendDirect(participantID, cancelled)
+		}
+	}
+
 	to := &telebot.Chat{
 		ID: event.ChatID,
 	}
@@ -130,18 +719,13 @@ func (s *Service) DeleteEvent(eventID string, userID *int64, userName string) er
 		return errors.New("event message ID is nil")
 	}
 
-	options := &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-		ReplyTo: &telebot.Message{
-			ID: int(*event.MessageID),
-		},
-	}
-
 	log.Default().Printf("Sending delete message to chat %d: %s", to.ID, message)
-	if _, err = s.Bot.Send(to, message, options); err != nil {
+	if _, err = s.Bot.Send(to, message, replyToEvent(event)); err != nil {
 		log.Default().Println("failed to send message:", err)
 	}
 
+	s.closeEventTopic(event)
+
 	if err = s.Bot.Delete(&telebot.Message{
 		ID: int(*event.MessageID),
 		Chat: &telebot.Chat{
@@ -162,6 +746,7 @@ func (s *Service) CreateGame(
 	name string,
 	maxPlayers *int,
 	bggUrl *string,
+	slot string,
 ) (*models.Event, *models.BoardGame, error) {
 	var err error
 	var event *models.Event
@@ -171,9 +756,18 @@ func (s *Service) CreateGame(
 		return nil, nil, fmt.Errorf("invalid event ID: %w", err)
 	}
 
-	if event.Locked && event.UserID != userID {
+	if err = ensureOpen(event); err != nil {
+		return nil, nil, err
+	}
+
+	if event.Locked && !s.IsHost(event, userID) {
 		log.Default().Println("event is locked")
-		return nil, nil, errors.New("unable to add game to locked event")
+		return nil, nil, ErrEventLocked
+	}
+
+	if rsvpClosedFor(event, userID, time.Now()) {
+		log.Default().Printf("rsvp deadline of event %s has passed", eventID)
+		return nil, nil, ErrRSVPClosed
 	}
 
 	bgCtx, bgCancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -188,7 +782,8 @@ func (s *Service) CreateGame(
 
 	log.Default().Printf("Inserting %s in the db", name)
 
-	if _, _, err = s.DB.InsertBoardGame(event.ID, id, name, finalMaxPlayers, bgID, bgInfo.Name, bgInfo.Url, bgInfo.ImageUrl); err != nil {
+	var gameUUID string
+	if _, gameUUID, err = s.DB.InsertBoardGame(event.ID, id, name, strings.TrimSpace(slot), finalMaxPlayers, bgID, bgInfo.Name, bgInfo.Url, bgInfo.ImageUrl); err != nil {
 		log.Default().Println("failed to insert board game:", err)
 		return nil, nil, fmt.Errorf("failed to insert board game: %w", err)
 	}
@@ -197,13 +792,8 @@ func (s *Service) CreateGame(
 		log.Default().Println("failed to update telegram", err)
 	}
 
-	var game *models.BoardGame
-	for _, g := range event.BoardGames {
-		if g.Name == name {
-			game = &g
-			break
-		}
-	}
+	// the same game may be played in several time slots
+	game := utils.PickGameUUID(event, gameUUID)
 
 	log.Default().Printf("Game %s created in event %s", name, event.Name)
 
@@ -215,6 +805,12 @@ func (s *Service) CreateGame(
 // non-fatal — the caller receives whatever partial data was obtained.
 // Returns an error only for invalid BGG URLs.
 func (s *Service) fetchBGGInfo(ctx context.Context, bggUrl *string, name string) (bgID *int64, info models.BggInfo, err error) {
+	if s.batch != nil {
+		// fetched by RunBatch: a request here would keep the transaction open
+		lookup := s.batch.bgg[bggLookupKey(bggUrl, name)]
+		return lookup.id, lookup.info, lookup.err
+	}
+
 	if bggUrl != nil && *bggUrl != "" {
 		var id int64
 		var valid bool
@@ -286,7 +882,12 @@ func resolveMaxPlayers(requested *int, fromBGG *int) int {
 // lockGame returns a function that unlocks the per-game mutex for gameID.
 // Callers must defer the returned unlock to ensure the lock is always released.
 func (s *Service) lockGame(gameID int64) func() {
-	v, _ := s.gameUpdateMu.LoadOrStore(gameID, &sync.Mutex{})
+	owner := s
+	if s.root != nil {
+		owner = s.root
+	}
+
+	v, _ := owner.gameUpdateMu.LoadOrStore(gameID, &sync.Mutex{})
 	mu := v.(*sync.Mutex)
 	mu.Lock()
 	return mu.Unlock
@@ -303,17 +904,22 @@ func (s *Service) UpdateGame(eventID string, gameID int64, userID int64, bg mode
 		return nil, nil, fmt.Errorf("invalid event ID: %w", err)
 	}
 
-	if event.Locked && event.UserID != userID {
+	if err = ensureOpen(event); err != nil {
+		return nil, nil, err
+	}
+
+	if event.Locked && !s.IsHost(event, userID) {
 		log.Default().Println("event is locked")
-		return nil, nil, errors.New("unable to add game to locked event")
+		return nil, nil, ErrEventLocked
 	}
 
 	game = utils.PickGame(event, gameID)
 	if game == nil {
 		log.Default().Printf("invalid game ID: %d", gameID)
-		return nil, nil, errors.New("invalid game ID")
+		return nil, nil, ErrInvalidGame
 	}
 
+	before := event
 	maxPlayers := int(game.MaxPlayers)
 	if bg.MaxPlayers != nil && *bg.MaxPlayers >= 0 {
 		maxPlayers = *bg.MaxPlayers
@@ -334,18 +940,16 @@ func (s *Service) UpdateGame(eventID string, gameID int64, userID int64, bg mode
 	}
 
 	if bg.BggUrl != nil && *bg.BggUrl != "" {
-		var valid bool
-		var id int64
-		if id, valid = models.ExtractBoardGameID(*bg.BggUrl); !valid {
-			return nil, nil, errors.New("invalid bgg url")
+		var id *int64
+		var bgInfo models.BggInfo
+		if id, bgInfo, err = s.fetchBGGInfo(bgCtx, bg.BggUrl, game.Name); err != nil {
+			return nil, nil, err
 		}
 
-		var bgInfo *models.BggInfo
-
-		if bgInfo, err = s.BGG.ExtractCachedGameInfo(bgCtx, id, game.Name); err != nil {
-			log.Default().Printf("Failed to get game %d: %v", id, err)
+		if id == nil {
+			log.Default().Printf("Failed to get game %s", *bg.BggUrl)
 		} else {
-			bgID = &id
+			bgID = id
 			bgName = bgInfo.Name
 			bgUrl = bgInfo.Url
 			bgImageUrl = bgInfo.ImageUrl
@@ -361,15 +965,24 @@ func (s *Service) UpdateGame(eventID string, gameID int64, userID int64, bg mode
 		return nil, nil, fmt.Errorf("failed to update board game: %w", err)
 	}
 
+	if bg.Slot != nil && strings.TrimSpace(*bg.Slot) != game.Slot {
+		if err = s.DB.UpdateBoardGameSlot(gameID, strings.TrimSpace(*bg.Slot)); err != nil {
+			log.Default().Println("failed to update board game slot:", err)
+			return nil, nil, fmt.Errorf("failed to update board game: %w", err)
+		}
+	}
+
 	if event, err = s.updateTelegram(eventID); err != nil {
 		log.Default().Println("failed to update telegram", err)
 		return nil, nil, err
 	}
 
+	s.notifyWaitlist(before, event)
+
 	game = utils.PickGame(event, gameID)
 	if game == nil {
 		log.Default().Printf("invalid game ID: %d", gameID)
-		return nil, nil, errors.New("invalid game ID")
+		return nil, nil, ErrInvalidGame
 	}
 
 	return event, game, nil
@@ -385,16 +998,20 @@ func (s *Service) DeleteGame(eventID string, gameUUID string, userID int64, user
 		return nil, nil, fmt.Errorf("invalid event ID: %w", err)
 	}
 
-	if event.Locked && event.UserID != userID {
+	if err = ensureOpen(event); err != nil {
+		return nil, nil, err
+	}
+
+	if event.Locked && !s.IsHost(event, userID) {
 		log.Default().Println("event is locked")
-		return nil, nil, errors.New("unable to delete game from locked event")
+		return nil, nil, ErrEventLocked
 	}
 
 	game = utils.PickGameUUID(event, gameUUID)
 
 	if game == nil {
 		log.Default().Printf("invalid game ID: %s", gameUUID)
-		return nil, nil, errors.New("invalid game ID")
+		return nil, nil, ErrInvalidGame
 	}
 
 	if err = s.DB.DeleteBoardGameByID(gameUUID); err != nil {
@@ -417,29 +1034,43 @@ func (s *Service) DeleteGame(eventID string, gameUUID string, userID int64, user
 		},
 	})
 
-	options := &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-		ReplyTo: &telebot.Message{
-			ID: int(*event.MessageID),
-		},
-	}
+	options := replyToEvent(event)
 
-	log.Default().Printf("Sending delete message to chat %d: %s", to.ID, message)
-	if _, err = s.Bot.Send(to, message, options); err != nil {
-		log.Default().Println("failed to send message:", err)
-	}
+	s.afterCommit(func() {
+		log.Default().Printf("Sending delete message to chat %d: %s", to.ID, message)
+		if _, err := s.Bot.Send(to, message, options); err != nil {
+			log.Default().Println("failed to send message:", err)
+		}
+	})
 
 	log.Default().Printf("Game %s deleted from event %s", game.Name, event.Name)
-	if _, err = s.updateTelegram(eventID); err != nil {
+	var after *models.Event
+	if after, err = s.updateTelegram(eventID); err != nil {
 		log.Default().Println("failed to update telegram", err)
 	}
 
+	s.notifyWaitlist(event, after)
+
 	return event, game, nil
 }
 
 func (s *Service) AddPlayer(id *string, eventID string, gameID int64, userID int64, username string, isTelegramUsername bool) (string, *models.Event, *models.BoardGame, error) {
 	var err error
 	var participantID string
+	var before *models.Event
+	if before, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+	}
+
+	if err = ensureOpen(before); err != nil {
+		return "", nil, nil, err
+	}
+
+	if before != nil && rsvpClosedFor(before, userID, time.Now()) {
+		log.Default().Printf("rsvp deadline of event %s has passed", eventID)
+		return "", nil, nil, ErrRSVPClosed
+	}
+
 	if participantID, err = s.DB.InsertParticipant(id, eventID, gameID, userID, username, isTelegramUsername); err != nil {
 		log.Default().Println("failed to add user to participants table:", err)
 		return "", nil, nil, fmt.Errorf("invalid form data: %w", err)
@@ -456,18 +1087,31 @@ func (s *Service) AddPlayer(id *string, eventID string, gameID int64, userID int
 		return "", nil, nil, fmt.Errorf("invalid event ID: %w", err)
 	}
 
+	s.notifyWaitlist(before, event)
+
 	game := utils.PickGame(event, gameID)
 
 	return participantID, event, game, nil
 }
 
-func (s *Service) DeletePlayer(eventID string, userID int64) (string, *models.Event, *models.BoardGame, error) {
+// DeletePlayer frees the seat of userID at a game, or every seat they took in
+// the event when gameID is nil. It returns the first seat freed.
+func (s *Service) DeletePlayer(eventID string, userID int64, gameID *int64) (string, *models.Event, *models.BoardGame, error) {
 	var err error
 	var participantID string
-	var gameID int64
+	var freedGameID int64
 	var event *models.Event
 	var game *models.BoardGame
-	if participantID, gameID, err = s.DB.RemoveParticipant(eventID, userID); err != nil {
+	var before *models.Event
+	if before, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+	}
+
+	if err = ensureOpen(before); err != nil {
+		return "", nil, nil, err
+	}
+
+	if participantID, freedGameID, err = s.DB.RemoveParticipant(eventID, userID, gameID); err != nil {
 		log.Default().Println("failed to remove participant from webhook:", err)
 		if errors.Is(err, database.ErrNoRows) {
 			return "", nil, nil, database.ErrNoRows
@@ -481,11 +1125,355 @@ func (s *Service) DeletePlayer(eventID string, userID int64) (string, *models.Ev
 		return "", nil, nil, err
 	}
 
-	game = utils.PickGame(event, gameID)
+	s.notifyWaitlist(before, event)
+
+	game = utils.PickGame(event, freedGameID)
 
 	return participantID, event, game, nil
 }
 
+// KickParticipant lets a host remove a participant from the event: their seats
+// with their guests, or their maybe or declined answer. The participant is
+// told in a private message.
+func (s *Service) KickParticipant(eventID string, hostID int64, hostName string, userID int64) (*models.Event, error) {
+	var err error
+	var before *models.Event
+	if before, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+		return nil, fmt.Errorf("invalid event ID: %w", err)
+	}
+
+	if err = ensureOpen(before); err != nil {
+		return nil, err
+	}
+
+	if !s.IsHost(before, hostID) {
+		log.Default().Printf("user %d is not a host of event %s", hostID, eventID)
+		return nil, ErrNotHost
+	}
+
+	participant, game := findParticipant(before, userID)
+	if participant == nil {
+		return nil, database.ErrNoRows
+	}
+
+	if game != nil {
+		_, _, err = s.DB.RemoveParticipant(eventID, userID, nil)
+	} else {
+		err = s.DB.RemoveRSVP(eventID, userID)
+	}
+	if err != nil {
+		log.Default().Println("failed to kick participant:", err)
+		return nil, fmt.Errorf("failed to remove participant: %w", err)
+	}
+	log.Default().Printf("User %s (%d) removed %s (%d) from event %s", hostName, hostID, participant.UserName, userID, eventID)
+
+	var event *models.Event
+	if event, err = s.updateTelegram(eventID); err != nil {
+		log.Default().Println("failed to update telegram", err)
+		return nil, err
+	}
+
+	s.notifyWaitlist(before, event)
+
+	payload := models.HookKickParticipantPayload{
+		EventID:  eventID,
+		UserID:   userID,
+		UserName: participant.UserName,
+		HostID:   hostID,
+		HostName: hostName,
+		KickedAt: time.Now(),
+	}
+	if game != nil {
+		payload.GameID = game.UUID
+	}
+	s.notifySeatsFreed(before, userID, payload.KickedAt)
+	s.notify(event.ChatID, models.HookWebhookTypeKickParticipant, payload)
+
+	if userID != hostID {
+		s.sendDirect(userID, s.Localizer(&event.ChatID).MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "KickedFromEvent"},
+			TemplateData: map[string]string{
+				"Host":  hostName,
+				"Event": event.Name,
+			},
+		}))
+	}
+
+	return event, nil
+}
+
+// MoveParticipant lets a host move the seat of a participant, with their
+// guests, to another game of the event: the seat in the slot of the game when
+// they have one, their first seat otherwise. The participant joins the end of
+// the list of the game, queued if it is full, and is told in a private
+// message.
+func (s *Service) MoveParticipant(eventID string, hostID int64, hostName string, userID, gameID int64) (*models.Event, *models.BoardGame, error) {
+	defer s.lockGame(gameID)()
+	var err error
+	var before *models.Event
+	if before, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+		return nil, nil, fmt.Errorf("invalid event ID: %w", err)
+	}
+
+	if err = ensureOpen(before); err != nil {
+		return nil, nil, err
+	}
+
+	if !s.IsHost(before, hostID) {
+		log.Default().Printf("user %d is not a host of event %s", hostID, eventID)
+		return nil, nil, ErrNotHost
+	}
+
+	to := utils.PickGame(before, gameID)
+	if to == nil {
+		log.Default().Printf("invalid game ID: %d", gameID)
+		return nil, nil, ErrInvalidGame
+	}
+
+	participant, from := findSeat(before, userID, to.Slot)
+	if participant == nil {
+		return nil, nil, database.ErrNoRows
+	}
+
+	if from.ID == to.ID {
+		return before, to, nil
+	}
+
+	if err = s.DB.MoveParticipant(eventID, userID, from.ID, gameID); err != nil {
+		log.Default().Println("failed to move participant:", err)
+		return nil, nil, fmt.Errorf("failed to move participant: %w", err)
+	}
+	log.Default().Printf("User %s (%d) moved %s (%d) from %s to %s in event %s", hostName, hostID, participant.UserName, userID, from.Name, to.Name, eventID)
+
+	var event *models.Event
+	if event, err = s.updateTelegram(eventID); err != nil {
+		log.Default().Println("failed to update telegram", err)
+		return nil, nil, err
+	}
+
+	s.notifyWaitlist(before, event)
+	s.notify(event.ChatID, models.HookWebhookTypeMoveParticipant, models.HookMoveParticipantPayload{
+		EventID:    eventID,
+		FromGameID: from.UUID,
+		ToGameID:   to.UUID,
+		UserID:     userID,
+		UserName:   participant.UserName,
+		HostID:     hostID,
+		HostName:   hostName,
+		MovedAt:    time.Now(),
+	})
+
+	if userID != hostID {
+		s.sendDirect(userID, s.Localizer(&event.ChatID).MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "MovedToGame"},
+			TemplateData: map[string]string{
+				"Host":  hostName,
+				"Game":  to.Name,
+				"Event": event.Name,
+			},
+		}))
+	}
+
+	return event, utils.PickGame(event, gameID), nil
+}
+
+// findParticipant looks up userID among the players of the event and then
+// among the users who answered maybe or declined. The game is nil for the
+// latter.
+func findParticipant(event *models.Event, userID int64) (*models.Participant, *models.BoardGame) {
+	for i := range event.BoardGames {
+		for j := range event.BoardGames[i].Participants {
+			if event.BoardGames[i].Participants[j].UserID == userID {
+				return &event.BoardGames[i].Participants[j], &event.BoardGames[i]
+			}
+		}
+	}
+
+	for _, answers := range [][]models.Participant{event.Tentative, event.Declined} {
+		for i := range answers {
+			if answers[i].UserID == userID {
+				return &answers[i], nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// findSeat looks up the seat of userID in the time slot, falling back to the
+// first seat they took in the event.
+func findSeat(event *models.Event, userID int64, slot string) (*models.Participant, *models.BoardGame) {
+	var participant *models.Participant
+	var game *models.BoardGame
+	for i := range event.BoardGames {
+		for j := range event.BoardGames[i].Participants {
+			if event.BoardGames[i].Participants[j].UserID != userID {
+				continue
+			}
+			if event.BoardGames[i].Slot == slot {
+				return &event.BoardGames[i].Participants[j], &event.BoardGames[i]
+			}
+			if participant == nil {
+				participant, game = &event.BoardGames[i].Participants[j], &event.BoardGames[i]
+			}
+		}
+	}
+
+	return participant, game
+}
+
+// notifySeatsFreed sends a remove_participant webhook for every seat userID
+// had in the event before leaving it.
+func (s *Service) notifySeatsFreed(before *models.Event, userID int64, at time.Time) {
+	for _, bg := range before.BoardGames {
+		for _, p := range bg.Participants {
+			if p.UserID != userID {
+				continue
+			}
+			s.notify(before.ChatID, models.HookWebhookTypeRemoveParticipant, models.HookRemoveParticipantPayload{
+				ID:        p.UUID,
+				EventID:   before.ID,
+				UserID:    userID,
+				GameID:    bg.UUID,
+				UserName:  p.UserName,
+				RemovedAt: at,
+			})
+		}
+	}
+}
+
+// SetRSVP records that the user answered maybe or that they cannot make it to
+// the event, freeing the seats they took. It returns the last game they left,
+// nil when they had no seat.
+func (s *Service) SetRSVP(eventID string, userID int64, userName string, isTelegramUsername bool, status models.RSVPStatus) (*models.Event, *models.BoardGame, error) {
+	var err error
+	var before *models.Event
+	if before, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+		return nil, nil, fmt.Errorf("invalid event ID: %w", err)
+	}
+
+	if err = ensureOpen(before); err != nil {
+		return nil, nil, err
+	}
+
+	var left *models.BoardGame
+	for i := range before.BoardGames {
+		for _, p := range before.BoardGames[i].Participants {
+			if p.UserID == userID {
+				left = &before.BoardGames[i]
+			}
+		}
+	}
+
+	var id string
+	if id, err = s.DB.SetParticipantRSVP(nil, eventID, userID, userName, isTelegramUsername, status); err != nil {
+		log.Default().Println("failed to set rsvp:", err)
+		return nil, nil, fmt.Errorf("failed to set rsvp: %w", err)
+	}
+	log.Default().Printf("User %s (%d) answered %s to event %s (%s)", userName, userID, status, eventID, id)
+
+	var event *models.Event
+	if event, err = s.updateTelegram(eventID); err != nil {
+		log.Default().Println("failed to update telegram", err)
+		return nil, nil, err
+	}
+
+	s.notifyWaitlist(before, event)
+	s.notifySeatsFreed(before, userID, time.Now())
+	s.notify(event.ChatID, models.HookWebhookTypeUpdateRSVP, models.HookRSVPPayload{
+		EventID:   eventID,
+		UserID:    userID,
+		UserName:  userName,
+		Status:    status,
+		UpdatedAt: time.Now(),
+	})
+
+	return event, left, nil
+}
+
+// AddGuest adds a guest, whose name may be empty, to the game joined by
+// userID, the one in the latest time slot when they joined several. It
+// returns database.ErrNoRows when the user is not taking part in the event.
+func (s *Service) AddGuest(eventID string, userID int64, name string) (*models.Event, *models.BoardGame, error) {
+	return s.updateGuests(eventID, userID, func(event *models.Event, guests []string) ([]string, error) {
+		if rsvpClosedFor(event, userID, time.Now()) {
+			return nil, ErrRSVPClosed
+		}
+		if len(guests) >= models.MaxGuests {
+			return nil, ErrTooManyGuests
+		}
+		return append(guests, name), nil
+	})
+}
+
+// RemoveGuest removes the last guest added by userID.
+func (s *Service) RemoveGuest(eventID string, userID int64) (*models.Event, *models.BoardGame, error) {
+	return s.updateGuests(eventID, userID, func(_ *models.Event, guests []string) ([]string, error) {
+		if len(guests) == 0 {
+			return nil, ErrNoGuests
+		}
+		return guests[:len(guests)-1], nil
+	})
+}
+
+func (s *Service) updateGuests(eventID string, userID int64, change func(event *models.Event, guests []string) ([]string, error)) (*models.Event, *models.BoardGame, error) {
+	var err error
+	var before *models.Event
+	if before, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+		return nil, nil, fmt.Errorf("invalid event ID: %w", err)
+	}
+
+	if err = ensureOpen(before); err != nil {
+		return nil, nil, err
+	}
+
+	var game *models.BoardGame
+	var participant *models.Participant
+	for i := range before.BoardGames {
+		for j := range before.BoardGames[i].Participants {
+			if before.BoardGames[i].Participants[j].UserID == userID {
+				game = &before.BoardGames[i]
+				participant = &before.BoardGames[i].Participants[j]
+			}
+		}
+	}
+	if participant == nil {
+		return nil, nil, database.ErrNoRows
+	}
+
+	var guests []string
+	if guests, err = change(before, append([]string{}, participant.Guests...)); err != nil {
+		return nil, nil, err
+	}
+
+	if err = s.DB.UpdateParticipantGuests(eventID, userID, game.ID, guests); err != nil {
+		log.Default().Println("failed to update guests:", err)
+		return nil, nil, fmt.Errorf("failed to update guests: %w", err)
+	}
+
+	var event *models.Event
+	if event, err = s.updateTelegram(eventID); err != nil {
+		log.Default().Println("failed to update telegram", err)
+		return nil, nil, err
+	}
+
+	s.notifyWaitlist(before, event)
+	s.notify(event.ChatID, models.HookWebhookTypeUpdateGuests, models.HookGuestsPayload{
+		EventID:   event.ID,
+		GameID:    game.UUID,
+		UserID:    userID,
+		UserName:  participant.UserName,
+		Guests:    guests,
+		UpdatedAt: time.Now(),
+	})
+
+	return event, utils.PickGame(event, game.ID), nil
+}
+
 func (s *Service) updateTelegram(eventID string) (*models.Event, error) {
 	var err error
 	var event *models.Event
@@ -495,32 +1483,490 @@ func (s *Service) updateTelegram(eventID string) (*models.Event, error) {
 		return nil, err
 	}
 
+	if s.batch != nil {
+		if !s.batch.seen[eventID] {
+			s.batch.seen[eventID] = true
+			s.batch.events = append(s.batch.events, eventID)
+		}
+		return event, nil
+	}
+
 	if event.MessageID == nil {
 		log.Default().Println("event message id is nil")
 		return nil, err
 	}
 
-	body, markup := event.FormatMsg(s.Localizer(&event.ChatID), s.Url)
+	if s.Refresher != nil {
+		s.Refresher.Schedule(event.ChatID, int(*event.MessageID), s.renderEvent(eventID))
+	} else {
+		body, markup := event.FormatMsg(s.Localizer(&event.ChatID), s.Url)
 
-	_, err = s.Bot.Edit(&telebot.Message{
-		ID: int(*event.MessageID),
-		Chat: &telebot.Chat{
-			ID: event.ChatID,
-		},
-	}, body, markup, telebot.NoPreview)
+		_, err = s.Bot.Edit(&telebot.Message{
+			ID: int(*event.MessageID),
+			Chat: &telebot.Chat{
+				ID: event.ChatID,
+			},
+		}, body, markup, telebot.NoPreview)
+		if err != nil {
+			if strings.Contains(err.Error(), models.MessageUnchangedErrorMessage) {
+				// Content is already up-to-date; not a real error.
+				err = nil
+			} else {
+				log.Default().Println("failed to edit message:", err)
+			}
+		}
+	}
+
+	return event, nil
+}
+
+// renderEvent reloads the event when the refresher is about to edit its
+// message, so that coalesced refreshes show the latest state.
+func (s *Service) renderEvent(eventID string) RenderFunc {
+	return func() (string, *telebot.ReplyMarkup, error) {
+		event, err := s.DB.SelectEventByEventID(eventID)
+		if err != nil {
+			return "", nil, err
+		}
+		if event.ID == "" {
+			return "", nil, fmt.Errorf("event %s not found", eventID)
+		}
+
+		body, markup := event.FormatMsg(s.Localizer(&event.ChatID), s.Url)
+		return body, markup, nil
+	}
+}
+
+// ensureOpen returns ErrEventFinished when the event is over and can no
+// longer be changed. A nil event, not loaded, is left to the caller.
+func ensureOpen(event *models.Event) error {
+	if event == nil || !event.Finished {
+		return nil
+	}
+
+	log.Default().Printf("event %s is over", event.ID)
+	return ErrEventFinished
+}
+
+// rsvpClosedFor reports whether the RSVP deadline of the event keeps userID
+// from joining it, adding games or bringing guests at now. The creator of the
+// event is not bound by its deadline.
+func rsvpClosedFor(event *models.Event, userID int64, now time.Time) bool {
+	return event.RSVPClosed(now) && event.UserID != userID
+}
+
+// IsHost reports whether the user can manage the event: its owner, one of
+// its co-hosts or an admin of its chat.
+func (s *Service) IsHost(event *models.Event, userID int64) bool {
+	if event.IsCoHost(userID) {
+		return true
+	}
+
+	if event.ChatID > 0 {
+		return false
+	}
+
+	return s.isChatAdmin(event.ChatID, userID)
+}
+
+// isChatAdmin reports whether the user administers the chat. A private chat
+// is administered by the user it belongs to.
+func (s *Service) isChatAdmin(chatID, userID int64) bool {
+	if chatID > 0 {
+		return chatID == userID
+	}
+
+	admins, err := s.Bot.AdminsOf(&telebot.Chat{ID: chatID})
 	if err != nil {
-		if strings.Contains(err.Error(), models.MessageUnchangedErrorMessage) {
-			// Content is already up-to-date; not a real error.
-			err = nil
-		} else {
-			log.Default().Println("failed to edit message:", err)
+		log.Default().Println("failed to get chat admins:", err)
+		return false
+	}
+
+	for _, admin := range admins {
+		if admin.User != nil && admin.User.ID == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetCoHost adds or removes a co-host of an event. Only the event owner can
+// change them.
+func (s *Service) SetCoHost(eventID string, userID int64, coHostID int64, coHostName string, add bool) (*models.Event, error) {
+	var err error
+	var event *models.Event
+
+	if event, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+		return nil, fmt.Errorf("invalid event ID: %w", err)
+	}
+
+	if err = ensureOpen(event); err != nil {
+		return nil, err
+	}
+
+	if event.UserID != userID {
+		log.Default().Printf("user %d is not the owner of event %s", userID, eventID)
+		return nil, ErrNotEventOwner
+	}
+
+	if coHostID == event.UserID {
+		return event, nil
+	}
+
+	if add {
+		err = s.DB.AddEventCoHost(eventID, coHostID, coHostName)
+	} else {
+		var removed bool
+		if removed, err = s.DB.RemoveEventCoHost(eventID, coHostID); err == nil && !removed {
+			return nil, ErrNotCoHost
 		}
 	}
+	if err != nil {
+		log.Default().Println("failed to update co-hosts:", err)
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	if event, err = s.updateTelegram(eventID); err != nil {
+		log.Default().Println("failed to update telegram", err)
+		return nil, err
+	}
+
+	s.notifyEventUpdated(event)
+	s.notify(event.ChatID, models.HookWebhookTypeUpdateCoHosts, models.HookCoHostsPayload{
+		EventID:   event.ID,
+		UserID:    userID,
+		CoHosts:   event.CoHosts,
+		UpdatedAt: time.Now(),
+	})
+
+	return event, nil
+}
+
+// SetEventLocked locks or unlocks an event. Only the event owner can change
+// it; setting the current state again is a no-op.
+func (s *Service) SetEventLocked(eventID string, userID int64, userName string, locked bool) (*models.Event, error) {
+	var err error
+	var event *models.Event
+
+	if event, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+		return nil, fmt.Errorf("invalid event ID: %w", err)
+	}
+
+	if err = ensureOpen(event); err != nil {
+		return nil, err
+	}
+
+	if event.UserID != userID {
+		log.Default().Printf("user %d is not the owner of event %s", userID, eventID)
+		return nil, ErrNotEventOwner
+	}
+
+	if event.Locked == locked {
+		return event, nil
+	}
+
+	if err = s.DB.UpdateEventLocked(eventID, locked); err != nil {
+		log.Default().Println("failed to update event lock:", err)
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	if event, err = s.updateTelegram(eventID); err != nil {
+		log.Default().Println("failed to update telegram", err)
+		return nil, err
+	}
+
+	s.notifyEventUpdated(event)
+
+	hookType := models.HookWebhookTypeUnlockEvent
+	if locked {
+		hookType = models.HookWebhookTypeLockEvent
+	}
+
+	s.notify(event.ChatID, hookType, models.HookLockEventPayload{
+		EventID:   event.ID,
+		UserID:    userID,
+		UserName:  userName,
+		Locked:    locked,
+		ChangedAt: time.Now(),
+	})
+
+	return event, nil
+}
+
+// SetRSVPDeadline sets the RSVP deadline of an event, nil removes it. Only the
+// event owner can change it.
+func (s *Service) SetRSVPDeadline(eventID string, userID int64, deadline *time.Time) (*models.Event, error) {
+	var err error
+	var event *models.Event
+
+	if event, err = s.DB.SelectEventByEventID(eventID); err != nil {
+		log.Default().Println("failed to load event:", err)
+		return nil, fmt.Errorf("invalid event ID: %w", err)
+	}
+
+	if err = ensureOpen(event); err != nil {
+		return nil, err
+	}
+
+	if event.UserID != userID {
+		log.Default().Printf("user %d is not the owner of event %s", userID, eventID)
+		return nil, ErrNotEventOwner
+	}
+
+	if err = s.DB.UpdateEventRSVPDeadline(eventID, deadline); err != nil {
+		log.Default().Println("failed to update rsvp deadline:", err)
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	if event, err = s.updateTelegram(eventID); err != nil {
+		log.Default().Println("failed to update telegram", err)
+		return nil, err
+	}
+
+	s.notifyEventUpdated(event)
 
 	return event, nil
 }
 
-func (t Service) Localizer(chatID *int64) *i18n.Localizer {
+// PostFinalLineups posts, in reply to each event whose RSVP deadline passed
+// before now, the final lineup of its games.
+func (s *Service) PostFinalLineups(now time.Time) {
+	events, err := s.DB.SelectPendingLineupEvents()
+	if err != nil {
+		log.Default().Println("failed to load events with a pending lineup:", err)
+		return
+	}
+
+	for _, event := range events {
+		if !event.RSVPClosed(now) {
+			continue
+		}
+
+		log.Default().Printf("Posting final lineup of event %s in chat %d", event.ID, event.ChatID)
+		options := &telebot.SendOptions{ParseMode: telebot.ModeHTML}
+		if event.MessageID != nil {
+			options = replyToEvent(&event)
+		}
+		options.DisableWebPagePreview = true
+
+		lineup := event.FormatLineup(s.Localizer(&event.ChatID), s.Url)
+		if _, err = s.Bot.Send(&telebot.Chat{ID: event.ChatID}, lineup, options); err != nil {
+			log.Default().Printf("failed to post final lineup of event %s: %v", event.ID, err)
+			continue
+		}
+
+		if err = s.DB.SetLineupPosted(event.ID); err != nil {
+			log.Default().Println("failed to mark lineup as posted:", err)
+		}
+
+		// show that the lineup is final in the event message
+		if _, err = s.updateTelegram(event.ID); err != nil {
+			log.Default().Println("failed to update telegram", err)
+		}
+	}
+}
+
+// ApplyRetention enforces the retention policy at now: the events held
+// before the configured number of months are anonymized or deleted.
+func (s *Service) ApplyRetention(now time.Time) {
+	if months := s.Retention.DeleteAfterMonths; months > 0 {
+		deleted, err := s.DB.DeleteEventsBefore(now.AddDate(0, -months, 0))
+		if err != nil {
+			log.Default().Println("failed to delete expired events:", err)
+		} else if deleted > 0 {
+			log.Default().Printf("Deleted %d events older than %d months", deleted, months)
+		}
+	}
+
+	if months := s.Retention.AnonymizeAfterMonths; months > 0 {
+		anonymized, err := s.DB.AnonymizeEventsBefore(now.AddDate(0, -months, 0), models.AnonymousUserName)
+		if err != nil {
+			log.Default().Println("failed to anonymize expired events:", err)
+		} else if anonymized > 0 {
+			log.Default().Printf("Anonymized %d events older than %d months", anonymized, months)
+		}
+	}
+}
+
+// ForgetUser erases the data of the user in every chat and refreshes the
+// messages of the events they were part of.
+func (s *Service) ForgetUser(userID int64) error {
+	eventIDs, err := s.DB.ForgetUser(userID, models.AnonymousUserName)
+	if err != nil {
+		log.Default().Println("failed to forget user:", err)
+		return fmt.Errorf("failed to forget user: %w", err)
+	}
+
+	log.Default().Printf("Erased user %d from %d events", userID, len(eventIDs))
+	for _, eventID := range eventIDs {
+		if _, err = s.updateTelegram(eventID); err != nil {
+			log.Default().Println("failed to update telegram", err)
+		}
+	}
+
+	return nil
+}
+
+// SetAutoPin turns the automatic pinning of new events of a chat on or off.
+// Only the chat admins can change it.
+func (s *Service) SetAutoPin(chatID, userID int64, enabled bool) error {
+	if !s.isChatAdmin(chatID, userID) {
+		log.Default().Printf("user %d is not admin in chat %d", userID, chatID)
+		return ErrNotChatAdmin
+	}
+
+	if err := s.DB.SetAutoPin(chatID, enabled); err != nil {
+		log.Default().Println("failed to set auto pin:", err)
+		return fmt.Errorf("failed to set auto pin: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeChat deletes every event, webhook and setting of a chat. Only the chat
+// admins can purge it. It returns how many events were deleted.
+func (s *Service) PurgeChat(chatID, userID int64) (int64, error) {
+	if !s.isChatAdmin(chatID, userID) {
+		log.Default().Printf("user %d is not admin in chat %d", userID, chatID)
+		return 0, ErrNotChatAdmin
+	}
+
+	deleted, err := s.DB.PurgeChat(chatID)
+	if err != nil {
+		log.Default().Println("failed to purge chat:", err)
+		return 0, fmt.Errorf("failed to purge chat: %w", err)
+	}
+
+	log.Default().Printf("Purged %d events of chat %d", deleted, chatID)
+	return deleted, nil
+}
+
+// ExportChat describes every event of the chat with its games and
+// participants. Only the chat admins can export it.
+func (s *Service) ExportChat(chatID, userID int64) (*models.ChatExport, error) {
+	if !s.isChatAdmin(chatID, userID) {
+		log.Default().Printf("user %d is not admin in chat %d", userID, chatID)
+		return nil, ErrNotChatAdmin
+	}
+
+	events, err := s.DB.SelectEventsByChatID(chatID)
+	if err != nil {
+		log.Default().Println("failed to load events:", err)
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+
+	export := models.NewChatExport(chatID, events, time.Now())
+	log.Default().Printf("User %d exported %d events of chat %d", userID, len(export.Events), chatID)
+	return &export, nil
+}
+
+// ImportChat recreates the events of an export in chatID, keeping their
+// UUIDs and those of their games and participants. Either everything is
+// imported or nothing is. The events are not posted to Telegram.
+func (s *Service) ImportChat(chatID int64, export *models.ChatExport) error {
+	if err := s.DB.WithTransaction(func(db database.DatabaseService) error {
+		// checked on the transaction, so the inserts see the same state
+		for _, e := range export.Events {
+			if event, err := db.SelectEventByEventID(e.ID); err == nil && event.ID != "" {
+				log.Default().Printf("event %s already exists", e.ID)
+				return ErrAlreadyImported
+			}
+		}
+		for _, g := range export.Games {
+			if _, err := db.SelectGameIDByGameUUID(g.ID); err == nil {
+				log.Default().Printf("game %s already exists", g.ID)
+				return ErrAlreadyImported
+			}
+		}
+
+		events := map[string]bool{}
+		for _, e := range export.Events {
+			id := e.ID
+			if _, err := db.InsertEventWithOptionalGame(&id, chatID, e.UserID, e.UserName, e.Name, e.Location, e.StartsAt, e.Locked, false); err != nil {
+				return err
+			}
+			if e.RSVPDeadline != nil {
+				if err := db.UpdateEventRSVPDeadline(id, e.RSVPDeadline); err != nil {
+					return err
+				}
+			}
+			for _, coHost := range e.CoHosts {
+				if err := db.AddEventCoHost(id, coHost.UserID, coHost.UserName); err != nil {
+					return err
+				}
+			}
+			events[id] = true
+		}
+
+		games := map[string]int64{}
+		for _, g := range export.Games {
+			if !events[g.EventID] {
+				return fmt.Errorf("game %s belongs to unknown event %s", g.ID, g.EventID)
+			}
+
+			id := g.ID
+			gameID, _, err := db.InsertBoardGame(g.EventID, &id, g.Name, g.Slot, int(g.MaxPlayers), g.BggID, g.BggName, g.BggUrl, g.BggImageUrl)
+			if err != nil {
+				return err
+			}
+			games[id] = gameID
+		}
+
+		for _, p := range export.Participants {
+			if !events[p.EventID] {
+				return fmt.Errorf("participant %s belongs to unknown event %s", p.ID, p.EventID)
+			}
+
+			id := p.ID
+			if p.GameID == "" {
+				if p.Status != models.RSVPMaybe && p.Status != models.RSVPDeclined {
+					return fmt.Errorf("participant %s has no game", p.ID)
+				}
+				if _, err := db.SetParticipantRSVP(&id, p.EventID, p.UserID, p.UserName, p.IsTelegramUsername, p.Status); err != nil {
+					return err
+				}
+				continue
+			}
+
+			gameID, ok := games[p.GameID]
+			if !ok {
+				return fmt.Errorf("participant %s sits at unknown game %s", p.ID, p.GameID)
+			}
+			if _, err := db.InsertParticipant(&id, p.EventID, gameID, p.UserID, p.UserName, p.IsTelegramUsername); err != nil {
+				return err
+			}
+			if len(p.Guests) == 0 {
+				continue
+			}
+			if err := db.UpdateParticipantGuests(p.EventID, p.UserID, gameID, p.Guests); err != nil {
+				return err
+			}
+		}
+
+		// finished events accept no changes, so they are archived last
+		for _, e := range export.Events {
+			if !e.Finished {
+				continue
+			}
+			if err := db.SetEventFinished(e.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		log.Default().Println("failed to import chat:", err)
+		return fmt.Errorf("failed to import chat: %w", err)
+	}
+
+	log.Default().Printf("Imported %d events, %d games and %d participants into chat %d", len(export.Events), len(export.Games), len(export.Participants), chatID)
+	return nil
+}
+
+func (t *Service) Localizer(chatID *int64) *i18n.Localizer {
 	if chatID == nil {
 		return i18n.NewLocalizer(t.LanguageBundle, "en")
 	}
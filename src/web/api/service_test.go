@@ -1,11 +1,14 @@
 package api
 
 import (
+	"boardgame-night-bot/src/database"
 	"boardgame-night-bot/src/mocks"
 	"boardgame-night-bot/src/models"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,7 +21,7 @@ import (
 	"gopkg.in/telebot.v3"
 )
 
-func BeforeEach() Service {
+func BeforeEach() *Service {
 	bundle := i18n.NewBundle(language.English)
 	bundle.RegisterUnmarshalFunc("toml", toml.Unmarshal)
 
@@ -46,7 +49,7 @@ func BeforeEach() Service {
 		},
 	}
 
-	return *service
+	return service
 }
 
 func TestCreateEvent(t *testing.T) {
@@ -54,7 +57,7 @@ func TestCreateEvent(t *testing.T) {
 	db := service.DB.(*mocks.MockDatabase)
 	telegram := service.Bot.(*mocks.MockTelegramService)
 	isEventWithGameInserted := false
-	db.InsertEventWithOptionalGameFunc = func(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, addPlayerCounter bool) (string, error) {
+	db.InsertEventWithOptionalGameFunc = func(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, locked, addPlayerCounter bool) (string, error) {
 		if chatID != 12345 {
 			t.Fatalf("Expected chatID 12345, got %d", chatID)
 		}
@@ -99,7 +102,7 @@ func TestCreateEvent(t *testing.T) {
 		return &telebot.Message{ID: int(responseTelegramID)}, nil
 	}
 
-	event, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event", nil, nil, true)
+	event, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event", nil, nil, false, true)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -129,7 +132,7 @@ func TestCreateEventWithLocationAndStartTime(t *testing.T) {
 	startsPropagated := false
 	wantStartsAt := time.Time{}.Add(24 * time.Hour)
 
-	db.InsertEventWithOptionalGameFunc = func(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, addPlayerCounter bool) (string, error) {
+	db.InsertEventWithOptionalGameFunc = func(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, locked, addPlayerCounter bool) (string, error) {
 		if location != nil && *location == "Test Location" {
 			locationPropagated = true
 		}
@@ -140,7 +143,7 @@ func TestCreateEventWithLocationAndStartTime(t *testing.T) {
 	}
 
 	location := "Test Location"
-	_, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event with Location and Time", &location, &wantStartsAt, false)
+	_, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event with Location and Time", &location, &wantStartsAt, false, false)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -156,11 +159,11 @@ func TestCreateEventDBFailure(t *testing.T) {
 	service := BeforeEach()
 	db := service.DB.(*mocks.MockDatabase)
 
-	db.InsertEventWithOptionalGameFunc = func(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, addPlayerCounter bool) (string, error) {
+	db.InsertEventWithOptionalGameFunc = func(id *string, chatID, userID int64, userName, name string, location *string, startsAt *time.Time, locked, addPlayerCounter bool) (string, error) {
 		return "", fmt.Errorf("db error")
 	}
 
-	_, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event", nil, nil, false)
+	_, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event", nil, nil, false, false)
 	if err == nil {
 		t.Fatal("Expected error when DB fails, got nil")
 	}
@@ -179,7 +182,7 @@ func TestCreateGameLockedEventBlocksNonOwner(t *testing.T) {
 	}
 
 	nonOwnerID := int64(12345)
-	_, _, err := service.CreateGame("mock-event-id", nil, nonOwnerID, "Chess", nil, nil)
+	_, _, err := service.CreateGame("mock-event-id", nil, nonOwnerID, "Chess", nil, nil, "")
 	if err == nil {
 		t.Fatal("Expected error adding game to locked event as non-owner, got nil")
 	}
@@ -207,12 +210,57 @@ func TestCreateGameLockedEventAllowsOwner(t *testing.T) {
 		return &telebot.Message{ID: 1}, nil
 	}
 
-	_, _, err := service.CreateGame("mock-event-id", nil, ownerID, "Chess", nil, nil)
+	_, _, err := service.CreateGame("mock-event-id", nil, ownerID, "Chess", nil, nil, "")
 	if err != nil {
 		t.Fatalf("Expected owner to add game to their own locked event, got: %v", err)
 	}
 }
 
+func TestLockedEventAllowsHosts(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+	messageID := int64(11111)
+
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:        eventID,
+			ChatID:    -100,
+			UserID:    1,
+			Locked:    true,
+			MessageID: &messageID,
+			CoHosts:   []models.CoHost{{UserID: 2, UserName: "cohost"}},
+		}, nil
+	}
+	telegram.AdminsOfFunc = func(chat *telebot.Chat) ([]telebot.ChatMember, error) {
+		return []telebot.ChatMember{{User: &telebot.User{ID: 3}, Role: telebot.Administrator}}, nil
+	}
+	telegram.EditFunc = func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		return &telebot.Message{ID: 1}, nil
+	}
+
+	for _, tc := range []struct {
+		name    string
+		userID  int64
+		allowed bool
+	}{
+		{"owner", 1, true},
+		{"co-host", 2, true},
+		{"chat admin", 3, true},
+		{"participant", 4, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := service.CreateGame("mock-event-id", nil, tc.userID, "Chess", nil, nil, "")
+			if tc.allowed && err != nil {
+				t.Fatalf("Expected %s to add a game to the locked event, got %v", tc.name, err)
+			}
+			if !tc.allowed && err == nil {
+				t.Fatalf("Expected %s not to add a game to the locked event", tc.name)
+			}
+		})
+	}
+}
+
 func TestAddPlayerToPlayerCounterGame(t *testing.T) {
 	service := BeforeEach()
 	db := service.DB.(*mocks.MockDatabase)
@@ -344,7 +392,7 @@ func TestCreateGame(t *testing.T) {
 	// telegram := service.Bot.(*mocks.MockTelegramService)
 
 	isGameInserted := false
-	db.InsertBoardGameFunc = func(eventID string, id *string, name string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
+	db.InsertBoardGameFunc = func(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
 		if eventID != "mock-event-id" {
 			t.Fatalf("Expected eventID 'mock-event-id', got '%s'", eventID)
 		}
@@ -425,7 +473,7 @@ func TestCreateGame(t *testing.T) {
 	}
 
 	maxPlayer := 4
-	_, bg, err := service.CreateGame("mock-event-id", nil, 123456, "Test Game", &maxPlayer, nil)
+	_, bg, err := service.CreateGame("mock-event-id", nil, 123456, "Test Game", &maxPlayer, nil, "")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -457,7 +505,7 @@ func TestCreateGameWithoutBGGID(t *testing.T) {
 	bggMock := service.BGG.(*mocks.MockBGGService)
 
 	isGameInserted := false
-	db.InsertBoardGameFunc = func(eventID string, id *string, name string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
+	db.InsertBoardGameFunc = func(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
 		if eventID != "mock-event-id" {
 			t.Fatalf("Expected eventID 'mock-event-id', got '%s'", eventID)
 		}
@@ -528,7 +576,7 @@ func TestCreateGameWithoutBGGID(t *testing.T) {
 
 	maxPlayer := 4
 	bggUrl := fmt.Sprintf("https://boardgamegeek.com/boardgame/%d/azul", bggID)
-	_, bg, err := service.CreateGame("mock-event-id", nil, 123456, "Test Game", &maxPlayer, &bggUrl)
+	_, bg, err := service.CreateGame("mock-event-id", nil, 123456, "Test Game", &maxPlayer, &bggUrl, "")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -553,7 +601,7 @@ func TestCreateGameRespectMaxPlayer(t *testing.T) {
 
 	requestedMaxPlayer := 8
 	isGameInserted := false
-	db.InsertBoardGameFunc = func(eventID string, id *string, name string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
+	db.InsertBoardGameFunc = func(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
 		if maxPlayers != requestedMaxPlayer {
 			t.Fatalf("Expected maxPlayers %d, got %d", requestedMaxPlayer, maxPlayers)
 		}
@@ -607,7 +655,7 @@ func TestCreateGameRespectMaxPlayer(t *testing.T) {
 	}
 
 	bggUrl := fmt.Sprintf("https://boardgamegeek.com/boardgame/%d/azul", bggID)
-	_, bg, err := service.CreateGame("mock-event-id", nil, 123456, "Test Game", &requestedMaxPlayer, &bggUrl)
+	_, bg, err := service.CreateGame("mock-event-id", nil, 123456, "Test Game", &requestedMaxPlayer, &bggUrl, "")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -632,7 +680,7 @@ func TestCreateGameRespectMaxPlayerNoUrl(t *testing.T) {
 
 	requestedMaxPlayer := 8
 	isGameInserted := false
-	db.InsertBoardGameFunc = func(eventID string, id *string, name string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
+	db.InsertBoardGameFunc = func(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
 		if maxPlayers != requestedMaxPlayer {
 			t.Fatalf("Expected maxPlayers %d, got %d", requestedMaxPlayer, maxPlayers)
 		}
@@ -694,7 +742,7 @@ func TestCreateGameRespectMaxPlayerNoUrl(t *testing.T) {
 		}, nil
 	}
 
-	_, bg, err := service.CreateGame("mock-event-id", nil, 123456, "Test Game", &requestedMaxPlayer, nil)
+	_, bg, err := service.CreateGame("mock-event-id", nil, 123456, "Test Game", &requestedMaxPlayer, nil, "")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -1057,7 +1105,7 @@ func TestDeletePlayer(t *testing.T) {
 	participantID := "mock-participant-id"
 	isParticipantDeleted := false
 
-	db.RemoveParticipantFunc = func(eID string, uID int64) (string, int64, error) {
+	db.RemoveParticipantFunc = func(eID string, uID int64, bgID *int64) (string, int64, error) {
 		if eID != eventID {
 			t.Fatalf("Expected eventID %s, got %s", eventID, eID)
 		}
@@ -1094,7 +1142,7 @@ func TestDeletePlayer(t *testing.T) {
 		return &telebot.Message{ID: 1}, nil
 	}
 
-	_, _, _, err := service.DeletePlayer(eventID, userID)
+	_, _, _, err := service.DeletePlayer(eventID, userID, nil)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -1106,3 +1154,1085 @@ func TestDeletePlayer(t *testing.T) {
 		t.Fatalf("Expected Telegram message to be updated")
 	}
 }
+
+func TestRunBatchRefreshesMessageOnce(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	eventID := "mock-event-id"
+	gameID := int64(123456)
+	messageID := int64(11111)
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:        eventID,
+			ChatID:    12345,
+			UserID:    67890,
+			MessageID: &messageID,
+			Name:      "event",
+			BoardGames: []models.BoardGame{{
+				ID:         gameID,
+				UUID:       "mock-game-uuid",
+				Name:       "Test Game",
+				MaxPlayers: 4,
+			}},
+		}, nil
+	}
+
+	inTransaction := false
+	db.WithTransactionFunc = func(fn func(db database.DatabaseService) error) error {
+		inTransaction = true
+		defer func() { inTransaction = false }()
+		return fn(db)
+	}
+
+	edits := 0
+	telegram.EditFunc = func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		if inTransaction {
+			t.Fatalf("Expected Telegram message to be updated after commit")
+		}
+		edits++
+		return &telebot.Message{ID: 1}, nil
+	}
+
+	err := service.RunBatch(nil, func(tx *Service) error {
+		for userID := int64(1); userID <= 3; userID++ {
+			if _, _, _, err := tx.AddPlayer(nil, eventID, gameID, userID, "user", false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if edits != 1 {
+		t.Fatalf("Expected Telegram message to be updated once, got %d", edits)
+	}
+}
+
+func TestRunBatchFailureSkipsTelegram(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	eventID := "mock-event-id"
+	gameID := int64(123456)
+	messageID := int64(11111)
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:        eventID,
+			ChatID:    12345,
+			UserID:    67890,
+			MessageID: &messageID,
+			Name:      "event",
+			BoardGames: []models.BoardGame{{
+				ID:         gameID,
+				UUID:       "mock-game-uuid",
+				Name:       "Test Game",
+				MaxPlayers: 4,
+			}},
+		}, nil
+	}
+
+	db.DeleteBoardGameByIDFunc = func(ID string) error {
+		return nil
+	}
+
+	telegram.EditFunc = func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		t.Fatalf("Expected no Telegram message update for a failed batch")
+		return nil, nil
+	}
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		t.Fatalf("Expected no Telegram message for a failed batch")
+		return nil, nil
+	}
+
+	err := service.RunBatch(nil, func(tx *Service) error {
+		if _, _, err := tx.DeleteGame(eventID, "mock-game-uuid", 67890, "owner"); err != nil {
+			return err
+		}
+		return errors.New("operation failed")
+	})
+
+	if err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func TestRunBatchFetchesBGGBeforeTransaction(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	bggMock := service.BGG.(*mocks.MockBGGService)
+
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{ID: eventID, ChatID: 12345, UserID: 67890, Name: "event"}, nil
+	}
+
+	var bggName *string
+	db.InsertBoardGameFunc = func(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggNameArg, bggUrl, bggImageUrl *string) (int64, string, error) {
+		bggName = bggNameArg
+		return 1, "mock-game-uuid", nil
+	}
+
+	inTransaction := false
+	db.WithTransactionFunc = func(fn func(db database.DatabaseService) error) error {
+		inTransaction = true
+		defer func() { inTransaction = false }()
+		return fn(db)
+	}
+
+	calls := 0
+	bggMock.ExtractGameInfoFunc = func(ctx context.Context, id int64, gameName string) (*models.BggInfo, error) {
+		if inTransaction {
+			t.Fatalf("Expected BGG to be queried before the transaction")
+		}
+		calls++
+		name := "Azul"
+		return &models.BggInfo{Name: &name}, nil
+	}
+
+	bggUrl := "https://boardgamegeek.com/boardgame/230802/azul"
+	queries := []BGGQuery{{URL: &bggUrl, Name: "Azul"}, {URL: &bggUrl, Name: "Azul"}}
+	err := service.RunBatch(queries, func(tx *Service) error {
+		_, _, err := tx.CreateGame("mock-event-id", nil, 67890, "Azul", nil, &bggUrl, "")
+		return err
+	})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected BGG to be queried once, got %d", calls)
+	}
+	if bggName == nil || *bggName != "Azul" {
+		t.Fatalf("Expected the prefetched BGG name to be stored, got %v", bggName)
+	}
+}
+
+func TestRunBatchSharesGameLocks(t *testing.T) {
+	service := BeforeEach()
+
+	err := service.RunBatch(nil, func(tx *Service) error {
+		unlock := tx.lockGame(1)
+		defer unlock()
+
+		if _, ok := service.gameUpdateMu.Load(int64(1)); !ok {
+			t.Fatalf("Expected the game lock to be shared with the batch")
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+type recordingNotifier struct {
+	payloads []models.HookWebhookEnvelope
+}
+
+func (r *recordingNotifier) SendAllWebhookAsync(ctx context.Context, chatID int64, payload models.HookWebhookEnvelope) {
+	r.payloads = append(r.payloads, payload)
+}
+
+func TestSetEventLocked(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	notifier := &recordingNotifier{}
+	service.Hook = notifier
+
+	messageID := int64(11111)
+	locked := false
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:        eventID,
+			ChatID:    12345,
+			UserID:    67890,
+			MessageID: &messageID,
+			Name:      "Game night",
+			Locked:    locked,
+		}, nil
+	}
+	db.UpdateEventLockedFunc = func(eventID string, l bool) error {
+		locked = l
+		return nil
+	}
+
+	event, err := service.SetEventLocked("mock-event-id", 67890, "owner", true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !locked || !event.Locked || event.Name != "Game night" {
+		t.Fatalf("Expected event to be locked, got %+v", event)
+	}
+
+	types := []models.HookWebhookType{}
+	for _, p := range notifier.payloads {
+		types = append(types, p.Type)
+	}
+	if len(types) != 2 || types[0] != models.HookWebhookTypeUpdateEvent || types[1] != models.HookWebhookTypeLockEvent {
+		t.Fatalf("Expected update_event and lock_event webhooks, got %v", types)
+	}
+
+	if _, err = service.SetEventLocked("mock-event-id", 67890, "owner", false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if locked {
+		t.Fatal("Expected event to be unlocked")
+	}
+}
+
+func TestSetEventLockedRejectsNonOwner(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{ID: eventID, ChatID: 12345, UserID: 67890, Name: "Game night"}, nil
+	}
+	db.UpdateEventLockedFunc = func(eventID string, locked bool) error {
+		t.Fatalf("Expected event not to be updated")
+		return nil
+	}
+
+	if _, err := service.SetEventLocked("mock-event-id", 1, "someone", true); !errors.Is(err, ErrNotEventOwner) {
+		t.Fatalf("Expected ErrNotEventOwner, got %v", err)
+	}
+}
+
+func TestSetCoHost(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	notifier := &recordingNotifier{}
+	service.Hook = notifier
+
+	messageID := int64(11111)
+	coHosts := []models.CoHost{}
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{ID: eventID, ChatID: -100, UserID: 1, MessageID: &messageID, Name: "Game night", CoHosts: coHosts}, nil
+	}
+	db.AddEventCoHostFunc = func(eventID string, userID int64, userName string) error {
+		coHosts = append(coHosts, models.CoHost{UserID: userID, UserName: userName})
+		return nil
+	}
+	db.RemoveEventCoHostFunc = func(eventID string, userID int64) (bool, error) {
+		for i, coHost := range coHosts {
+			if coHost.UserID == userID {
+				coHosts = append(coHosts[:i], coHosts[i+1:]...)
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if _, err := service.SetCoHost("mock-event-id", 2, 3, "mario", true); !errors.Is(err, ErrNotEventOwner) {
+		t.Fatalf("Expected ErrNotEventOwner, got %v", err)
+	}
+
+	event, err := service.SetCoHost("mock-event-id", 1, 2, "anna", true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !event.IsCoHost(2) || event.IsCoHost(3) {
+		t.Fatalf("Expected anna to be the only co-host, got %+v", event.CoHosts)
+	}
+
+	last := notifier.payloads[len(notifier.payloads)-1]
+	if last.Type != models.HookWebhookTypeUpdateCoHosts || len(last.Data.(models.HookCoHostsPayload).CoHosts) != 1 {
+		t.Fatalf("Expected update_cohosts webhook with anna, got %+v", last)
+	}
+
+	if _, err = service.SetCoHost("mock-event-id", 1, 3, "mario", false); !errors.Is(err, ErrNotCoHost) {
+		t.Fatalf("Expected ErrNotCoHost, got %v", err)
+	}
+	if _, err = service.SetCoHost("mock-event-id", 1, 2, "anna", false); err != nil || len(coHosts) != 0 {
+		t.Fatalf("Expected anna to be removed, got %v and %+v", err, coHosts)
+	}
+}
+
+func TestSetRSVPDeadlineNotifiesEventUpdate(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	notifier := &recordingNotifier{}
+	service.Hook = notifier
+
+	messageID := int64(11111)
+	var stored *time.Time
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{ID: eventID, ChatID: 12345, UserID: 1, MessageID: &messageID, Name: "Game night", RSVPDeadline: stored}, nil
+	}
+	db.UpdateEventRSVPDeadlineFunc = func(eventID string, deadline *time.Time) error {
+		stored = deadline
+		return nil
+	}
+
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	if _, err := service.SetRSVPDeadline("mock-event-id", 1, &deadline); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(notifier.payloads) != 1 || notifier.payloads[0].Type != models.HookWebhookTypeUpdateEvent {
+		t.Fatalf("Expected a single update_event webhook, got %+v", notifier.payloads)
+	}
+	if payload := notifier.payloads[0].Data.(models.HookUpdateEventPayload); payload.RSVPDeadline == nil || !payload.RSVPDeadline.Equal(deadline) {
+		t.Errorf("Expected the new deadline in the payload, got %+v", payload)
+	}
+}
+
+func TestCreateEventAutoPin(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	db.GetAutoPinFunc = func(chatID int64) bool { return true }
+	pinnedInDB := false
+	db.UpdateEventPinnedFunc = func(eventID string, pinned bool) error {
+		pinnedInDB = pinned
+		return nil
+	}
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		return &telebot.Message{ID: 42}, nil
+	}
+	var pinnedID string
+	telegram.PinFunc = func(msg telebot.Editable, opts ...interface{}) error {
+		pinnedID, _ = msg.MessageSig()
+		return nil
+	}
+
+	event, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event", nil, nil, false, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pinnedID != "42" {
+		t.Errorf("Expected event message 42 to be pinned, got %q", pinnedID)
+	}
+	if !event.Pinned || !pinnedInDB {
+		t.Error("Expected event to be marked as pinned")
+	}
+}
+
+func TestCreateEventAutoPinWithoutRights(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	db.GetAutoPinFunc = func(chatID int64) bool { return true }
+	db.UpdateEventPinnedFunc = func(eventID string, pinned bool) error {
+		t.Error("Expected event not to be marked as pinned")
+		return nil
+	}
+	sent := 0
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		sent++
+		return &telebot.Message{ID: 42}, nil
+	}
+	telegram.PinFunc = func(msg telebot.Editable, opts ...interface{}) error {
+		return errors.New("not enough rights to manage pinned messages in the chat")
+	}
+
+	event, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event", nil, nil, false, true)
+	if err != nil {
+		t.Fatalf("Expected event creation to succeed without pin rights, got %v", err)
+	}
+	if event.Pinned {
+		t.Error("Expected event not to be pinned")
+	}
+	if sent != 2 {
+		t.Errorf("Expected the event and a notice to be sent, got %d messages", sent)
+	}
+}
+
+func TestSetAutoPinRequiresChatAdmin(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	var saved *bool
+	db.SetAutoPinFunc = func(chatID int64, enabled bool) error {
+		saved = &enabled
+		return nil
+	}
+	telegram.AdminsOfFunc = func(chat *telebot.Chat) ([]telebot.ChatMember, error) {
+		return []telebot.ChatMember{{User: &telebot.User{ID: 7}, Role: telebot.Administrator}}, nil
+	}
+
+	if err := service.SetAutoPin(-100, 3, true); !errors.Is(err, ErrNotChatAdmin) {
+		t.Fatalf("Expected ErrNotChatAdmin, got %v", err)
+	}
+	if saved != nil {
+		t.Fatalf("Expected the setting not to change")
+	}
+
+	if err := service.SetAutoPin(-100, 7, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if saved == nil || !*saved {
+		t.Fatalf("Expected auto pin to be enabled")
+	}
+}
+
+func TestUnpinEndedEvents(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	now := time.Date(2026, 6, 1, 23, 0, 0, 0, time.UTC)
+	ended := now.Add(-3 * time.Hour)
+	running := now.Add(-time.Hour)
+	messageID := int64(7)
+	db.SelectPinnedEventsFunc = func() ([]models.Event, error) {
+		return []models.Event{
+			{ID: "ended", ChatID: 1, MessageID: &messageID, StartsAt: &ended, Pinned: true},
+			{ID: "running", ChatID: 1, MessageID: &messageID, StartsAt: &running, Pinned: true},
+			{ID: "undated", ChatID: 1, MessageID: &messageID, Pinned: true},
+		}, nil
+	}
+	unpinnedInDB := []string{}
+	db.UpdateEventPinnedFunc = func(eventID string, pinned bool) error {
+		if !pinned {
+			unpinnedInDB = append(unpinnedInDB, eventID)
+		}
+		return nil
+	}
+	unpins := 0
+	telegram.UnpinFunc = func(chat telebot.Recipient, ids ...int) error {
+		unpins++
+		return nil
+	}
+
+	service.UnpinEndedEvents(now)
+
+	if unpins != 1 || len(unpinnedInDB) != 1 || unpinnedInDB[0] != "ended" {
+		t.Errorf("Expected only the ended event to be unpinned, got %d unpins and %v", unpins, unpinnedInDB)
+	}
+}
+
+func TestCreateEventInTopic(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	db.GetEventTopicsFunc = func(chatID int64) bool { return true }
+	var storedTopic int64
+	db.UpdateEventTopicFunc = func(eventID string, topicID int64) error {
+		storedTopic = topicID
+		return nil
+	}
+	var topicName string
+	telegram.CreateTopicFunc = func(chat *telebot.Chat, topic *telebot.Topic) (*telebot.Topic, error) {
+		topicName = topic.Name
+		return &telebot.Topic{Name: topic.Name, ThreadID: 99}, nil
+	}
+	var threadID int
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		for _, opt := range opts {
+			if o, ok := opt.(*telebot.SendOptions); ok {
+				threadID = o.ThreadID
+			}
+		}
+		return &telebot.Message{ID: 42}, nil
+	}
+
+	event, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event", nil, nil, false, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if topicName != event.Name {
+		t.Errorf("Expected topic to be named %q, got %q", event.Name, topicName)
+	}
+	if threadID != 99 {
+		t.Errorf("Expected event to be posted in topic 99, got %d", threadID)
+	}
+	if event.TopicID == nil || *event.TopicID != 99 || storedTopic != 99 {
+		t.Errorf("Expected topic 99 to be stored on the event, got %v and %d", event.TopicID, storedTopic)
+	}
+}
+
+func TestCreateEventInTopicNotAllowed(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	db.GetEventTopicsFunc = func(chatID int64) bool { return true }
+	db.UpdateEventTopicFunc = func(eventID string, topicID int64) error {
+		t.Error("Expected no topic to be stored")
+		return nil
+	}
+	telegram.CreateTopicFunc = func(chat *telebot.Chat, topic *telebot.Topic) (*telebot.Topic, error) {
+		return nil, errors.New("the chat is not a forum")
+	}
+	sent := 0
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		sent++
+		return &telebot.Message{ID: 42}, nil
+	}
+
+	event, err := service.CreateEvent(12345, nil, nil, 67890, "testuser", "Test Event", nil, nil, false, true)
+	if err != nil {
+		t.Fatalf("Expected event creation to succeed without a topic, got %v", err)
+	}
+	if event.TopicID != nil {
+		t.Error("Expected event not to have a topic")
+	}
+	if sent != 2 {
+		t.Errorf("Expected the event and a notice to be sent, got %d messages", sent)
+	}
+}
+
+func TestCloseEndedEventTopics(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	now := time.Date(2026, 6, 1, 23, 0, 0, 0, time.UTC)
+	ended := now.Add(-3 * time.Hour)
+	running := now.Add(-time.Hour)
+	topicID := int64(99)
+	db.SelectOpenTopicEventsFunc = func() ([]models.Event, error) {
+		return []models.Event{
+			{ID: "ended", ChatID: 1, TopicID: &topicID, StartsAt: &ended},
+			{ID: "running", ChatID: 1, TopicID: &topicID, StartsAt: &running},
+			{ID: "undated", ChatID: 1, TopicID: &topicID},
+		}, nil
+	}
+	closedInDB := []string{}
+	db.CloseEventTopicFunc = func(eventID string) error {
+		closedInDB = append(closedInDB, eventID)
+		return nil
+	}
+	var closedTopics []int
+	telegram.CloseTopicFunc = func(chat *telebot.Chat, topic *telebot.Topic) error {
+		closedTopics = append(closedTopics, topic.ThreadID)
+		return nil
+	}
+
+	service.CloseEndedEventTopics(now)
+
+	if len(closedTopics) != 1 || closedTopics[0] != 99 || len(closedInDB) != 1 || closedInDB[0] != "ended" {
+		t.Errorf("Expected only the topic of the ended event to be closed, got %v and %v", closedTopics, closedInDB)
+	}
+}
+
+func TestArchiveEndedEvents(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	now := time.Date(2026, 6, 1, 23, 0, 0, 0, time.UTC)
+	ended := now.Add(-3 * time.Hour)
+	running := now.Add(-time.Hour)
+	messageID := int64(7)
+	gameMessageID := int64(8)
+	db.SelectEventsToArchiveFunc = func() ([]models.Event, error) {
+		return []models.Event{
+			{ID: "ended", ChatID: 1, MessageID: &messageID, StartsAt: &ended, BoardGames: []models.BoardGame{
+				{ID: 1, Name: "Catan", MessageID: &gameMessageID},
+				{ID: 2, Name: "Azul"},
+			}},
+			{ID: "running", ChatID: 1, MessageID: &messageID, StartsAt: &running},
+			{ID: "undated", ChatID: 1, MessageID: &messageID},
+		}, nil
+	}
+	finished := []string{}
+	db.SetEventFinishedFunc = func(eventID string) error {
+		finished = append(finished, eventID)
+		return nil
+	}
+	var edited []string
+	telegram.EditMarkupFunc = func(msg telebot.Editable, markup *telebot.ReplyMarkup) (*telebot.Message, error) {
+		id, _ := msg.MessageSig()
+		edited = append(edited, id)
+		if markup != nil && len(markup.InlineKeyboard) > 0 {
+			t.Errorf("Expected the buttons of message %s to be removed", id)
+		}
+		return &telebot.Message{}, nil
+	}
+	telegram.EditFunc = func(msg telebot.Editable, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		t.Error("Expected only the buttons of the message to be edited")
+		return &telebot.Message{}, nil
+	}
+
+	service.ArchiveEndedEvents(now)
+
+	if len(finished) != 1 || finished[0] != "ended" || len(edited) != 2 || edited[0] != "7" || edited[1] != "8" {
+		t.Errorf("Expected only the ended event to be archived, got %v and edits %v", finished, edited)
+	}
+}
+
+func TestFinishedEventRejectsChanges(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:       eventID,
+			ChatID:   1,
+			UserID:   1,
+			Finished: true,
+			BoardGames: []models.BoardGame{
+				{ID: 1, UUID: "game-uuid", Name: "Catan", MaxPlayers: 4, Participants: []models.Participant{{UserID: 2, UserName: "anna"}}},
+			},
+		}, nil
+	}
+	db.InsertParticipantFunc = func(id *string, eventID string, boardgameID, userID int64, userName string, isTelegramUsername bool) (string, error) {
+		t.Error("Expected no participant to be added to a finished event")
+		return "", nil
+	}
+	db.InsertBoardGameFunc = func(eventID string, id *string, name, slot string, maxPlayers int, bggID *int64, bggName, bggUrl, bggImageUrl *string) (int64, string, error) {
+		t.Error("Expected no game to be added to a finished event")
+		return 0, "", nil
+	}
+
+	changes := map[string]func() error{
+		"create game": func() error {
+			_, _, err := service.CreateGame("event", nil, 1, "Azul", nil, nil, "")
+			return err
+		},
+		"delete game": func() error {
+			_, _, err := service.DeleteGame("event", "game-uuid", 1, "host")
+			return err
+		},
+		"join": func() error {
+			_, _, _, err := service.AddPlayer(nil, "event", 1, 3, "luca", false)
+			return err
+		},
+		"leave": func() error {
+			_, _, _, err := service.DeletePlayer("event", 2, nil)
+			return err
+		},
+		"maybe": func() error {
+			_, _, err := service.SetRSVP("event", 3, "luca", false, models.RSVPMaybe)
+			return err
+		},
+		"guest": func() error {
+			_, _, err := service.AddGuest("event", 2, "")
+			return err
+		},
+		"kick": func() error {
+			_, err := service.KickParticipant("event", 1, "host", 2)
+			return err
+		},
+		"lock": func() error {
+			_, err := service.SetEventLocked("event", 1, "host", true)
+			return err
+		},
+	}
+	for name, change := range changes {
+		if err := change(); !errors.Is(err, ErrEventFinished) {
+			t.Errorf("%s: expected ErrEventFinished, got %v", name, err)
+		}
+	}
+}
+
+func TestDeletePlayerNotifiesPromotedUser(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		service := BeforeEach()
+		db := service.DB.(*mocks.MockDatabase)
+		telegram := service.Bot.(*mocks.MockTelegramService)
+
+		messageID := int64(11111)
+		game := models.BoardGame{ID: 1, UUID: "mock-game-uuid", Name: "Catan", MaxPlayers: 1}
+		loads := 0
+		db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+			loads++
+			participants := []models.Participant{{ID: 2, UserID: 2, UserName: "queued"}}
+			if loads == 1 {
+				participants = append([]models.Participant{{ID: 1, UserID: 1, UserName: "leaving"}}, participants...)
+			}
+			bg := game
+			bg.Participants = participants
+			return &models.Event{ID: eventID, ChatID: 12345, MessageID: &messageID, Name: "Game night", BoardGames: []models.BoardGame{bg}}, nil
+		}
+		db.RemoveParticipantFunc = func(eventID string, userID int64, boardgameID *int64) (string, int64, error) {
+			return "mock-participant-id", game.ID, nil
+		}
+		db.GetNotificationPreferencesFunc = func(userID int64) models.NotificationPreferences {
+			return models.NotificationPreferences{Waitlist: enabled, Cancellations: true}
+		}
+		var directTo []string
+		var direct string
+		telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+			directTo = append(directTo, to.Recipient())
+			direct, _ = what.(string)
+			return &telebot.Message{ID: 1}, nil
+		}
+
+		if _, _, _, err := service.DeletePlayer("mock-event-id", 1, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !enabled {
+			if len(directTo) != 0 {
+				t.Errorf("Expected no direct message when disabled, got %v", directTo)
+			}
+			continue
+		}
+		if len(directTo) != 1 || directTo[0] != "2" {
+			t.Fatalf("Expected a direct message to user 2, got %v", directTo)
+		}
+		if !strings.Contains(direct, "Catan") || !strings.Contains(direct, "Game night") {
+			t.Errorf("Unexpected direct message %q", direct)
+		}
+	}
+}
+
+func TestDeleteEventNotifiesParticipants(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	messageID := int64(11111)
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:        eventID,
+			ChatID:    12345,
+			UserID:    1,
+			MessageID: &messageID,
+			Name:      "Game night",
+			BoardGames: []models.BoardGame{{
+				ID:           1,
+				Name:         "Catan",
+				MaxPlayers:   4,
+				Participants: []models.Participant{{UserID: 1}, {UserID: 2}, {UserID: 3}},
+			}},
+		}, nil
+	}
+	db.GetNotificationPreferencesFunc = func(userID int64) models.NotificationPreferences {
+		return models.NotificationPreferences{Waitlist: true, Cancellations: userID != 3}
+	}
+	recipients := []string{}
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		if _, ok := to.(*telebot.User); ok {
+			recipients = append(recipients, to.Recipient())
+		}
+		return &telebot.Message{}, nil
+	}
+
+	deleter := int64(1)
+	if err := service.DeleteEvent("mock-event-id", &deleter, "host"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(recipients) != 1 || recipients[0] != "2" {
+		t.Errorf("Expected only user 2 to be notified, got %v", recipients)
+	}
+}
+
+func TestAddGuest(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+
+	messageID := int64(11111)
+	guests := []string{}
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:        eventID,
+			ChatID:    12345,
+			MessageID: &messageID,
+			BoardGames: []models.BoardGame{{
+				ID:           1,
+				UUID:         "mock-game-uuid",
+				Name:         "Catan",
+				MaxPlayers:   4,
+				Participants: []models.Participant{{ID: 1, UserID: 1, UserName: "host", Guests: guests}},
+			}},
+		}, nil
+	}
+	db.UpdateParticipantGuestsFunc = func(eventID string, userID, boardgameID int64, updated []string) error {
+		guests = updated
+		return nil
+	}
+
+	if _, _, err := service.AddGuest("mock-event-id", 2, "Anna"); !errors.Is(err, database.ErrNoRows) {
+		t.Errorf("Expected ErrNoRows for a user not taking part, got %v", err)
+	}
+
+	_, game, err := service.AddGuest("mock-event-id", 1, "Anna")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(guests) != 1 || guests[0] != "Anna" || game.SeatCount() != 2 {
+		t.Errorf("Expected Anna to take a seat, got %v", guests)
+	}
+
+	guests = make([]string, models.MaxGuests)
+	if _, _, err = service.AddGuest("mock-event-id", 1, ""); !errors.Is(err, ErrTooManyGuests) {
+		t.Errorf("Expected ErrTooManyGuests, got %v", err)
+	}
+
+	guests = []string{}
+	if _, _, err = service.RemoveGuest("mock-event-id", 1); !errors.Is(err, ErrNoGuests) {
+		t.Errorf("Expected ErrNoGuests, got %v", err)
+	}
+}
+
+func TestRemindMaybeUsers(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	now := time.Now()
+	soon := now.Add(2 * time.Hour)
+	later := now.Add(3 * 24 * time.Hour)
+	started := now.Add(-time.Hour)
+	tentative := []models.Participant{{UserID: 2, UserName: "unsure"}, {UserID: 3, UserName: "quiet"}}
+	db.SelectMaybeEventsToRemindFunc = func() ([]models.Event, error) {
+		return []models.Event{
+			{ID: "soon", ChatID: 12345, Name: "Game night", StartsAt: &soon, Tentative: tentative},
+			{ID: "later", ChatID: 12345, Name: "Next week", StartsAt: &later, Tentative: tentative},
+			{ID: "started", ChatID: 12345, Name: "Tonight", StartsAt: &started, Tentative: tentative},
+		}, nil
+	}
+	db.GetNotificationPreferencesFunc = func(userID int64) models.NotificationPreferences {
+		return models.NotificationPreferences{Reminders: userID != 3}
+	}
+	var reminded []string
+	db.SetMaybeRemindedFunc = func(eventID string) error {
+		reminded = append(reminded, eventID)
+		return nil
+	}
+	var recipients []string
+	var message string
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		recipients = append(recipients, to.Recipient())
+		message, _ = what.(string)
+		return &telebot.Message{}, nil
+	}
+
+	service.RemindMaybeUsers(now)
+
+	if len(recipients) != 1 || recipients[0] != "2" || !strings.Contains(message, "Game night") {
+		t.Errorf("Expected only user 2 to be reminded of Game night, got %v %q", recipients, message)
+	}
+	if len(reminded) != 2 || reminded[0] != "soon" || reminded[1] != "started" {
+		t.Errorf("Expected the events within the lead time to be marked, got %v", reminded)
+	}
+}
+
+func TestRSVPDeadlineRejectsChanges(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+
+	ownerID := int64(99999)
+	messageID := int64(11111)
+	deadline := time.Now().Add(-time.Minute)
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		return &models.Event{
+			ID:           eventID,
+			ChatID:       12345,
+			UserID:       ownerID,
+			MessageID:    &messageID,
+			RSVPDeadline: &deadline,
+			BoardGames: []models.BoardGame{{
+				ID:           1,
+				Name:         "Catan",
+				MaxPlayers:   4,
+				Participants: []models.Participant{{UserID: 1, UserName: "host"}},
+			}},
+		}, nil
+	}
+	inserted := false
+	db.InsertParticipantFunc = func(id *string, eventID string, boardgameID, userID int64, userName string, isTelegramUsername bool) (string, error) {
+		inserted = true
+		return "mock-participant-uuid", nil
+	}
+
+	if _, _, _, err := service.AddPlayer(nil, "mock-event-id", 1, 2, "late", true); !errors.Is(err, ErrRSVPClosed) {
+		t.Errorf("Expected ErrRSVPClosed when joining, got %v", err)
+	}
+	if inserted {
+		t.Error("Expected no participant to be inserted after the deadline")
+	}
+	if _, _, err := service.AddGuest("mock-event-id", 1, ""); !errors.Is(err, ErrRSVPClosed) {
+		t.Errorf("Expected ErrRSVPClosed when bringing a guest, got %v", err)
+	}
+	if _, _, err := service.CreateGame("mock-event-id", nil, 2, "Chess", nil, nil, ""); !errors.Is(err, ErrRSVPClosed) {
+		t.Errorf("Expected ErrRSVPClosed when adding a game, got %v", err)
+	}
+	if _, _, err := service.CreateGame("mock-event-id", nil, ownerID, "Chess", nil, nil, ""); err != nil {
+		t.Errorf("Expected the owner to add games after the deadline, got %v", err)
+	}
+	if _, _, _, err := service.AddPlayer(nil, "mock-event-id", 1, ownerID, "owner", true); err != nil || !inserted {
+		t.Errorf("Expected the owner to join after the deadline, got %v", err)
+	}
+}
+
+func TestPostFinalLineups(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+
+	now := time.Now()
+	passed := now.Add(-time.Minute)
+	upcoming := now.Add(time.Hour)
+	messageID := int64(11111)
+	games := []models.BoardGame{{
+		ID:           1,
+		Name:         "Catan",
+		MaxPlayers:   1,
+		Participants: []models.Participant{{UserID: 1, UserName: "first", IsTelegramUsername: true}, {UserID: 2, UserName: "second", IsTelegramUsername: true}},
+	}}
+	db.SelectPendingLineupEventsFunc = func() ([]models.Event, error) {
+		return []models.Event{
+			{ID: "passed", ChatID: 12345, Name: "Game night", MessageID: &messageID, RSVPDeadline: &passed, BoardGames: games},
+			{ID: "upcoming", ChatID: 12345, Name: "Next week", MessageID: &messageID, RSVPDeadline: &upcoming, BoardGames: games},
+		}, nil
+	}
+	var posted []string
+	db.SetLineupPostedFunc = func(eventID string) error {
+		posted = append(posted, eventID)
+		return nil
+	}
+	var lineups []string
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		text, _ := what.(string)
+		lineups = append(lineups, text)
+		return &telebot.Message{}, nil
+	}
+
+	service.PostFinalLineups(now)
+
+	if len(posted) != 1 || posted[0] != "passed" {
+		t.Fatalf("Expected only the lineup of the passed deadline to be posted, got %v", posted)
+	}
+	if len(lineups) != 1 || !strings.Contains(lineups[0], "Final lineup of <b>Game night</b>") || !strings.Contains(lineups[0], "@second (queued 1)") {
+		t.Errorf("Unexpected lineup %q", lineups)
+	}
+}
+
+func TestKickParticipant(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+	notifier := &recordingNotifier{}
+	service.Hook = notifier
+
+	messageID := int64(11111)
+	kicked := false
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		event := &models.Event{
+			ID:        eventID,
+			ChatID:    -100,
+			UserID:    1,
+			Name:      "Game night",
+			MessageID: &messageID,
+			CoHosts:   []models.CoHost{{UserID: 2, UserName: "cohost"}},
+			BoardGames: []models.BoardGame{{
+				ID:         10,
+				UUID:       "catan-uuid",
+				Name:       "Catan",
+				MaxPlayers: 4,
+			}},
+		}
+		if !kicked {
+			event.BoardGames[0].Participants = []models.Participant{{UUID: "seat-uuid", UserID: 3, UserName: "mistake"}}
+		}
+		return event, nil
+	}
+	db.RemoveParticipantFunc = func(eventID string, userID int64, boardgameID *int64) (string, int64, error) {
+		if userID != 3 {
+			t.Fatalf("Expected user 3 to be removed, got %d", userID)
+		}
+		kicked = true
+		return "seat-uuid", 10, nil
+	}
+	var recipients []string
+	var message string
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		recipients = append(recipients, to.Recipient())
+		message, _ = what.(string)
+		return &telebot.Message{}, nil
+	}
+
+	if _, err := service.KickParticipant("mock-event-id", 4, "someone", 3); !errors.Is(err, ErrNotHost) {
+		t.Fatalf("Expected ErrNotHost, got %v", err)
+	}
+	if _, err := service.KickParticipant("mock-event-id", 2, "cohost", 5); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected ErrNoRows for a user not in the event, got %v", err)
+	}
+
+	if _, err := service.KickParticipant("mock-event-id", 2, "cohost", 3); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !kicked {
+		t.Fatal("Expected the seat to be removed")
+	}
+	if len(recipients) != 1 || recipients[0] != "3" || !strings.Contains(message, "cohost removed you from <b>Game night</b>") {
+		t.Errorf("Expected user 3 to be told, got %v %q", recipients, message)
+	}
+
+	types := []models.HookWebhookType{}
+	for _, p := range notifier.payloads {
+		types = append(types, p.Type)
+	}
+	if len(types) != 2 || types[0] != models.HookWebhookTypeRemoveParticipant || types[1] != models.HookWebhookTypeKickParticipant {
+		t.Fatalf("Expected remove_participant and kick_participant webhooks, got %v", types)
+	}
+	if payload := notifier.payloads[1].Data.(models.HookKickParticipantPayload); payload.GameID != "catan-uuid" || payload.HostID != 2 {
+		t.Errorf("Unexpected kick payload %+v", payload)
+	}
+}
+
+func TestMoveParticipant(t *testing.T) {
+	service := BeforeEach()
+	db := service.DB.(*mocks.MockDatabase)
+	telegram := service.Bot.(*mocks.MockTelegramService)
+	notifier := &recordingNotifier{}
+	service.Hook = notifier
+
+	messageID := int64(11111)
+	gameOf := int64(10)
+	db.SelectEventByEventIDFunc = func(eventID string) (*models.Event, error) {
+		event := &models.Event{
+			ID:        eventID,
+			ChatID:    -100,
+			UserID:    1,
+			Name:      "Game night",
+			MessageID: &messageID,
+			BoardGames: []models.BoardGame{
+				{ID: 10, UUID: "catan-uuid", Name: "Catan", MaxPlayers: 4},
+				{ID: 20, UUID: "azul-uuid", Name: "Azul", MaxPlayers: 4},
+			},
+		}
+		for i := range event.BoardGames {
+			if event.BoardGames[i].ID == gameOf {
+				event.BoardGames[i].Participants = []models.Participant{{UserID: 3, UserName: "player"}}
+			}
+		}
+		return event, nil
+	}
+	db.MoveParticipantFunc = func(eventID string, userID, fromBoardgameID, toBoardgameID int64) error {
+		gameOf = toBoardgameID
+		return nil
+	}
+	telegram.AdminsOfFunc = func(chat *telebot.Chat) ([]telebot.ChatMember, error) {
+		return []telebot.ChatMember{{User: &telebot.User{ID: 7}, Role: telebot.Administrator}}, nil
+	}
+	var message string
+	telegram.SendFunc = func(to telebot.Recipient, what interface{}, opts ...interface{}) (*telebot.Message, error) {
+		message, _ = what.(string)
+		return &telebot.Message{}, nil
+	}
+
+	if _, _, err := service.MoveParticipant("mock-event-id", 3, "player", 3, 20); !errors.Is(err, ErrNotHost) {
+		t.Fatalf("Expected ErrNotHost, got %v", err)
+	}
+
+	_, game, err := service.MoveParticipant("mock-event-id", 7, "admin", 3, 20)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if gameOf != 20 || game.Name != "Azul" || len(game.Participants) != 1 {
+		t.Fatalf("Expected the player to be moved to Azul, got %+v", game)
+	}
+	if !strings.Contains(message, "admin moved you to <b>Azul</b>") {
+		t.Errorf("Expected the player to be told, got %q", message)
+	}
+
+	last := notifier.payloads[len(notifier.payloads)-1]
+	payload, ok := last.Data.(models.HookMoveParticipantPayload)
+	if last.Type != models.HookWebhookTypeMoveParticipant || !ok || payload.FromGameID != "catan-uuid" || payload.ToGameID != "azul-uuid" {
+		t.Fatalf("Expected a move_participant webhook, got %+v", last)
+	}
+}
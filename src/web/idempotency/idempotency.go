@@ -0,0 +1,168 @@
+package idempotency
+
+import (
+	"boardgame-night-bot/src/models"
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// MaxKeyLength bounds the size of a key kept in memory.
+	MaxKeyLength = 128
+	// sweepInterval is how often the background sweeper runs.
+	sweepInterval = time.Minute
+)
+
+type response struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+type entry struct {
+	done      bool
+	response  response
+	expiresAt time.Time
+}
+
+// Store remembers the response produced for each (webhook_id, idempotency key)
+// pair for the duration of the replay window. A request carrying a key that was
+// already processed receives the original response instead of being executed
+// again, so a captured request cannot be replayed to create duplicate data.
+type Store struct {
+	ttl     time.Duration
+	entries map[string]*entry
+	mu      sync.Mutex
+}
+
+// NewStore creates a Store that keeps keys for ttl. ttl should be at least as
+// long as the window in which a signed request is accepted.
+func NewStore(ttl time.Duration) *Store {
+	s := &Store{
+		ttl:     ttl,
+		entries: make(map[string]*entry),
+	}
+	go s.sweep()
+	return s
+}
+
+// sweep periodically removes expired entries, preventing the map from growing
+// unboundedly.
+func (s *Store) sweep() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		s.mu.Lock()
+		for key, e := range s.entries {
+			if e.done && now.After(e.expiresAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// reserve claims key for a new request. It returns the stored entry and false
+// when the key was already seen (either completed or still in flight).
+func (s *Store) reserve(key string) (*entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, exists := s.entries[key]; exists && (!e.done || time.Now().Before(e.expiresAt)) {
+		copied := *e
+		return &copied, false
+	}
+
+	s.entries[key] = &entry{}
+	return nil, true
+}
+
+func (s *Store) complete(key string, r response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &entry{
+		done:      true,
+		response:  r,
+		expiresAt: time.Now().Add(s.ttl),
+	}
+}
+
+// release forgets a reservation without recording a response, so that a
+// request aborted before producing a result can be retried with the same key.
+func (s *Store) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// recorder tees everything written to the client into a buffer so the
+// response can be replayed for duplicates.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// GinHandler returns a Gin middleware that deduplicates requests per
+// webhook_id path param and the key found in the given header. It must run
+// after the request signature has been verified, so that the key is trusted.
+func (s *Store) GinHandler(header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(header)
+		if key == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.NewHookErrorResponse(models.HookErrorCodeMissingHeader, "missing idempotency key"))
+			return
+		}
+		if len(key) > MaxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.NewHookErrorResponse(models.HookErrorCodeInvalidPayload, "idempotency key too long"))
+			return
+		}
+
+		storeKey := c.Param("webhook_id") + "|" + key
+		previous, ok := s.reserve(storeKey)
+		if !ok {
+			if !previous.done {
+				c.AbortWithStatusJSON(http.StatusConflict, models.NewHookErrorResponse(models.HookErrorCodeIdempotencyConflict, "request with the same idempotency key is in progress"))
+				return
+			}
+
+			c.Header("X-BGNB-Idempotent-Replay", "true")
+			c.Data(previous.response.status, previous.response.contentType, previous.response.body)
+			c.Abort()
+			return
+		}
+
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+
+		defer func() {
+			// server errors may be temporary: the request can be retried
+			if !rec.Written() || rec.Status() >= http.StatusInternalServerError {
+				s.release(storeKey)
+				return
+			}
+
+			s.complete(storeKey, response{
+				status:      rec.Status(),
+				contentType: rec.Header().Get("Content-Type"),
+				body:        rec.body.Bytes(),
+			})
+		}()
+
+		c.Next()
+	}
+}
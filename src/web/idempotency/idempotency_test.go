@@ -0,0 +1,119 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testHeader = "X-BGNB-Idempotency-Key"
+
+func setupRouter(store *Store, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhooks/:webhook_id", store.GinHandler(testHeader), func(ctx *gin.Context) {
+		*calls++
+		ctx.JSON(http.StatusOK, gin.H{"call": *calls})
+	})
+	return router
+}
+
+func doRequest(router *gin.Engine, webhookID, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/"+webhookID, nil)
+	if key != "" {
+		req.Header.Set(testHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDuplicateRequestReturnsOriginalResponse(t *testing.T) {
+	calls := 0
+	router := setupRouter(NewStore(time.Minute), &calls)
+
+	first := doRequest(router, "hook", "key-1")
+	second := doRequest(router, "hook", "key-1")
+
+	if calls != 1 {
+		t.Fatalf("Expected handler to run once, ran %d times", calls)
+	}
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for both requests, got %d and %d", first.Code, second.Code)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("Expected replayed body %q, got %q", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("X-BGNB-Idempotent-Replay") != "true" {
+		t.Errorf("Expected replay header on duplicate response")
+	}
+}
+
+func TestKeysAreScopedPerWebhook(t *testing.T) {
+	calls := 0
+	router := setupRouter(NewStore(time.Minute), &calls)
+
+	doRequest(router, "hook-a", "key-1")
+	doRequest(router, "hook-b", "key-1")
+	doRequest(router, "hook-a", "key-2")
+
+	if calls != 3 {
+		t.Fatalf("Expected handler to run 3 times, ran %d times", calls)
+	}
+}
+
+func TestMissingKeyIsRejected(t *testing.T) {
+	calls := 0
+	router := setupRouter(NewStore(time.Minute), &calls)
+
+	w := doRequest(router, "hook", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", w.Code)
+	}
+	if calls != 0 {
+		t.Fatalf("Expected handler not to run, ran %d times", calls)
+	}
+}
+
+func TestExpiredKeyIsAcceptedAgain(t *testing.T) {
+	calls := 0
+	router := setupRouter(NewStore(time.Millisecond), &calls)
+
+	doRequest(router, "hook", "key-1")
+	time.Sleep(5 * time.Millisecond)
+	doRequest(router, "hook", "key-1")
+
+	if calls != 2 {
+		t.Fatalf("Expected handler to run twice after expiry, ran %d times", calls)
+	}
+}
+
+func TestServerErrorsAreNotCached(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	router := gin.New()
+	router.POST("/webhooks/:webhook_id", NewStore(time.Minute).GinHandler(testHeader), func(ctx *gin.Context) {
+		calls++
+		if calls == 1 {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"call": calls})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+
+	first := doRequest(router, "hook", "key-1")
+	second := doRequest(router, "hook", "key-1")
+
+	if calls != 2 {
+		t.Fatalf("Expected the failed request to be retried, ran %d times", calls)
+	}
+	if first.Code != http.StatusInternalServerError || second.Code != http.StatusOK {
+		t.Fatalf("Expected 500 then 200, got %d and %d", first.Code, second.Code)
+	}
+	if second.Header().Get("X-BGNB-Idempotent-Replay") != "" {
+		t.Errorf("Expected the retry not to be a replay")
+	}
+}
@@ -1,6 +1,7 @@
 package limiter
 
 import (
+	"boardgame-night-bot/src/models"
 	"sync"
 	"time"
 
@@ -75,12 +76,12 @@ func (l *Limiter) GinHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		webhookID := c.Param("webhook_id")
 		if webhookID == "" {
-			c.AbortWithStatusJSON(400, gin.H{"error": "webhook_id path param required"})
+			c.AbortWithStatusJSON(400, models.NewHookErrorResponse(models.HookErrorCodeInvalidWebhook, "webhook_id path param required"))
 			return
 		}
 		limiter := l.getLimiter(webhookID)
 		if !limiter.Allow() {
-			c.AbortWithStatusJSON(429, gin.H{"error": "rate limit exceeded for webhook_id"})
+			c.AbortWithStatusJSON(429, models.NewHookErrorResponse(models.HookErrorCodeRateLimited, "rate limit exceeded for webhook_id"))
 			return
 		}
 		c.Next()
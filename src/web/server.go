@@ -4,7 +4,9 @@ import (
 	"boardgame-night-bot/src/bgg"
 	"boardgame-night-bot/src/database"
 	"boardgame-night-bot/src/hooks"
+	"boardgame-night-bot/src/models"
 	"boardgame-night-bot/src/web/api"
+	"boardgame-night-bot/src/web/tgwebhook"
 	"fmt"
 	"html/template"
 	"log"
@@ -15,7 +17,9 @@ import (
 	"gopkg.in/telebot.v3"
 )
 
-func StartServer(port int, db *database.Database, bgg bgg.BGGService, bot *telebot.Bot, bundle *i18n.Bundle, hook *hooks.WebhookClient, service *api.Service) {
+// StartServer runs the HTTP server. telegramWebhook, when not nil, receives the
+// Telegram updates in webhook mode.
+func StartServer(port int, db *database.Database, bgg bgg.BGGService, bot *telebot.Bot, bundle *i18n.Bundle, hook *hooks.WebhookClient, service *api.Service, telegramWebhook gin.HandlerFunc) {
 	var err error
 	router := gin.Default()
 
@@ -53,17 +57,8 @@ func StartServer(port int, db *database.Database, bgg bgg.BGGService, bot *teleb
 		"sub": func(a, b int) int {
 			return a - b
 		},
-		"queuedText": func(num int, lang string) string {
-			localizer := i18n.NewLocalizer(bundle, lang, "en")
-			return localizer.MustLocalize(&i18n.LocalizeConfig{
-				DefaultMessage: &i18n.Message{
-					ID:    "Queued",
-					Other: "(queued {{.Number}})",
-				},
-				TemplateData: map[string]string{
-					"Number": fmt.Sprintf("%d", num),
-				},
-			})
+		"seatText": func(seat models.Seat, lang string) string {
+			return seat.Display(i18n.NewLocalizer(bundle, lang, "en"))
 		},
 	})
 
@@ -73,6 +68,10 @@ func StartServer(port int, db *database.Database, bgg bgg.BGGService, bot *teleb
 
 	controller.InjectRoute()
 
+	if telegramWebhook != nil {
+		router.POST(tgwebhook.Path, telegramWebhook)
+	}
+
 	router.NoRoute(func(ctx *gin.Context) {
 		controller.NoRoute(ctx)
 	})
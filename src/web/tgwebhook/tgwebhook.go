@@ -0,0 +1,57 @@
+package tgwebhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	// Path is the route, relative to BASE_URL, where Telegram posts updates.
+	Path = "/telegram/webhook"
+	// SecretTokenHeader is the header Telegram fills with the secret_token
+	// given to setWebhook.
+	SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// NewPoller returns the telebot.Webhook poller used in webhook mode. It only
+// registers the webhook on Telegram when the bot starts: no listener is opened
+// because updates are received by GinHandler on the existing server.
+func NewPoller(baseUrl, secret string, allowedUpdates []string) *telebot.Webhook {
+	return &telebot.Webhook{
+		SecretToken:    secret,
+		AllowedUpdates: allowedUpdates,
+		Endpoint: &telebot.WebhookEndpoint{
+			PublicURL: strings.TrimSuffix(baseUrl, "/") + Path,
+		},
+	}
+}
+
+// GinHandler returns a Gin handler that verifies the secret token header and
+// hands the update to the bot, so the same handlers registered for long
+// polling are used.
+func GinHandler(bot *telebot.Bot, secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(SecretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			log.Default().Println("telegram webhook: invalid secret token")
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		var update telebot.Update
+		if err := json.NewDecoder(c.Request.Body).Decode(&update); err != nil {
+			log.Default().Println("telegram webhook: cannot decode update:", err)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		bot.ProcessUpdate(update)
+		c.Status(http.StatusOK)
+	}
+}
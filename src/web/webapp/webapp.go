@@ -0,0 +1,112 @@
+package webapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// InitDataHeader is the header the mini app fills with
+	// Telegram.WebApp.initData.
+	InitDataHeader = "X-Telegram-Init-Data"
+	// InitDataField is the form field holding the init data when the mini
+	// app submits a form.
+	InitDataField = "init_data"
+	// MaxAge is how long init data is accepted after Telegram signed it.
+	MaxAge = 24 * time.Hour
+)
+
+var (
+	// ErrMissingInitData is returned when the request carries no init data.
+	ErrMissingInitData = errors.New("missing init data")
+	// ErrInvalidInitData is returned when the init data is malformed or not
+	// signed with the bot token.
+	ErrInvalidInitData = errors.New("invalid init data")
+	// ErrExpiredInitData is returned when the init data is older than MaxAge.
+	ErrExpiredInitData = errors.New("expired init data")
+)
+
+// User is the Telegram user who opened the mini app.
+type User struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+}
+
+// DisplayName is the username of the user, or their full name when they have
+// none, as shown by the mini app.
+func (u User) DisplayName() string {
+	if u.Username != "" {
+		return u.Username
+	}
+
+	return fmt.Sprintf("%s %s", u.FirstName, u.LastName)
+}
+
+// Validate checks that initData was signed by Telegram for the bot with
+// botToken less than MaxAge before now, and returns the user it describes.
+// See https://core.telegram.org/bots/webapps#validating-data-received-via-the-mini-app
+func Validate(initData, botToken string, now time.Time) (*User, error) {
+	if initData == "" {
+		return nil, ErrMissingInitData
+	}
+
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInitData, err)
+	}
+
+	hash := values.Get("hash")
+	if hash == "" {
+		return nil, ErrInvalidInitData
+	}
+
+	pairs := []string{}
+	for key := range values {
+		if key == "hash" {
+			continue
+		}
+		pairs = append(pairs, key+"="+values.Get(key))
+	}
+	sort.Strings(pairs)
+
+	if !hmac.Equal([]byte(hash), []byte(Sign(strings.Join(pairs, "\n"), botToken))) {
+		return nil, ErrInvalidInitData
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInitData, err)
+	}
+	if now.Sub(time.Unix(authDate, 0)) > MaxAge {
+		return nil, ErrExpiredInitData
+	}
+
+	var user User
+	if err = json.Unmarshal([]byte(values.Get("user")), &user); err != nil || user.ID == 0 {
+		return nil, ErrInvalidInitData
+	}
+
+	return &user, nil
+}
+
+// Sign returns the hash Telegram computes over the data check string of the
+// init data: the sorted key=value pairs, without hash, joined by new lines.
+func Sign(dataCheckString, botToken string) string {
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(dataCheckString))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,70 @@
+package webapp
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testToken = "123456:test-token"
+
+func signedInitData(user string, authDate time.Time) string {
+	values := url.Values{}
+	values.Set("auth_date", strconv.FormatInt(authDate.Unix(), 10))
+	values.Set("query_id", "AAH")
+	values.Set("user", user)
+	check := "auth_date=" + values.Get("auth_date") + "\nquery_id=AAH\nuser=" + user
+	values.Set("hash", Sign(check, testToken))
+	return values.Encode()
+}
+
+func TestValidateReturnsTheUser(t *testing.T) {
+	now := time.Now()
+	initData := signedInitData(`{"id":42,"first_name":"Ada","last_name":"Lovelace"}`, now.Add(-time.Minute))
+
+	user, err := Validate(initData, testToken, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if user.ID != 42 || user.DisplayName() != "Ada Lovelace" {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestValidateRejectsForgedData(t *testing.T) {
+	now := time.Now()
+	initData := signedInitData(`{"id":42,"first_name":"Ada"}`, now)
+
+	forged, err := url.ParseQuery(initData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.Set("user", `{"id":1,"first_name":"Owner"}`)
+
+	for name, data := range map[string]string{
+		"forged user": forged.Encode(),
+		"no hash":     "auth_date=1&user=%7B%22id%22%3A1%7D",
+	} {
+		if _, err = Validate(data, testToken, now); !errors.Is(err, ErrInvalidInitData) {
+			t.Errorf("%s: expected ErrInvalidInitData, got %v", name, err)
+		}
+	}
+
+	if _, err = Validate(initData, "654321:other-token", now); !errors.Is(err, ErrInvalidInitData) {
+		t.Errorf("expected data signed for another bot to be rejected, got %v", err)
+	}
+	if _, err = Validate("", testToken, now); !errors.Is(err, ErrMissingInitData) {
+		t.Errorf("expected ErrMissingInitData, got %v", err)
+	}
+}
+
+func TestValidateRejectsExpiredData(t *testing.T) {
+	now := time.Now()
+	initData := signedInitData(`{"id":42,"first_name":"Ada"}`, now.Add(-MaxAge-time.Minute))
+
+	if _, err := Validate(initData, testToken, now); !errors.Is(err, ErrExpiredInitData) {
+		t.Errorf("expected ErrExpiredInitData, got %v", err)
+	}
+}